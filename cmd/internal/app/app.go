@@ -2,14 +2,16 @@ package app
 
 import (
 	"llm-router/cmd/internal/cache"
+	"llm-router/cmd/internal/embeddings"
+	"llm-router/cmd/internal/middleware"
 	"llm-router/cmd/internal/providers"
 	"llm-router/cmd/internal/resilience"
 	"llm-router/cmd/internal/router"
 	"llm-router/config"
 	"llm-router/types"
 	"llm-router/utils"
-	"os"
 
+	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
@@ -26,6 +28,11 @@ type ConfigResolver interface {
 	Reload() error
 }
 
+// RequestResolver picks the ConfigResolver serving a request.
+type RequestResolver interface {
+	Resolve(c *gin.Context) ConfigResolver
+}
+
 type App struct {
 	Config          *config.Config
 	Router          router.Router
@@ -35,10 +42,14 @@ type App struct {
 	Circuit         map[string]types.CircuitBreaker
 	ProviderManager *providers.ProviderManager
 	FallbackChain   []string
+	// Consumer of each API key in Config, empty when authentication is disabled
+	Consumers map[string]*types.ConsumerData
 }
 
 var logger = utils.SetUpLogger()
 
+const defaultEmbeddingModelPath = "assets/models/embedding.onnx"
+
 func SetUpApp() (*App, error) {
 	defer logger.Sync()
 
@@ -51,6 +62,19 @@ func SetUpApp() (*App, error) {
 	}
 
 	providers.InitializeModelRegistry(providers.GetDefaultCatalog(), cfg.Models.Catalog)
+
+	var redisClient *redis.Client
+	if cfg.Redis.Addr != "" {
+		redisClient = cache.NewRedisClient(cfg.Redis)
+		logger.Info("Using Redis", zap.String("addr", cfg.Redis.Addr))
+	}
+
+	return newApp(cfg, redisClient, "", logger)
+}
+
+// newApp builds the providers, router, cache and resilience handlers for cfg. Everything it
+// keeps in Redis is prefixed with namespace, so apps sharing a Redis server stay isolated.
+func newApp(cfg *config.Config, redisClient *redis.Client, namespace string, appLogger *zap.Logger) (*App, error) {
 	latencyTracker := router.NewLatencyTracker()
 	providerFactory := providers.NewProviderFactory()
 
@@ -64,48 +88,43 @@ func SetUpApp() (*App, error) {
 	providerManager := providers.NewProviderManager(providerFactory)
 	providerManager.SetProviders(wrappedProviders)
 
-	var redisClient *redis.Client
-	if cfg.Redis.Addr != "" {
-		redisClient = cache.NewRedisClient(cfg.Redis)
-		logger.Info("Using Redis", zap.String("addr", cfg.Redis.Addr))
-	}
-
-	llmRouter, fallback, err := initializeRouter(cfg, providerManager, latencyTracker, redisClient)
+	llmRouter, fallback, err := initializeRouter(cfg, providerManager, latencyTracker, redisClient, namespace)
 	if err != nil {
-		logger.Error("Failed to initialize router", zap.Error(err))
-		os.Exit(1)
+		appLogger.Error("Failed to initialize router", zap.Error(err))
+		return nil, err
 	}
 
 	var cacheInstance cache.Cache
 
 	if cfg.CacheConfig.Enabled {
-		cacheInstance, err = cache.NewCacheClient(cfg.CacheConfig, redisClient)
+		cacheInstance, err = cache.NewCacheClient(cfg.CacheConfig, redisClient, initializeCacheEmbedder(cfg), namespace)
 
 		if err != nil {
-			logger.Error("Failed to initialize cache", zap.Error(err))
+			appLogger.Error("Failed to initialize cache", zap.Error(err))
 		}
 	}
 
 	resillienceConfig := cfg.GetResilienceConfigData()
-	retry := resilience.NewRetryHandler(resillienceConfig.RetriesConfig, logger)
+	retry := resilience.NewRetryHandler(resillienceConfig.RetriesConfig, appLogger)
 	circuit := initializeCircuitBreakers(cfg)
 
 	// Create app with all dependencies
 	app := &App{
 		Config:          cfg,
 		Router:          llmRouter,
-		Logger:          logger,
+		Logger:          appLogger,
 		Cache:           cacheInstance,
 		Retry:           retry,
 		Circuit:         circuit,
 		ProviderManager: providerManager,
 		FallbackChain:   fallback,
+		Consumers:       middleware.ConsumerIndex(cfg.Security),
 	}
 
 	return app, nil
 }
 
-func initializeRouter(cfg *config.Config, providerManager *providers.ProviderManager, tracker *router.LatencyTracker, redisClient *redis.Client) (router.Router, []string, error) {
+func initializeRouter(cfg *config.Config, providerManager *providers.ProviderManager, tracker *router.LatencyTracker, redisClient *redis.Client, namespace string) (router.Router, []string, error) {
 	routerStrategy := cfg.GetRouterStrategy()
 
 	logger.Info("Initializing router", zap.String("strategy", routerStrategy.Strategy))
@@ -122,9 +141,9 @@ func initializeRouter(cfg *config.Config, providerManager *providers.ProviderMan
 	var historyManager router.UsageHistoryManager
 
 	if redisClient != nil {
-		budgetManager = router.NewRedisBudgetManager(redisClient, budgets, logger)
-		rateLimitManager = router.NewRedisRateLimitManager(redisClient, logger)
-		historyManager = router.NewRedisUsageHistoryManager(redisClient, logger)
+		budgetManager = router.NewRedisBudgetManager(redisClient, namespace, budgets, logger)
+		rateLimitManager = router.NewRedisRateLimitManager(redisClient, namespace, logger)
+		historyManager = router.NewRedisUsageHistoryManager(redisClient, namespace, logger)
 		logger.Info("Using shared Redis client for budget, rate limit, and usage tracking")
 	} else {
 		budgetManager = router.NewInMemoryBudgetManager(budgets, logger)
@@ -141,11 +160,51 @@ func initializeRouter(cfg *config.Config, providerManager *providers.ProviderMan
 		}
 	}
 
-	llmRouter, fallback, err := router.ConfigureRouterStrategy(routerStrategy, providerManager, tracker, budgetManager, rateLimitManager, rateLimits, historyManager)
+	defaultModels := make(map[string]string)
+	for _, provider := range cfg.GetProviderConfigWithExtras() {
+		if provider.Defaults != nil && provider.Defaults.Model != "" {
+			defaultModels[provider.Name] = provider.Defaults.Model
+		}
+	}
+
+	llmRouter, fallback, err := router.ConfigureRouterStrategy(routerStrategy, providerManager, tracker, budgetManager, rateLimitManager, rateLimits, historyManager, defaultModels, cfg.Experiments)
 
 	return llmRouter, fallback, err
 }
 
+// initializeCacheEmbedder loads the MiniLM model for semantic caching.
+// It reuses the semantic routing model unless the cache config points at a different one.
+func initializeCacheEmbedder(cfg *config.Config) cache.Embedder {
+	semanticCache := cfg.CacheConfig.Semantic
+	if !semanticCache.Enabled {
+		return nil
+	}
+
+	modelPath := semanticCache.ModelPath
+	sharedLibPath := semanticCache.SharedLibPath
+
+	if policies := cfg.Routing.Policies; policies != nil && policies.Semantic != nil {
+		if modelPath == "" {
+			modelPath = policies.Semantic.ModelPath
+		}
+		if sharedLibPath == "" {
+			sharedLibPath = policies.Semantic.SharedLibPath
+		}
+	}
+
+	if modelPath == "" {
+		modelPath = defaultEmbeddingModelPath
+	}
+
+	model, err := embeddings.Shared(modelPath, sharedLibPath, logger)
+	if err != nil {
+		logger.Error("Failed to load embedding model for semantic cache", zap.Error(err))
+		return nil
+	}
+
+	return model
+}
+
 func initializeCircuitBreakers(cfg *config.Config) map[string]types.CircuitBreaker {
 	enabled := cfg.GetEnabledProviders()
 	resillienceConfig := cfg.GetResilienceConfigData()
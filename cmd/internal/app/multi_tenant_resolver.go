@@ -1,66 +1,213 @@
 package app
 
 import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
 	"llm-router/cmd/internal/cache"
+	"llm-router/cmd/internal/middleware"
 	"llm-router/cmd/internal/providers"
-	"llm-router/cmd/internal/resilience"
-	"llm-router/cmd/internal/router"
-	"llm-router/config"
 	"llm-router/types"
+	"net/http"
+	"sync"
 
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
-type MultiTenantResolver struct {
-	Logger *zap.Logger
+// tenantKey is the gin context key holding the *tenantResolver of the request's tenant.
+const tenantKey = "octo.tenant"
+
+const defaultTenantCacheSize = 100
+
+// TenantSettings configures multi-tenant mode. They come from the server's environment, since
+// there is no server config file in this mode.
+type TenantSettings struct {
+	// "file" (the default) or "redis"
+	Store string
+	// Directory of <tenant-id>.yaml configs for the file store
+	Dir string
+	// Shared by every tenant for budgets, rate limits, usage history and caching, and by the redis
+	// store. Required, since evicted tenants would otherwise lose their budgets and limits
+	Redis types.RedisData
+	// Maximum number of tenants kept built in memory
+	CacheSize int
 }
 
-func (m *MultiTenantResolver) GetConfig() *config.Config {
-	// Extract API key from request
-	// apiKey := c.GetHeader("X-API-Key")
-	// Fetch tenant config from DB/cache
-	// return fetchTenantConfig(apiKey)
-	return nil
+// MultiTenantResolver serves each request with the App of the tenant owning its API key.
+// Tenant Apps are built from the TenantStore on first use and kept in an LRU cache, so idle
+// tenants are evicted (losing their circuit breaker and latency state) and rebuilt when they return.
+// Each tenant has its own providers, router and circuit breakers, and its Redis keys are
+// namespaced by tenant ID, so tenants never share budgets, limits, usage or cached responses.
+type MultiTenantResolver struct {
+	Logger      *zap.Logger
+	store       TenantStore
+	redisClient *redis.Client
+
+	mu       sync.Mutex
+	capacity int
+	tenants  map[string]*list.Element
+	lru      *list.List
 }
 
-// When I implement multi-tenancy
-// it might not be initializeRouter,
-// it might be some other function that checks
-// if a router is already cached for said user, then retrieve
-// if not fetch cfg from database and configure router properly
-func (m *MultiTenantResolver) GetRouter() router.Router {
-	// cfg := m.GetConfig(c)
-	// providerManager, _ := initializeProviderManager(cfg)
-	// router, _ := initializeRouter(cfg, providerManager)
-	// return router
+func SetUpMultiTenant(settings TenantSettings) (*MultiTenantResolver, error) {
+	// Tenants can't extend the catalog, so the registry only holds the built-in models
+	providers.InitializeModelRegistry(providers.GetDefaultCatalog(), nil)
 
-	return nil
+	// Tenants are rebuilt after eviction, so their budgets, rate limits and usage must outlive them
+	if settings.Redis.Addr == "" {
+		return nil, fmt.Errorf("multi-tenant mode requires a redis address")
+	}
+	redisClient := cache.NewRedisClient(settings.Redis)
+	logger.Info("Using Redis for tenants", zap.String("addr", settings.Redis.Addr))
+
+	var store TenantStore
+	switch settings.Store {
+	case "", "file":
+		fileStore, err := NewFileTenantStore(settings.Dir, settings.Redis)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tenants: %w", err)
+		}
+		store = fileStore
+	case "redis":
+		store = NewRedisTenantStore(redisClient, settings.Redis)
+	default:
+		return nil, fmt.Errorf("unknown tenant store %q, expected file or redis", settings.Store)
+	}
+
+	return NewMultiTenantResolver(store, redisClient, settings.CacheSize, logger), nil
 }
 
-func (m *MultiTenantResolver) GetLogger() *zap.Logger {
-	return m.Logger
+func NewMultiTenantResolver(store TenantStore, redisClient *redis.Client, capacity int, logger *zap.Logger) *MultiTenantResolver {
+	if capacity <= 0 {
+		capacity = defaultTenantCacheSize
+	}
+
+	return &MultiTenantResolver{
+		Logger:      logger,
+		store:       store,
+		redisClient: redisClient,
+		capacity:    capacity,
+		tenants:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
 }
 
-func (m *MultiTenantResolver) GetCache() cache.Cache {
-	return nil
+// Authenticate finds the tenant owning the request's API key and records it, along with the
+// consumer the key belongs to within that tenant, for Resolve and the handlers.
+func (m *MultiTenantResolver) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey, err := middleware.APIKey(c)
+		if err != nil {
+			middleware.Unauthorized(c, err.Error())
+			return
+		}
+
+		ctx := c.Request.Context()
+
+		tenantID, err := m.store.TenantForKey(ctx, apiKey)
+		if errors.Is(err, ErrUnknownTenant) {
+			middleware.Unauthorized(c, "Invalid API key")
+			return
+		}
+		if err != nil {
+			m.Logger.Error("Tenant lookup failed", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, types.NewErrorResponse(types.ErrorTypeServer, "", "Tenant lookup failed"))
+			return
+		}
+
+		tenant, err := m.tenant(ctx, tenantID)
+		if err != nil {
+			m.Logger.Error("Failed to load tenant", zap.String("tenant", tenantID), zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, types.NewErrorResponse(types.ErrorTypeServer, "", "Tenant configuration is unavailable"))
+			return
+		}
+
+		// The store's key index may be stale, so the tenant's own config has the final say
+		consumer, ok := tenant.Consumers()[apiKey]
+		if !ok {
+			middleware.Unauthorized(c, "Invalid API key")
+			return
+		}
+
+		c.Set(middleware.ConsumerKey, consumer)
+		c.Set(tenantKey, tenant)
+		c.Next()
+	}
 }
 
-func (m *MultiTenantResolver) GetRetry() *resilience.Retry {
-	return nil
+// Resolve returns the resolver of the tenant Authenticate found for the request.
+func (m *MultiTenantResolver) Resolve(c *gin.Context) ConfigResolver {
+	value, ok := c.Get(tenantKey)
+	if !ok {
+		return nil
+	}
+	return value.(*tenantResolver)
 }
 
-func (m *MultiTenantResolver) GetCircuitBreaker() map[string]types.CircuitBreaker {
-	return nil
+// tenant returns the tenant's resolver, building its App on a cache miss. Concurrent misses for
+// the same tenant may each build an App; only the first one to finish is kept.
+func (m *MultiTenantResolver) tenant(ctx context.Context, tenantID string) (*tenantResolver, error) {
+	m.mu.Lock()
+	if element, ok := m.tenants[tenantID]; ok {
+		m.lru.MoveToFront(element)
+		m.mu.Unlock()
+		return element.Value.(*tenantResolver), nil
+	}
+	m.mu.Unlock()
+
+	tenant := &tenantResolver{id: tenantID, tenants: m}
+	if err := tenant.load(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.tenants[tenantID]; ok {
+		m.lru.MoveToFront(element)
+		return element.Value.(*tenantResolver), nil
+	}
+
+	m.tenants[tenantID] = m.lru.PushFront(tenant)
+
+	if m.lru.Len() > m.capacity {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		evicted := oldest.Value.(*tenantResolver)
+		delete(m.tenants, evicted.id)
+		m.Logger.Info("Evicted tenant from cache", zap.String("tenant", evicted.id))
+	}
+
+	return tenant, nil
 }
 
-func (m *MultiTenantResolver) GetProviderManager() *providers.ProviderManager {
-	return nil
+// tenantResolver serves a single tenant's requests; Reload rebuilds it from the tenant store.
+type tenantResolver struct {
+	SingleTenantResolver
+	id      string
+	tenants *MultiTenantResolver
 }
 
-func (m *MultiTenantResolver) GetFallbackChain() []string {
-	return nil
+func (t *tenantResolver) Reload() error {
+	return t.load(context.Background())
 }
 
-func (m *MultiTenantResolver) Reload() error {
+func (t *tenantResolver) load(ctx context.Context) error {
+	cfg, err := t.tenants.store.LoadConfig(ctx, t.id)
+	if err != nil {
+		return err
+	}
+
+	tenantLogger := t.tenants.Logger.With(zap.String("tenant", t.id))
+	app, err := newApp(cfg, t.tenants.redisClient, "tenant:"+t.id, tenantLogger)
+	if err != nil {
+		return err
+	}
+
+	t.App.Store(app)
+	tenantLogger.Info("Loaded tenant")
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"llm-router/types"
 	"sync/atomic"
 
+	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
@@ -16,6 +17,11 @@ type SingleTenantResolver struct {
 	App atomic.Pointer[App]
 }
 
+// Resolve serves every request with the single App.
+func (s *SingleTenantResolver) Resolve(c *gin.Context) ConfigResolver {
+	return s
+}
+
 func (s *SingleTenantResolver) GetConfig() *config.Config {
 	return s.App.Load().Config
 }
@@ -48,6 +54,12 @@ func (s *SingleTenantResolver) GetFallbackChain() []string {
 	return s.App.Load().FallbackChain
 }
 
+// Consumers returns the consumer of each API key in the current config, so keys added or removed
+// by a reload take effect immediately.
+func (s *SingleTenantResolver) Consumers() map[string]*types.ConsumerData {
+	return s.App.Load().Consumers
+}
+
 func (s *SingleTenantResolver) Reload() error {
 	newApp, err := SetUpApp()
 	if err != nil {
@@ -0,0 +1,201 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"llm-router/config"
+	"llm-router/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrUnknownTenant is returned when no tenant owns an API key.
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// TenantStore is where multi-tenant mode finds each tenant's configuration.
+type TenantStore interface {
+	// TenantForKey returns the ID of the tenant owning apiKey, or ErrUnknownTenant.
+	TenantForKey(ctx context.Context, apiKey string) (string, error)
+	// LoadConfig reads and validates a tenant's configuration.
+	LoadConfig(ctx context.Context, tenantID string) (*config.Config, error)
+}
+
+// fileRescanInterval limits how often an unknown key makes FileTenantStore re-read its directory.
+const fileRescanInterval = 30 * time.Second
+
+// FileTenantStore reads tenants from a directory holding one <tenant-id>.yaml config per tenant.
+// A tenant's API keys are the ones in its security section (apiKeys and consumers).
+type FileTenantStore struct {
+	dir       string
+	redisData types.RedisData
+
+	mu       sync.Mutex
+	keys     map[string]string
+	lastScan time.Time
+}
+
+func NewFileTenantStore(dir string, redisData types.RedisData) (*FileTenantStore, error) {
+	store := &FileTenantStore{dir: dir, redisData: redisData}
+	if err := store.scan(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *FileTenantStore) TenantForKey(ctx context.Context, apiKey string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tenantID, ok := s.keys[apiKey]; ok {
+		return tenantID, nil
+	}
+
+	// The key may belong to a tenant added since the last scan
+	if time.Since(s.lastScan) < fileRescanInterval {
+		return "", ErrUnknownTenant
+	}
+	if err := s.scan(); err != nil {
+		return "", err
+	}
+
+	if tenantID, ok := s.keys[apiKey]; ok {
+		return tenantID, nil
+	}
+	return "", ErrUnknownTenant
+}
+
+func (s *FileTenantStore) LoadConfig(ctx context.Context, tenantID string) (*config.Config, error) {
+	if !validTenantID(tenantID) {
+		return nil, fmt.Errorf("invalid tenant id %q", tenantID)
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, tenantID+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config for tenant %s: %w", tenantID, err)
+	}
+
+	cfg, err := config.ParseConfig(data, s.redisData)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s: %w", tenantID, err)
+	}
+	return cfg, nil
+}
+
+// scan rebuilds the API key index from the directory. Callers must hold s.mu.
+// Tenants with unreadable configs are skipped so one broken file doesn't lock out the others.
+func (s *FileTenantStore) scan() error {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]string)
+	loaded := 0
+	for _, file := range files {
+		tenantID := strings.TrimSuffix(filepath.Base(file), ".yaml")
+
+		cfg, err := s.LoadConfig(context.Background(), tenantID)
+		if err != nil {
+			logger.Warn("Skipping tenant with invalid config", zap.String("tenant", tenantID), zap.Error(err))
+			continue
+		}
+		loaded++
+
+		for _, key := range tenantKeys(cfg.Security) {
+			if owner, ok := keys[key]; ok && owner != tenantID {
+				logger.Warn("API key is used by several tenants, keeping the first", zap.String("tenant", owner), zap.String("duplicate", tenantID))
+				continue
+			}
+			keys[key] = tenantID
+		}
+	}
+
+	s.keys = keys
+	s.lastScan = time.Now()
+	logger.Info("Loaded tenants", zap.String("dir", s.dir), zap.Int("tenants", loaded))
+	return nil
+}
+
+func tenantKeys(security types.SecurityData) []string {
+	var keys []string
+	for _, key := range security.APIKeys {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	for _, consumer := range security.Consumers {
+		for _, key := range consumer.APIKeys {
+			if key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// RedisTenantStore reads tenants from Redis, where octo:tenant-key:<sha256 of API key> holds the
+// ID of the tenant owning that key and octo:tenant:<tenant-id>:config holds its YAML config.
+// Only key hashes are stored, so the Redis data doesn't reveal API keys.
+type RedisTenantStore struct {
+	client    *redis.Client
+	redisData types.RedisData
+}
+
+func NewRedisTenantStore(client *redis.Client, redisData types.RedisData) *RedisTenantStore {
+	return &RedisTenantStore{client: client, redisData: redisData}
+}
+
+func (s *RedisTenantStore) TenantForKey(ctx context.Context, apiKey string) (string, error) {
+	hash := sha256.Sum256([]byte(apiKey))
+
+	tenantID, err := s.client.Get(ctx, "octo:tenant-key:"+hex.EncodeToString(hash[:])).Result()
+	if err == redis.Nil {
+		return "", ErrUnknownTenant
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up tenant: %w", err)
+	}
+	return tenantID, nil
+}
+
+func (s *RedisTenantStore) LoadConfig(ctx context.Context, tenantID string) (*config.Config, error) {
+	if !validTenantID(tenantID) {
+		return nil, fmt.Errorf("invalid tenant id %q", tenantID)
+	}
+
+	data, err := s.client.Get(ctx, fmt.Sprintf("octo:tenant:%s:config", tenantID)).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("no config for tenant %s", tenantID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config for tenant %s: %w", tenantID, err)
+	}
+
+	cfg, err := config.ParseConfig(data, s.redisData)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s: %w", tenantID, err)
+	}
+	return cfg, nil
+}
+
+// validTenantID keeps tenant IDs safe to use in file names and Redis keys.
+func validTenantID(tenantID string) bool {
+	if tenantID == "" || len(tenantID) > 64 {
+		return false
+	}
+	for _, r := range tenantID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"llm-router/types"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const tenantConfig = `
+providers:
+  - name: openai
+    apiKey: sk-tenant
+    enabled: true
+resilience:
+  timeout: 30000
+security:
+  apiKeys: ["%s"]
+`
+
+func writeTenant(t *testing.T, dir string, tenantID string, apiKey string) {
+	t.Helper()
+	data := []byte(fmt.Sprintf(tenantConfig, apiKey))
+	if err := os.WriteFile(filepath.Join(dir, tenantID+".yaml"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileTenantStore(t *testing.T) {
+	dir := t.TempDir()
+	writeTenant(t, dir, "acme", "key-acme")
+	writeTenant(t, dir, "globex", "key-globex")
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("providers: []"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileTenantStore(dir, types.RedisData{})
+	if err != nil {
+		t.Fatalf("NewFileTenantStore() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	for key, want := range map[string]string{"key-acme": "acme", "key-globex": "globex"} {
+		got, err := store.TenantForKey(ctx, key)
+		if err != nil || got != want {
+			t.Errorf("TenantForKey(%q) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+
+	if _, err := store.TenantForKey(ctx, "key-unknown"); !errors.Is(err, ErrUnknownTenant) {
+		t.Errorf("TenantForKey(unknown) error = %v, want ErrUnknownTenant", err)
+	}
+
+	cfg, err := store.LoadConfig(ctx, "acme")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Providers[0].APIKey != "sk-tenant" {
+		t.Errorf("LoadConfig() provider key = %q, want sk-tenant", cfg.Providers[0].APIKey)
+	}
+
+	if _, err := store.LoadConfig(ctx, "../acme"); err == nil {
+		t.Error("LoadConfig() accepted a tenant id outside the directory")
+	}
+}
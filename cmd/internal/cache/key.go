@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"llm-router/types"
+	"strings"
+)
+
+const keyPrefix = "cache:v1"
+
+// normalizedRequest is the subset of a completion request that determines its answer.
+// Fields that do not change the output (e.g. stream) are intentionally left out.
+type normalizedRequest struct {
+	Messages         []types.Message `json:"messages"`
+	Model            string          `json:"model"`
+	Tier             string          `json:"tier"`
+	Temperature      *float64        `json:"temperature"`
+	MaxTokens        *int            `json:"max_tokens"`
+	TopP             *float64        `json:"top_p"`
+	FrequencyPenalty *float64        `json:"frequency_penalty"`
+	PresencePenalty  *float64        `json:"presence_penalty"`
+	// Omitted when unset so keys for requests without tools or a response format are unchanged
+	Tools          []types.Tool          `json:"tools,omitempty"`
+	ToolChoice     *types.ToolChoice     `json:"tool_choice,omitempty"`
+	ResponseFormat *types.ResponseFormat `json:"response_format,omitempty"`
+}
+
+// HashRequest returns a stable hash for a completion request.
+// Roles are lower-cased and content is trimmed so that cosmetic differences
+// between otherwise identical requests still hit the same entry.
+func HashRequest(request *types.Completion) string {
+	messages := make([]types.Message, len(request.Messages))
+	for i, msg := range request.Messages {
+		messages[i] = types.Message{
+			Role:       strings.ToLower(strings.TrimSpace(msg.Role)),
+			Content:    strings.TrimSpace(msg.Content),
+			Parts:      msg.Parts,
+			ToolCalls:  msg.ToolCalls,
+			ToolCallID: msg.ToolCallID,
+		}
+	}
+
+	normalized := normalizedRequest{
+		Messages:         messages,
+		Model:            strings.ToLower(strings.TrimSpace(request.Model)),
+		Tier:             strings.ToLower(strings.TrimSpace(request.Tier)),
+		Temperature:      request.Temperature,
+		MaxTokens:        request.MaxTokens,
+		TopP:             request.TopP,
+		FrequencyPenalty: request.FrequencyPenalty,
+		PresencePenalty:  request.PresencePenalty,
+		Tools:            request.Tools,
+		ToolChoice:       request.ToolChoice,
+		ResponseFormat:   request.ResponseFormat,
+	}
+
+	// Marshalling a struct of plain values cannot fail
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Scope partitions cache entries so an answer is only ever reused for requests
+// asking for the same model and tier (e.g. premium answers are never served to budget requests).
+// Partition further separates entries per user when a cache rule asks for it.
+type Scope struct {
+	Model     string
+	Tier      string
+	Partition string
+	// Set by the cache itself to keep each tenant's entries apart; empty for a single tenant
+	Namespace string
+}
+
+const (
+	anyScope        = "any"
+	sharedPartition = "shared"
+)
+
+// ScopeFor returns the shared scope a request's cache entries live under.
+func ScopeFor(request *types.Completion) Scope {
+	return Scope{
+		Model:     scopeValue(request.Model),
+		Tier:      scopeValue(request.Tier),
+		Partition: sharedPartition,
+	}
+}
+
+// userScope keeps entries private to the request's user. The user id is hashed so it never appears in keys.
+func userScope(request *types.Completion) Scope {
+	scope := ScopeFor(request)
+	sum := sha256.Sum256([]byte(request.User))
+	scope.Partition = "user-" + hex.EncodeToString(sum[:8])
+	return scope
+}
+
+func scopeValue(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return anyScope
+	}
+	return value
+}
+
+func (s Scope) prefix(kind string) string {
+	partition := s.Partition
+	if partition == "" {
+		partition = sharedPartition
+	}
+	return fmt.Sprintf("%s:%s:%s:%s:%s", s.keyPrefix(), kind, s.Tier, s.Model, partition)
+}
+
+func (s Scope) keyPrefix() string {
+	if s.Namespace == "" {
+		return keyPrefix
+	}
+	return keyPrefix + ":" + s.Namespace
+}
+
+// pattern builds a redis match pattern for purging; empty fields match every value.
+func (s Scope) pattern(kind string) string {
+	tier, model := s.Tier, s.Model
+	if tier == "" {
+		tier = "*"
+	}
+	if model == "" {
+		model = "*"
+	}
+	if s.Partition != "" {
+		return fmt.Sprintf("%s:%s:%s:%s:%s:*", s.keyPrefix(), kind, tier, model, s.Partition)
+	}
+	return fmt.Sprintf("%s:%s:%s:%s:*", s.keyPrefix(), kind, tier, model)
+}
+
+func exactKey(scope Scope, request *types.Completion) string {
+	return scope.prefix("exact") + ":" + HashRequest(request)
+}
+
+// contextHash identifies everything in a request except its final message,
+// so semantic matches are only made between prompts asked in the same context.
+func contextHash(request *types.Completion) string {
+	withoutPrompt := *request
+	if len(withoutPrompt.Messages) > 0 {
+		withoutPrompt.Messages = withoutPrompt.Messages[:len(withoutPrompt.Messages)-1]
+	}
+	return HashRequest(&withoutPrompt)
+}
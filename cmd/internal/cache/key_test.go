@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"llm-router/types"
+	"testing"
+)
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func TestHashRequest(t *testing.T) {
+	base := types.Completion{
+		Messages:    []types.Message{{Role: "user", Content: "What is the capital of France?"}},
+		Model:       "openai/gpt-4o",
+		Tier:        "premium",
+		Temperature: float64Ptr(0),
+	}
+
+	tests := []struct {
+		name      string
+		request   types.Completion
+		wantEqual bool
+	}{
+		{
+			name:      "identical request",
+			request:   base,
+			wantEqual: true,
+		},
+		{
+			name: "whitespace and role casing are ignored",
+			request: types.Completion{
+				Messages:    []types.Message{{Role: "User", Content: "  What is the capital of France?\n"}},
+				Model:       "openai/gpt-4o",
+				Tier:        "premium",
+				Temperature: float64Ptr(0),
+			},
+			wantEqual: true,
+		},
+		{
+			name: "stream flag is ignored",
+			request: types.Completion{
+				Messages:    base.Messages,
+				Model:       base.Model,
+				Tier:        base.Tier,
+				Temperature: base.Temperature,
+				Stream:      true,
+			},
+			wantEqual: true,
+		},
+		{
+			name: "different tier",
+			request: types.Completion{
+				Messages:    base.Messages,
+				Model:       base.Model,
+				Tier:        "budget",
+				Temperature: base.Temperature,
+			},
+			wantEqual: false,
+		},
+		{
+			name: "different temperature",
+			request: types.Completion{
+				Messages:    base.Messages,
+				Model:       base.Model,
+				Tier:        base.Tier,
+				Temperature: float64Ptr(0.7),
+			},
+			wantEqual: false,
+		},
+		{
+			name: "different content",
+			request: types.Completion{
+				Messages:    []types.Message{{Role: "user", Content: "What is the capital of Spain?"}},
+				Model:       base.Model,
+				Tier:        base.Tier,
+				Temperature: base.Temperature,
+			},
+			wantEqual: false,
+		},
+		{
+			name: "tools defined",
+			request: types.Completion{
+				Messages:    base.Messages,
+				Model:       base.Model,
+				Tier:        base.Tier,
+				Temperature: base.Temperature,
+				Tools: []types.Tool{{
+					Type:     "function",
+					Function: types.FunctionDefinition{Name: "get_capital"},
+				}},
+			},
+			wantEqual: false,
+		},
+	}
+
+	baseHash := HashRequest(&base)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HashRequest(&tt.request)
+			if (got == baseHash) != tt.wantEqual {
+				t.Errorf("HashRequest() equal = %v, want %v", got == baseHash, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestScopeNamespace(t *testing.T) {
+	shared := Scope{Model: "openai/gpt-4o", Tier: "premium"}
+	tenant := Scope{Model: "openai/gpt-4o", Tier: "premium", Namespace: "tenant:acme"}
+
+	if got, want := shared.prefix("exact"), "cache:v1:exact:premium:openai/gpt-4o:shared"; got != want {
+		t.Errorf("prefix() = %q, want %q", got, want)
+	}
+	if got, want := tenant.prefix("exact"), "cache:v1:tenant:acme:exact:premium:openai/gpt-4o:shared"; got != want {
+		t.Errorf("prefix() = %q, want %q", got, want)
+	}
+	if got, want := (Scope{Namespace: "tenant:acme"}).pattern("exact"), "cache:v1:tenant:acme:exact:*:*:*"; got != want {
+		t.Errorf("pattern() = %q, want %q", got, want)
+	}
+}
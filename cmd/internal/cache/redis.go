@@ -1,22 +1,33 @@
 package cache
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"llm-router/types"
-	"strconv"
+	"llm-router/utils"
+	"time"
 
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
 // have 1 interface and 2 structs that implement them namely SemanticCache and DefaultCache
 
 type Cache interface {
-	SetItem()
-	GetItem()
+	// Decide applies the cache rules to a request. tokens lazily counts the prompt tokens.
+	Decide(request *types.Completion, tokens func() int) Decision
+	GetItem(ctx context.Context, request *types.Completion, decision Decision) (*CachedResponse, bool)
+	SetItem(ctx context.Context, request *types.Completion, decision Decision, response *CachedResponse)
+	Purge(ctx context.Context, scope Scope) (int, error)
 }
 
-type SemanticCache struct {
-	client *redis.Client
-	config cacheConfig
+// CachedResponse is what gets stored for a successful non-streaming completion.
+type CachedResponse struct {
+	Response types.CompletionResponse `json:"response"`
+	Provider string                   `json:"provider"`
+	Model    string                   `json:"model"`
+	CachedAt time.Time                `json:"cached_at"`
 }
 
 type DefaultCache struct {
@@ -26,17 +37,73 @@ type DefaultCache struct {
 
 type cacheConfig struct {
 	strategy            string
-	ttl                 int
+	policy              *Policy
 	similarityThreshold float64
+	maxEntries          int
+	namespace           string
+}
+
+var logger = utils.SetUpLogger()
+
+func (d *DefaultCache) Decide(request *types.Completion, tokens func() int) Decision {
+	decision := d.config.policy.Decide(request, tokens)
+	decision.Scope.Namespace = d.config.namespace
+	return decision
+}
+
+func (d *DefaultCache) GetItem(ctx context.Context, request *types.Completion, decision Decision) (*CachedResponse, bool) {
+	key := exactKey(decision.Scope, request)
+
+	data, err := d.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			logger.Error("Failed to read from cache", zap.Error(err), zap.String("key", key))
+		}
+		return nil, false
+	}
+
+	var cached CachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		logger.Warn("Discarding unreadable cache entry", zap.Error(err), zap.String("key", key))
+		d.client.Del(ctx, key)
+		return nil, false
+	}
+
+	return &cached, true
+}
+
+func (d *DefaultCache) SetItem(ctx context.Context, request *types.Completion, decision Decision, response *CachedResponse) {
+	key := exactKey(decision.Scope, request)
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Failed to encode cache entry", zap.Error(err))
+		return
+	}
+
+	if err := d.client.Set(ctx, key, data, decision.TTL).Err(); err != nil {
+		logger.Error("Failed to write to cache", zap.Error(err), zap.String("key", key))
+	}
 }
 
-func (d *DefaultCache) GetItem() {}
+func (d *DefaultCache) Purge(ctx context.Context, scope Scope) (int, error) {
+	scope.Namespace = d.config.namespace
+	return deleteByPattern(ctx, d.client, scope.pattern("exact"))
+}
 
-func (d *DefaultCache) SetItem() {}
+func deleteByPattern(ctx context.Context, client *redis.Client, pattern string) (int, error) {
+	deleted := 0
+	iter := client.Scan(ctx, 0, pattern, 100).Iterator()
 
-func (s *SemanticCache) GetItem() {}
+	for iter.Next(ctx) {
+		if err := client.Del(ctx, iter.Val()).Err(); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
 
-func (s *SemanticCache) SetItem() {}
+	return deleted, iter.Err()
+}
 
 func NewRedisClient(config types.RedisData) *redis.Client {
 	return redis.NewClient(&redis.Options{
@@ -46,30 +113,44 @@ func NewRedisClient(config types.RedisData) *redis.Client {
 	})
 }
 
-func NewCacheClient(cfg types.CacheData, client *redis.Client) (Cache, error) {
-
-	semanticEnabled := cfg.Semantic["enabled"]
-	similarityThreshold := cfg.Semantic["similaritythreshold"]
+// NewCacheClient builds the response cache described by cfg.
+// The embedder is only used when semantic caching is enabled; without one the exact-match cache is used.
+// Keys are prefixed with namespace (if any), so tenants sharing a Redis server never share entries.
+func NewCacheClient(cfg types.CacheData, client *redis.Client, embedder Embedder, namespace string) (Cache, error) {
+	policy, err := NewPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	derivedSimilarityThreshold, _ := strconv.ParseFloat(similarityThreshold, 64)
+	similarityThreshold := cfg.Semantic.SimilarityThreshold
+	if similarityThreshold == 0 {
+		similarityThreshold = defaultSimilarityThreshold
+	}
 
-	if semanticEnabled == "1" {
-		return &SemanticCache{
-			client: client,
-			config: cacheConfig{
+	if cfg.Semantic.Enabled {
+		if embedder != nil {
+			return newSemanticCache(client, embedder, cacheConfig{
 				strategy:            "semantic",
-				similarityThreshold: derivedSimilarityThreshold,
-				ttl:                 cfg.Ttl,
-			},
-		}, nil
+				similarityThreshold: similarityThreshold,
+				policy:              policy,
+				maxEntries:          cfg.Semantic.MaxEntries,
+				namespace:           namespace,
+			}), nil
+		}
+		logger.Warn("Semantic caching enabled without an embedding model, falling back to exact match")
+	}
+
+	if client == nil {
+		return nil, fmt.Errorf("redis client is required for exact-match caching")
 	}
 
 	return &DefaultCache{
 		client: client,
 		config: cacheConfig{
 			strategy:            "default",
-			similarityThreshold: derivedSimilarityThreshold,
-			ttl:                 cfg.Ttl,
+			similarityThreshold: similarityThreshold,
+			policy:              policy,
+			namespace:           namespace,
 		},
 	}, nil
 }
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"fmt"
+	"llm-router/expr"
+	"llm-router/types"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Decision describes how a single request interacts with the cache.
+type Decision struct {
+	Cacheable bool
+	TTL       time.Duration // 0 keeps entries until they are evicted
+	Scope     Scope
+	Rule      string // Condition of the matching rule, for logging
+}
+
+// Policy evaluates the configured cache rules against incoming requests.
+// Rules are checked in order and the first match wins. When rules are configured
+// but none match, the request is not cached; without rules every request is cacheable.
+type Policy struct {
+	rules []cacheRule
+	ttl   time.Duration
+}
+
+type cacheRule struct {
+	condition *expr.Expression // nil matches every request
+	source    string
+	cache     bool
+	maxSize   int
+	ttl       time.Duration
+	perUser   bool
+}
+
+// NewPolicy compiles the rules in cfg. Conditions are validated again here so a
+// policy can never be built from rules that config.Validate would reject.
+func NewPolicy(cfg types.CacheData) (*Policy, error) {
+	policy := &Policy{ttl: seconds(cfg.Ttl)}
+
+	for i, rule := range cfg.Rules {
+		compiled := cacheRule{
+			source:  rule.If,
+			cache:   rule.Cache,
+			maxSize: rule.MaxSize,
+			ttl:     seconds(rule.Ttl),
+		}
+
+		switch rule.Scope {
+		case "", "shared":
+		case "user":
+			compiled.perUser = true
+		default:
+			return nil, fmt.Errorf("cache rule %d: unknown scope %q", i, rule.Scope)
+		}
+
+		if rule.If != "" {
+			condition, err := expr.Compile(rule.If, types.CacheRuleFields)
+			if err != nil {
+				return nil, fmt.Errorf("cache rule %d: %w", i, err)
+			}
+			compiled.condition = condition
+		}
+
+		policy.rules = append(policy.rules, compiled)
+	}
+
+	return policy, nil
+}
+
+// Decide picks the rule that applies to request. tokens is only called when a rule needs the prompt size.
+func (p *Policy) Decide(request *types.Completion, tokens func() int) Decision {
+	if p == nil || len(p.rules) == 0 {
+		decision := Decision{Cacheable: true, Scope: ScopeFor(request)}
+		if p != nil {
+			decision.TTL = p.ttl
+		}
+		return decision
+	}
+
+	promptTokens := -1
+	countTokens := func() int {
+		if promptTokens < 0 {
+			promptTokens = 0
+			if tokens != nil {
+				promptTokens = tokens()
+			}
+		}
+		return promptTokens
+	}
+
+	env := func(name string) any {
+		switch name {
+		case "request.temperature":
+			return request.Temperature
+		case "request.user":
+			return request.User
+		case "request.model":
+			return request.Model
+		case "request.tier":
+			return request.Tier
+		case "request.tokens":
+			return countTokens()
+		}
+		return nil
+	}
+
+	for _, rule := range p.rules {
+		if rule.condition != nil {
+			matched, err := rule.condition.Eval(env)
+			if err != nil {
+				logger.Warn("Skipping cache rule that failed to evaluate", zap.Error(err))
+				continue
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		if rule.maxSize > 0 && countTokens() > rule.maxSize {
+			continue
+		}
+
+		decision := Decision{Cacheable: rule.cache, TTL: p.ttl, Scope: ScopeFor(request), Rule: rule.source}
+		if rule.ttl > 0 {
+			decision.TTL = rule.ttl
+		}
+
+		if rule.perUser {
+			// Without a user there is nothing to keep the entries private to
+			if request.User == "" {
+				decision.Cacheable = false
+			}
+			decision.Scope = userScope(request)
+		}
+
+		return decision
+	}
+
+	return Decision{Cacheable: false, Scope: ScopeFor(request)}
+}
+
+func seconds(value int) time.Duration {
+	if value <= 0 {
+		return 0
+	}
+	return time.Duration(value) * time.Second
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"llm-router/types"
+	"testing"
+	"time"
+)
+
+func TestPolicy_Decide(t *testing.T) {
+	policy, err := NewPolicy(types.CacheData{
+		Ttl: 3600,
+		Rules: []types.CacheRule{
+			{If: `request.user == "premium"`, Cache: false},
+			{If: "request.temperature == 0", Cache: true, Ttl: 60},
+			{If: `request.tier == "budget"`, Cache: true, Scope: "user"},
+			{Cache: true, MaxSize: 1024},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+
+	zero := 0.0
+	request := func(user string, tier string, temperature *float64) *types.Completion {
+		return &types.Completion{
+			Messages:    []types.Message{{Role: "user", Content: "hello"}},
+			User:        user,
+			Tier:        tier,
+			Temperature: temperature,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		request       *types.Completion
+		tokens        int
+		wantCacheable bool
+		wantTTL       time.Duration
+		wantPerUser   bool
+	}{
+		{name: "premium user bypasses", request: request("premium", "", &zero), tokens: 10},
+		{name: "deterministic request uses rule ttl", request: request("alice", "", &zero), tokens: 5000, wantCacheable: true, wantTTL: time.Minute},
+		{name: "budget tier is scoped per user", request: request("alice", "budget", nil), tokens: 10, wantCacheable: true, wantTTL: time.Hour, wantPerUser: true},
+		{name: "per user rule without a user bypasses", request: request("", "budget", nil), tokens: 10, wantPerUser: true},
+		{name: "small prompt uses default ttl", request: request("alice", "", nil), tokens: 1024, wantCacheable: true, wantTTL: time.Hour},
+		{name: "large prompt matches no rule", request: request("alice", "", nil), tokens: 1025},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := policy.Decide(tt.request, func() int { return tt.tokens })
+
+			if decision.Cacheable != tt.wantCacheable {
+				t.Errorf("Cacheable = %v, want %v", decision.Cacheable, tt.wantCacheable)
+			}
+			if tt.wantCacheable && decision.TTL != tt.wantTTL {
+				t.Errorf("TTL = %v, want %v", decision.TTL, tt.wantTTL)
+			}
+			if perUser := decision.Scope.Partition != sharedPartition; perUser != tt.wantPerUser {
+				t.Errorf("Scope.Partition = %q, want per user %v", decision.Scope.Partition, tt.wantPerUser)
+			}
+		})
+	}
+}
+
+func TestPolicy_NoRulesCachesEverything(t *testing.T) {
+	policy, err := NewPolicy(types.CacheData{Ttl: 30})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+
+	decision := policy.Decide(userRequest("hello", "premium"), nil)
+	if !decision.Cacheable || decision.TTL != 30*time.Second {
+		t.Errorf("Decide() = %+v, want cacheable with a 30s ttl", decision)
+	}
+}
@@ -0,0 +1,404 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"llm-router/cmd/internal/embeddings"
+	"llm-router/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSimilarityThreshold = 0.95
+	defaultMaxEntries          = 1000
+)
+
+// Embedder turns a prompt into a vector. It is satisfied by the MiniLM model used for semantic routing.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// SemanticCache serves a cached answer when a new prompt is close enough in meaning to a previous one.
+// Exact matches are still checked first since they are cheaper than running the embedding model.
+//
+// Entries are only compared within the same scope (model and tier) and conversation context,
+// so only the final prompt is matched semantically. Vectors live in redis and fall back to an
+// in-process index whenever redis is unavailable.
+type SemanticCache struct {
+	client   *redis.Client
+	config   cacheConfig
+	embedder Embedder
+	exact    *DefaultCache
+	local    *memoryIndex
+}
+
+type semanticEntry struct {
+	Vector   []float32      `json:"vector"`
+	Response CachedResponse `json:"response"`
+}
+
+func newSemanticCache(client *redis.Client, embedder Embedder, config cacheConfig) *SemanticCache {
+	if config.maxEntries <= 0 {
+		config.maxEntries = defaultMaxEntries
+	}
+
+	s := &SemanticCache{
+		client:   client,
+		config:   config,
+		embedder: embedder,
+		local:    newMemoryIndex(config.maxEntries),
+	}
+
+	if client != nil {
+		s.exact = &DefaultCache{client: client, config: config}
+	}
+
+	return s
+}
+
+func (s *SemanticCache) Decide(request *types.Completion, tokens func() int) Decision {
+	decision := s.config.policy.Decide(request, tokens)
+	decision.Scope.Namespace = s.config.namespace
+	return decision
+}
+
+func (s *SemanticCache) GetItem(ctx context.Context, request *types.Completion, decision Decision) (*CachedResponse, bool) {
+	if s.exact != nil {
+		if cached, ok := s.exact.GetItem(ctx, request, decision); ok {
+			return cached, true
+		}
+	}
+
+	if !semanticallyComparable(request) {
+		return nil, false
+	}
+
+	vector, err := s.embedPrompt(ctx, request)
+	if err != nil {
+		logger.Warn("Failed to embed prompt for semantic cache lookup", zap.Error(err))
+		return nil, false
+	}
+
+	scope := decision.Scope
+	group := semanticGroupKey(scope, request)
+
+	var candidates []semanticEntry
+	if s.client != nil {
+		candidates, err = s.loadEntries(ctx, group)
+		if err != nil {
+			logger.Warn("Semantic cache unavailable in redis, using in-process index", zap.Error(err))
+			candidates = s.local.entries(group)
+		}
+	} else {
+		candidates = s.local.entries(group)
+	}
+
+	var best *semanticEntry
+	bestScore := -1.0
+
+	for i := range candidates {
+		score := embeddings.CosineSimilarity(vector, candidates[i].Vector)
+		if score > bestScore {
+			bestScore = score
+			best = &candidates[i]
+		}
+	}
+
+	if best == nil || bestScore < s.config.similarityThreshold {
+		return nil, false
+	}
+
+	logger.Debug("Semantic cache match found",
+		zap.Float64("similarity", bestScore),
+		zap.Float64("threshold", s.config.similarityThreshold),
+		zap.String("tier", scope.Tier),
+		zap.String("model", scope.Model),
+	)
+
+	return &best.Response, true
+}
+
+func (s *SemanticCache) SetItem(ctx context.Context, request *types.Completion, decision Decision, response *CachedResponse) {
+	if s.exact != nil {
+		s.exact.SetItem(ctx, request, decision, response)
+	}
+
+	if !semanticallyComparable(request) {
+		return
+	}
+
+	vector, err := s.embedPrompt(ctx, request)
+	if err != nil {
+		logger.Warn("Failed to embed prompt for semantic cache", zap.Error(err))
+		return
+	}
+
+	entry := semanticEntry{Vector: vector, Response: *response}
+	group := semanticGroupKey(decision.Scope, request)
+	id := HashRequest(request)
+
+	if s.client != nil {
+		err := s.storeEntry(ctx, group, id, entry, decision.TTL)
+		if err == nil {
+			return
+		}
+		logger.Warn("Failed to store semantic cache entry in redis, using in-process index", zap.Error(err))
+	}
+
+	s.local.add(group, id, entry, decision.TTL)
+}
+
+func (s *SemanticCache) Purge(ctx context.Context, scope Scope) (int, error) {
+	scope.Namespace = s.config.namespace
+	purged := s.local.purge(scope.pattern("semantic"))
+
+	if s.client == nil {
+		return purged, nil
+	}
+
+	exactPurged, err := s.exact.Purge(ctx, scope)
+	purged += exactPurged
+	if err != nil {
+		return purged, err
+	}
+
+	redisPurged, err := deleteByPattern(ctx, s.client, scope.pattern("semantic"))
+	return purged + redisPurged, err
+}
+
+// embedPrompt embeds the final message of the request; earlier messages are part of the group key instead.
+func (s *SemanticCache) embedPrompt(ctx context.Context, request *types.Completion) ([]float32, error) {
+	prompt := ""
+	if len(request.Messages) > 0 {
+		prompt = strings.TrimSpace(request.Messages[len(request.Messages)-1].Content)
+	}
+	return s.embedder.Embed(ctx, prompt)
+}
+
+// semanticallyComparable reports whether the request's prompt can be matched by meaning. Only the
+// prompt's text is embedded, so prompts with images or other media are only ever matched exactly.
+func semanticallyComparable(request *types.Completion) bool {
+	return len(request.Messages) == 0 || !request.Messages[len(request.Messages)-1].HasMedia()
+}
+
+func semanticGroupKey(scope Scope, request *types.Completion) string {
+	return scope.prefix("semantic") + ":" + contextHash(request)
+}
+
+func (s *SemanticCache) loadEntries(ctx context.Context, group string) ([]semanticEntry, error) {
+	indexKey := group + ":index"
+
+	ids, err := s.client.SMembers(ctx, indexKey).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = group + ":entry:" + id
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]semanticEntry, 0, len(values))
+	var expired []interface{}
+
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			// The entry expired but its id is still indexed
+			expired = append(expired, ids[i])
+			continue
+		}
+
+		var entry semanticEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			expired = append(expired, ids[i])
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(expired) > 0 {
+		s.client.SRem(ctx, indexKey, expired...)
+	}
+
+	return entries, nil
+}
+
+func (s *SemanticCache) storeEntry(ctx context.Context, group string, id string, entry semanticEntry, expiration time.Duration) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	indexKey := group + ":index"
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, group+":entry:"+id, data, expiration)
+	pipe.SAdd(ctx, indexKey, id)
+	if expiration > 0 {
+		pipe.Expire(ctx, indexKey, expiration)
+	}
+	size := pipe.SCard(ctx, indexKey)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	// Keep each group bounded by evicting random entries
+	for excess := size.Val() - int64(s.config.maxEntries); excess > 0; excess-- {
+		evicted, err := s.client.SPop(ctx, indexKey).Result()
+		if err != nil {
+			break
+		}
+		s.client.Del(ctx, group+":entry:"+evicted)
+	}
+
+	return nil
+}
+
+// memoryIndexSweepInterval is how often adding an entry also removes expired entries from every
+// group, so groups that are no longer written to don't keep them forever.
+const memoryIndexSweepInterval = time.Minute
+
+// memoryIndex is the in-process fallback used when redis cannot be reached.
+type memoryIndex struct {
+	mu         sync.RWMutex
+	groups     map[string]map[string]memoryEntry
+	maxEntries int
+	lastSweep  time.Time
+}
+
+type memoryEntry struct {
+	entry     semanticEntry
+	expiresAt time.Time
+}
+
+func newMemoryIndex(maxEntries int) *memoryIndex {
+	return &memoryIndex{
+		groups:     make(map[string]map[string]memoryEntry),
+		maxEntries: maxEntries,
+	}
+}
+
+func (m *memoryIndex) add(group string, id string, entry semanticEntry, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) >= memoryIndexSweepInterval {
+		m.sweep(now)
+	}
+
+	entries, ok := m.groups[group]
+	if !ok {
+		entries = make(map[string]memoryEntry)
+		m.groups[group] = entries
+	}
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
+	}
+
+	// Expired entries go first, so they never push out live ones
+	removeExpired(entries, now)
+
+	// Map iteration order is random, which gives the same random eviction as redis SPOP
+	for evictID := range entries {
+		if len(entries) < m.maxEntries {
+			break
+		}
+		delete(entries, evictID)
+	}
+
+	entries[id] = memoryEntry{entry: entry, expiresAt: expiresAt}
+}
+
+// sweep removes expired entries from every group and deletes the groups left empty. The caller
+// must hold the write lock.
+func (m *memoryIndex) sweep(now time.Time) {
+	for group, entries := range m.groups {
+		removeExpired(entries, now)
+		if len(entries) == 0 {
+			delete(m.groups, group)
+		}
+	}
+	m.lastSweep = now
+}
+
+func removeExpired(entries map[string]memoryEntry, now time.Time) {
+	for id, e := range entries {
+		if e.expired(now) {
+			delete(entries, id)
+		}
+	}
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+func (m *memoryIndex) entries(group string) []semanticEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	var result []semanticEntry
+
+	for _, e := range m.groups[group] {
+		if e.expired(now) {
+			continue
+		}
+		result = append(result, e.entry)
+	}
+
+	return result
+}
+
+// purge removes every group matching a scope pattern and returns how many entries were dropped.
+func (m *memoryIndex) purge(pattern string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purged := 0
+	for group, entries := range m.groups {
+		if matchPattern(pattern, group+":") {
+			purged += len(entries)
+			delete(m.groups, group)
+		}
+	}
+
+	return purged
+}
+
+// matchPattern supports the subset of redis glob syntax produced by Scope.pattern ('*' wildcards).
+func matchPattern(pattern string, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+
+	for i := 1; i < len(parts); i++ {
+		if i == len(parts)-1 && parts[i] == "" {
+			return true
+		}
+		idx := strings.Index(value, parts[i])
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(parts[i]):]
+	}
+
+	return value == ""
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"llm-router/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keywordEmbedder maps prompts onto two axes so similarity is predictable in tests.
+type keywordEmbedder struct{}
+
+func (keywordEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	text = strings.ToLower(text)
+	vector := []float32{0.01, 0.01}
+	if strings.Contains(text, "france") {
+		vector[0] = 1
+	}
+	if strings.Contains(text, "joke") {
+		vector[1] = 1
+	}
+	return vector, nil
+}
+
+func userRequest(content string, tier string) *types.Completion {
+	return &types.Completion{
+		Messages: []types.Message{{Role: "user", Content: content}},
+		Tier:     tier,
+	}
+}
+
+func setItem(ctx context.Context, c Cache, request *types.Completion, response *CachedResponse) {
+	c.SetItem(ctx, request, c.Decide(request, nil), response)
+}
+
+func getItem(ctx context.Context, c Cache, request *types.Completion) (*CachedResponse, bool) {
+	return c.GetItem(ctx, request, c.Decide(request, nil))
+}
+
+func TestSemanticCache_InProcessFallback(t *testing.T) {
+	ctx := context.Background()
+	c := newSemanticCache(nil, keywordEmbedder{}, cacheConfig{similarityThreshold: 0.9})
+
+	setItem(ctx, c, userRequest("What is the capital of France?", "premium"), &CachedResponse{
+		Response: types.CompletionResponse{Message: types.Message{Role: "assistant", Content: "Paris"}},
+		Provider: "openai",
+	})
+
+	tests := []struct {
+		name    string
+		request *types.Completion
+		wantHit bool
+	}{
+		{
+			name:    "similar prompt in same tier",
+			request: userRequest("tell me the capital city of france", "premium"),
+			wantHit: true,
+		},
+		{
+			name:    "similar prompt in different tier",
+			request: userRequest("tell me the capital city of france", "budget"),
+			wantHit: false,
+		},
+		{
+			name:    "unrelated prompt",
+			request: userRequest("tell me a joke", "premium"),
+			wantHit: false,
+		},
+		{
+			name: "similar prompt in different conversation",
+			request: &types.Completion{
+				Messages: []types.Message{
+					{Role: "system", Content: "Answer in French"},
+					{Role: "user", Content: "What is the capital of France?"},
+				},
+				Tier: "premium",
+			},
+			wantHit: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached, hit := getItem(ctx, c, tt.request)
+			if hit != tt.wantHit {
+				t.Fatalf("GetItem() hit = %v, want %v", hit, tt.wantHit)
+			}
+			if hit && cached.Response.Message.Content != "Paris" {
+				t.Errorf("unexpected cached content %q", cached.Response.Message.Content)
+			}
+		})
+	}
+}
+
+func TestSemanticCache_Purge(t *testing.T) {
+	ctx := context.Background()
+	c := newSemanticCache(nil, keywordEmbedder{}, cacheConfig{similarityThreshold: 0.9})
+
+	setItem(ctx, c, userRequest("capital of france", "premium"), &CachedResponse{Provider: "openai"})
+	setItem(ctx, c, userRequest("capital of france", "budget"), &CachedResponse{Provider: "gemini"})
+
+	purged, err := c.Purge(ctx, Scope{Tier: "budget"})
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Purge() purged %d entries, want 1", purged)
+	}
+
+	if _, hit := getItem(ctx, c, userRequest("capital of france", "budget")); hit {
+		t.Error("budget entry should have been purged")
+	}
+	if _, hit := getItem(ctx, c, userRequest("capital of france", "premium")); !hit {
+		t.Error("premium entry should have survived a budget purge")
+	}
+}
+
+func TestMemoryIndex_Expiry(t *testing.T) {
+	index := newMemoryIndex(2)
+
+	index.add("live", "short", semanticEntry{Response: CachedResponse{Provider: "short"}}, time.Millisecond)
+	index.add("live", "long", semanticEntry{Response: CachedResponse{Provider: "long"}}, 0)
+	index.add("stale", "short", semanticEntry{Response: CachedResponse{Provider: "short"}}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	// The expired entry makes room rather than a random live one
+	index.lastSweep = time.Time{}
+	index.add("live", "new", semanticEntry{Response: CachedResponse{Provider: "new"}}, 0)
+
+	if got := len(index.entries("live")); got != 2 {
+		t.Errorf("entries() returned %d entries, want the 2 live ones", got)
+	}
+	if _, ok := index.groups["live"]["long"]; !ok {
+		t.Error("live entry was evicted while an expired one was kept")
+	}
+	if _, ok := index.groups["stale"]; ok {
+		t.Error("group with only expired entries was not removed")
+	}
+}
@@ -0,0 +1,300 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/pretrained"
+	ort "github.com/yalue/onnxruntime_go"
+	"go.uber.org/zap"
+)
+
+// Constants for the MiniLM-L6-v2 model
+const (
+	MaxSeqLength = 128 // Transformer input limit
+	EmbeddingDim = 384 // MiniLM output vector size
+)
+
+// Model wraps a local MiniLM ONNX session and turns text into embeddings.
+// A single session is not safe for concurrent inference, so calls are serialized.
+type Model struct {
+	tokenizer *tokenizer.Tokenizer
+	session   *ort.AdvancedSession
+	mu        sync.Mutex
+	logger    *zap.Logger
+
+	// Pre-allocated buffers for inference (optimized for performance)
+	inputIds      []int64
+	attentionMask []int64
+	tokenTypeIds  []int64
+	outputData    []float32
+}
+
+var (
+	sharedModels = make(map[string]*Model)
+	sharedMu     sync.Mutex
+)
+
+// Shared returns the process-wide model loaded from modelPath, loading it on first use.
+// Semantic routing and the semantic cache both go through here so the model is only loaded once.
+func Shared(modelPath string, sharedLibPath string, logger *zap.Logger) (*Model, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if model, ok := sharedModels[modelPath]; ok {
+		return model, nil
+	}
+
+	model, err := NewModel(modelPath, sharedLibPath, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedModels[modelPath] = model
+	return model, nil
+}
+
+// NewModel initializes the ONNX runtime environment and loads the model at modelPath.
+func NewModel(modelPath string, sharedLibPath string, logger *zap.Logger) (*Model, error) {
+	// 1. Initialize ONNX runtime environment
+	if !ort.IsInitialized() {
+		libPath := resolveLibPath(sharedLibPath, logger)
+		if libPath != "" {
+			ort.SetSharedLibraryPath(libPath)
+		}
+
+		err := ort.InitializeEnvironment()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize onnxruntime: %w", err)
+		}
+	}
+
+	m := &Model{
+		inputIds:      make([]int64, MaxSeqLength),
+		attentionMask: make([]int64, MaxSeqLength),
+		tokenTypeIds:  make([]int64, MaxSeqLength),
+		outputData:    make([]float32, MaxSeqLength*EmbeddingDim),
+		logger:        logger,
+	}
+
+	if err := m.load(modelPath); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// resolveLibPath finds the best ONNX shared library path based on priority:
+// 1. Environment variable (ONNXRUNTIME_LIB_PATH)
+// 2. Configuration file (shared_lib_path)
+// 3. Known system locations (e.g. Homebrew)
+func resolveLibPath(configPath string, logger *zap.Logger) string {
+	// 1. Try Env Var
+	if env := os.Getenv("ONNXRUNTIME_LIB_PATH"); env != "" {
+		if logger != nil {
+			logger.Debug("Using ONNX library from environment variable", zap.String("path", env))
+		}
+		return env
+	}
+
+	// 2. Try Config
+	if configPath != "" {
+		if _, err := os.Stat(configPath); err == nil {
+			if logger != nil {
+				logger.Debug("Using ONNX library from config", zap.String("path", configPath))
+			}
+			return configPath
+		}
+	}
+
+	// 3. Known System Paths (macOS/Linux)
+	defaults := []string{
+		"/usr/local/lib/libonnxruntime.dylib",
+		"/opt/homebrew/lib/libonnxruntime.dylib",
+		"/usr/lib/libonnxruntime.so",
+	}
+	for _, p := range defaults {
+		if _, err := os.Stat(p); err == nil {
+			if logger != nil {
+				logger.Debug("Using ONNX library from default path", zap.String("path", p))
+			}
+			return p
+		}
+	}
+
+	return ""
+}
+
+// load prepares the tokenizer and ONNX inference session.
+func (m *Model) load(path string) error {
+	// Resolve relative model path
+	finalPath := path
+	if _, err := os.Stat(finalPath); os.IsNotExist(err) && !filepath.IsAbs(finalPath) {
+		tryPaths := []string{
+			filepath.Join("..", "..", path),
+			filepath.Join("..", path),
+		}
+		for _, tp := range tryPaths {
+			if _, err := os.Stat(tp); err == nil {
+				finalPath = tp
+				break
+			}
+		}
+	}
+
+	// 1. Load Tokenizer (custom tokenizer.json if present)
+	tokenizerPath := filepath.Join(filepath.Dir(finalPath), "tokenizer.json")
+	if _, err := os.Stat(tokenizerPath); err == nil {
+		m.logger.Debug("Loading tokenizer from file", zap.String("path", tokenizerPath), zap.String("finalPath", finalPath))
+
+		fileInfo, err := os.Stat(tokenizerPath)
+		if err != nil {
+			m.logger.Error("Cannot stat tokenizer file", zap.Error(err))
+		}
+		m.logger.Debug("Tokenizer file info",
+			zap.Int64("size", fileInfo.Size()),
+			zap.String("mode", fileInfo.Mode().String()))
+
+		tk := tokenizer.NewTokenizerFromFile(tokenizerPath)
+		if tk != nil {
+			m.tokenizer = tk
+		} else {
+			m.tokenizer = pretrained.BertBaseUncased()
+			m.logger.Debug("failed to load tokenizer from file", zap.String("path", tokenizerPath))
+		}
+	} else {
+		return fmt.Errorf("tokenizer.json not found at %s. This file is required for embeddings", tokenizerPath)
+	}
+
+	// 2. Bind Tensors to Buffers
+	shape := ort.NewShape(1, MaxSeqLength)
+	inputIdsTensor, _ := ort.NewTensor(shape, m.inputIds)
+	maskTensor, _ := ort.NewTensor(shape, m.attentionMask)
+	typeIdsTensor, _ := ort.NewTensor(shape, m.tokenTypeIds)
+	outputShape := ort.NewShape(1, MaxSeqLength, EmbeddingDim)
+	outputTensor, _ := ort.NewTensor(outputShape, m.outputData)
+
+	// 3. Create Session
+	session, err := ort.NewAdvancedSession(finalPath,
+		[]string{"input_ids", "attention_mask", "token_type_ids"},
+		[]string{"last_hidden_state"},
+		[]ort.Value{inputIdsTensor, maskTensor, typeIdsTensor},
+		[]ort.Value{outputTensor},
+		nil)
+	if err != nil {
+		return fmt.Errorf("failed to create onnx session: %w", err)
+	}
+	m.session = session
+
+	return nil
+}
+
+// Embed processes a string through the neural network.
+func (m *Model) Embed(ctx context.Context, text string) ([]float32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 1. Tokenization: Break text into sub-words (WordPiece)
+	en, err := m.tokenizer.EncodeSingle(text, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := en.GetIds()
+	mask := en.GetAttentionMask()
+	typeIds := en.GetTypeIds()
+
+	// Ensure BERT special tokens are present ([CLS] at 0, [SEP] at end)
+	if len(ids) > 0 && ids[0] != 101 {
+		newIds := []int{101}
+		newIds = append(newIds, ids...)
+		if newIds[len(newIds)-1] != 102 {
+			newIds = append(newIds, 102)
+		}
+		ids = newIds
+		// Resync mask
+		mask = make([]int, len(ids))
+		for i := range mask {
+			mask[i] = 1
+		}
+		typeIds = make([]int, len(ids))
+	}
+
+	// 2. Data Preparation: Fill ONNX buffers with padding
+	for i := 0; i < MaxSeqLength; i++ {
+		if i < len(ids) {
+			m.inputIds[i] = int64(ids[i])
+			m.attentionMask[i] = int64(mask[i])
+			m.tokenTypeIds[i] = int64(typeIds[i])
+		} else {
+			m.inputIds[i] = 0 // [PAD] id
+			m.attentionMask[i] = 0
+			m.tokenTypeIds[i] = 0
+		}
+	}
+
+	// 3. Inference: Run the Transformer model
+	err = m.session.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Mean Pooling: Average the vectors of all tokens in the sentence
+	// This reduces a matrix (Words x Dimensions) into a single vector (Dimensions).
+	embedding := make([]float32, EmbeddingDim)
+	var validTokens float32
+
+	for i := 0; i < MaxSeqLength; i++ {
+		if i < len(ids) {
+			validTokens++
+			for d := 0; d < EmbeddingDim; d++ {
+				embedding[d] += m.outputData[i*EmbeddingDim+d]
+			}
+		}
+	}
+
+	if validTokens > 0 {
+		for d := 0; d < EmbeddingDim; d++ {
+			embedding[d] /= validTokens
+		}
+	}
+
+	// Calculate magnitude for debug
+	var mag float64
+	for _, v := range embedding {
+		mag += float64(v) * float64(v)
+	}
+	magnitude := math.Sqrt(mag)
+
+	m.logger.Debug("Point embedding calculated",
+		zap.String("text", text),
+		zap.Int("tokens", len(ids)),
+		zap.Float64("magnitude", magnitude),
+	)
+
+	return embedding, nil
+}
+
+// CosineSimilarity measures how "aligned" two vectors are.
+// Scores range from -1.0 (opposite) to 1.0 (identical).
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
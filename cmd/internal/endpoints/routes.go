@@ -3,33 +3,51 @@ package endpoints
 import (
 	"llm-router/cmd/internal/app"
 	"llm-router/cmd/internal/handlers"
+	"llm-router/cmd/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetUpRoutes(resolver app.ConfigResolver, ginRouter *gin.Engine) {
+// SetUpRoutes registers the endpoints. auth authenticates the API and admin endpoints; /health
+// is left open for load balancer probes.
+func SetUpRoutes(resolvers app.RequestResolver, ginRouter *gin.Engine, auth ...gin.HandlerFunc) {
 
 	ginRouter.GET("/health", func(c *gin.Context) {
-		handlers.Health(resolver, c)
+		handlers.Health(resolvers.Resolve(c), c)
 	})
 
-	ginRouter.POST("/v1/chat/completions", func(c *gin.Context) {
-		handlers.Completions(resolver, c)
-	})
+	api := ginRouter.Group("/v1", auth...)
 
-	ginRouter.GET("/admin/usage", func(c *gin.Context) {
-		handlers.GetUsageHistory(resolver, c)
-	})
+	api.POST("/chat/completions", handle(resolvers, handlers.Completions))
 
-	ginRouter.GET("/admin/status", func(c *gin.Context) {
-		handlers.GetSystemStatus(resolver, c)
-	})
+	api.POST("/messages", handle(resolvers, handlers.Messages))
 
-	ginRouter.POST("/admin/budgets/reset", func(c *gin.Context) {
-		handlers.ResetBudget(resolver, c)
-	})
+	api.POST("/embeddings", handle(resolvers, handlers.Embeddings))
 
-	ginRouter.POST("/admin/config/reload", func(c *gin.Context) {
-		handlers.ReloadConfig(resolver, c)
-	})
+	admin := ginRouter.Group("/admin", auth...)
+	admin.Use(middleware.RequireAdmin())
+
+	admin.GET("/usage", handle(resolvers, handlers.GetUsageHistory))
+
+	admin.GET("/experiments", handle(resolvers, handlers.GetExperiments))
+
+	admin.GET("/status", handle(resolvers, handlers.GetSystemStatus))
+
+	admin.POST("/budgets/reset", handle(resolvers, handlers.ResetBudget))
+
+	admin.POST("/cache/purge", handle(resolvers, handlers.PurgeCache))
+
+	admin.POST("/config/reload", handle(resolvers, handlers.ReloadConfig))
+}
+
+// handle runs handler with the resolver serving the request (the tenant's, in multi-tenant mode).
+func handle(resolvers app.RequestResolver, handler func(app.ConfigResolver, *gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resolver := resolvers.Resolve(c)
+		if resolver == nil {
+			middleware.Unauthorized(c, "Authorization header is required")
+			return
+		}
+		handler(resolver, c)
+	}
 }
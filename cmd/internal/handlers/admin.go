@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"llm-router/cmd/internal/app"
+	"llm-router/cmd/internal/cache"
+	"llm-router/cmd/internal/router"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -35,9 +38,81 @@ func GetUsageHistory(resolver app.ConfigResolver, c *gin.Context) {
 		return
 	}
 
+	consumerStats, err := usageHistory.GetDailyConsumerUsage(ctx, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch usage history",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"date":  date,
-		"usage": stats,
+		"date":      date,
+		"usage":     stats,
+		"consumers": consumerStats,
+	})
+}
+
+// GetExperiments compares the variants of each configured experiment. Latency and error rate
+// cover the requests assigned to a variant; cost covers what its requests spent upstream.
+func GetExperiments(resolver app.ConfigResolver, c *gin.Context) {
+	usageHistory := resolver.GetRouter().GetUsageHistoryManager()
+	if usageHistory == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"error": "Usage history is not enabled",
+		})
+		return
+	}
+
+	stats, err := usageHistory.GetExperimentStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch experiment results",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	experiments := make([]gin.H, 0, len(resolver.GetConfig().Experiments))
+	for _, experiment := range resolver.GetConfig().Experiments {
+		variants := make(gin.H, len(experiment.Variants))
+		for name, variant := range experiment.Variants {
+			results := stats[experiment.Name][name]
+			if results == nil {
+				results = &router.ExperimentStats{}
+			}
+
+			comparison := gin.H{
+				"provider":       variant.Provider,
+				"model":          variant.Model,
+				"requests":       results.Requests,
+				"errors":         results.Errors,
+				"error_rate":     0.0,
+				"avg_latency_ms": 0.0,
+				"cost_usd":       results.CostUSD,
+				"avg_cost_usd":   0.0,
+				"input_tokens":   results.InputTokens,
+				"output_tokens":  results.OutputTokens,
+			}
+			if results.Requests > 0 {
+				comparison["error_rate"] = float64(results.Errors) / float64(results.Requests)
+				comparison["avg_latency_ms"] = float64(results.LatencyMs) / float64(results.Requests)
+				comparison["avg_cost_usd"] = results.CostUSD / float64(results.Requests)
+			}
+			variants[name] = comparison
+		}
+
+		experiments = append(experiments, gin.H{
+			"name":     experiment.Name,
+			"enabled":  experiment.Enabled,
+			"traffic":  experiment.Traffic,
+			"variants": variants,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"experiments": experiments,
 	})
 }
 
@@ -71,6 +146,7 @@ func GetSystemStatus(resolver app.ConfigResolver, c *gin.Context) {
 			"strategy": resolver.GetConfig().Routing.Strategy,
 		},
 		"circuit_breakers": cbStates,
+		"api_keys":         resolver.GetProviderManager().KeyUsage(),
 		"timestamp":        time.Now(),
 	})
 }
@@ -95,3 +171,33 @@ func ResetBudget(resolver app.ConfigResolver, c *gin.Context) {
 		"provider": provider,
 	})
 }
+
+func PurgeCache(resolver app.ConfigResolver, c *gin.Context) {
+	responseCache := resolver.GetCache()
+	if responseCache == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Response caching is not enabled"})
+		return
+	}
+
+	scope := cache.Scope{
+		Model: strings.ToLower(c.Query("model")),
+		Tier:  strings.ToLower(c.Query("tier")),
+	}
+
+	purged, err := responseCache.Purge(c.Request.Context(), scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to purge cache",
+			"details": err.Error(),
+			"purged":  purged,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "Cache purged successfully",
+		"model":  scope.Model,
+		"tier":   scope.Tier,
+		"purged": purged,
+	})
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"llm-router/cmd/internal/app"
+	"llm-router/types"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Messages serves the Anthropic Messages API. Requests go through the same pipeline as
+// /v1/chat/completions, whichever provider ends up serving them.
+func Messages(resolver app.ConfigResolver, c *gin.Context) {
+	var request types.AnthropicMessagesRequest
+	format := anthropicFormat{}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		message, _ := describeValidationError(err)
+		format.writeError(c, http.StatusBadRequest, errorInvalidRequest, message)
+		return
+	}
+
+	if len(request.StopSequences) > 0 {
+		format.writeError(c, http.StatusBadRequest, errorInvalidRequest, "stop_sequences is not supported")
+		return
+	}
+
+	if len(request.Tools) > 0 {
+		format.writeError(c, http.StatusBadRequest, errorInvalidRequest, "tools are not supported on /v1/messages, use /v1/chat/completions")
+		return
+	}
+
+	runCompletion(resolver, c, request.ToCompletion(), format)
+}
+
+// anthropicFormat renders completions in the Anthropic Messages format.
+type anthropicFormat struct{}
+
+func (anthropicFormat) writeError(c *gin.Context, status int, kind errorKind, message string) {
+	errType := "invalid_request_error"
+	switch kind {
+	case errorRateLimit, errorQuota:
+		errType = "rate_limit_error"
+	case errorPermission:
+		errType = "permission_error"
+	case errorUnavailable:
+		errType = "overloaded_error"
+	case errorUpstream:
+		errType = "api_error"
+	}
+
+	c.JSON(status, types.NewAnthropicErrorResponse(errType, message))
+}
+
+func (anthropicFormat) writeCompletion(c *gin.Context, response *types.CompletionResponse, providerName string, model string, cached bool) {
+	if response.Model != "" {
+		model = response.Model
+	}
+
+	stopReason := types.AnthropicStopReason(response.FinishReason)
+
+	setOctoHeaders(c, providerName, model)
+	c.Header(octoCostHeader, strconv.FormatFloat(response.CostUSD, 'f', -1, 64))
+
+	c.JSON(http.StatusOK, types.AnthropicMessageResponse{
+		ID:         newResponseID("msg_"),
+		Type:       "message",
+		Role:       "assistant",
+		Model:      model,
+		Content:    []types.AnthropicContentBlock{{Type: "text", Text: response.Message.Content}},
+		StopReason: &stopReason,
+		Usage: types.AnthropicUsage{
+			InputTokens:  response.Usage.PromptTokens,
+			OutputTokens: response.Usage.CompletionTokens,
+		},
+		Octo: &types.OctoExtension{
+			Provider: providerName,
+			CostUSD:  response.CostUSD,
+			Cached:   cached,
+		},
+	})
+}
+
+func (anthropicFormat) newStream(c *gin.Context, request types.Completion, providerName string, model string) completionStream {
+	return &anthropicStream{
+		c:            c,
+		id:           newResponseID("msg_"),
+		model:        model,
+		providerName: providerName,
+	}
+}
+
+// anthropicStream writes the Anthropic streaming event sequence for a single text block:
+// message_start, content_block_start, content_block_delta..., content_block_stop, message_delta, message_stop.
+type anthropicStream struct {
+	c            *gin.Context
+	id           string
+	model        string
+	providerName string
+}
+
+func (s *anthropicStream) write(event string, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.c.Writer, "event: %s\ndata: %s\n\n", event, data)
+	s.c.Writer.Flush()
+}
+
+func (s *anthropicStream) start() {
+	setSSEHeaders(s.c)
+	setOctoHeaders(s.c, s.providerName, s.model)
+
+	s.write("message_start", gin.H{
+		"type": "message_start",
+		"message": types.AnthropicMessageResponse{
+			ID:      s.id,
+			Type:    "message",
+			Role:    "assistant",
+			Model:   s.model,
+			Content: []types.AnthropicContentBlock{},
+		},
+	})
+	s.write("content_block_start", gin.H{
+		"type":          "content_block_start",
+		"index":         0,
+		"content_block": types.AnthropicContentBlock{Type: "text", Text: ""},
+	})
+}
+
+func (s *anthropicStream) delta(content string) {
+	s.write("content_block_delta", gin.H{
+		"type":  "content_block_delta",
+		"index": 0,
+		"delta": gin.H{"type": "text_delta", "text": content},
+	})
+}
+
+// toolCalls is a no-op: Messages rejects tool definitions, so its requests never produce tool calls.
+func (s *anthropicStream) toolCalls(deltas []types.ToolCallDelta) {}
+
+func (s *anthropicStream) finish(chunk *types.StreamChunk) {
+	s.write("content_block_stop", gin.H{
+		"type":  "content_block_stop",
+		"index": 0,
+	})
+	s.write("message_delta", gin.H{
+		"type": "message_delta",
+		"delta": gin.H{
+			"stop_reason":   types.AnthropicStopReason(chunk.FinishReason),
+			"stop_sequence": nil,
+		},
+		"usage": gin.H{
+			"input_tokens":  chunk.Usage.PromptTokens,
+			"output_tokens": chunk.Usage.CompletionTokens,
+		},
+		"x_octo": types.OctoExtension{Provider: s.providerName, CostUSD: chunk.CostUSD},
+	})
+	s.write("message_stop", gin.H{"type": "message_stop"})
+}
+
+func (s *anthropicStream) fail(err error) {
+	s.write("error", types.NewAnthropicErrorResponse("api_error", err.Error()))
+}
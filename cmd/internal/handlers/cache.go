@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"llm-router/cmd/internal/app"
+	"llm-router/cmd/internal/cache"
+	"llm-router/cmd/internal/metrics"
+	"llm-router/types"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	cacheStatusHeader   = "X-Cache"
+	cacheDecisionCtxKey = "cache_decision"
+)
+
+// serveFromCache applies the cache rules to the request and writes a cached completion if one exists.
+// It reports whether the response has been written.
+func serveFromCache(ctx context.Context, resolver app.ConfigResolver, c *gin.Context, request types.Completion, format completionFormat) bool {
+	responseCache := resolver.GetCache()
+	if responseCache == nil || request.Stream {
+		return false
+	}
+
+	decision := responseCache.Decide(&request, func() int {
+		return countPromptTokens(ctx, resolver, request.Tokens)
+	})
+	c.Set(cacheDecisionCtxKey, decision)
+
+	if !decision.Cacheable {
+		c.Header(cacheStatusHeader, "BYPASS")
+		return false
+	}
+
+	cached, ok := responseCache.GetItem(ctx, &request, decision)
+
+	// Entries are shared between consumers, so an answer from a model the caller may not use is a miss
+	if ok && len(request.AllowedModels) > 0 && !slices.Contains(request.AllowedModels, cached.Model) {
+		ok = false
+	}
+
+	if !ok {
+		metrics.CacheMissesTotal.Inc()
+		c.Header(cacheStatusHeader, "MISS")
+		return false
+	}
+
+	metrics.CacheHitsTotal.Inc()
+
+	resolver.GetLogger().Info("Serving completion from cache",
+		zap.String("provider", cached.Provider),
+		zap.String("model", cached.Model),
+		zap.Time("cached_at", cached.CachedAt),
+	)
+
+	c.Header(cacheStatusHeader, "HIT")
+
+	// Nothing was spent upstream for this request
+	response := cached.Response
+	response.CostUSD = 0
+	format.writeCompletion(c, &response, cached.Provider, cached.Model, true)
+	return true
+}
+
+// storeInCache saves a successful completion using the decision made by serveFromCache.
+func storeInCache(ctx context.Context, resolver app.ConfigResolver, c *gin.Context, request types.Completion, response *types.CompletionResponse, providerName string, model string) {
+	responseCache := resolver.GetCache()
+	if responseCache == nil || request.Stream {
+		return
+	}
+
+	value, exists := c.Get(cacheDecisionCtxKey)
+	decision, ok := value.(cache.Decision)
+	if !exists || !ok || !decision.Cacheable {
+		return
+	}
+
+	responseCache.SetItem(ctx, &request, decision, &cache.CachedResponse{
+		Response: *response,
+		Provider: providerName,
+		Model:    model,
+		CachedAt: time.Now(),
+	})
+}
+
+// countPromptTokens counts tokens with the first provider able to do so; cache and routing rules only need an estimate.
+func countPromptTokens(ctx context.Context, resolver app.ConfigResolver, requestTokens *types.RequestTokens) int {
+	for _, provider := range resolver.GetRouter().GetProviderManager().GetProviders() {
+		tokens, err := requestTokens.Prompt(ctx, provider)
+		if err == nil {
+			return tokens
+		}
+	}
+
+	resolver.GetLogger().Warn("Failed to count prompt tokens for rules")
+	return 0
+}
@@ -2,89 +2,73 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"llm-router/cmd/internal/app"
+	"llm-router/cmd/internal/middleware"
+	providererrors "llm-router/cmd/internal/provider_errors"
+	"llm-router/cmd/internal/providers"
 	"llm-router/cmd/internal/resilience"
-	"llm-router/cmd/internal/validations"
+	"llm-router/cmd/internal/router"
+	"llm-router/jsonschema"
 	"llm-router/types"
 	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-func HandleStreamingCompletion(resolver app.ConfigResolver, c *gin.Context, provider types.Provider, model string, request types.Completion) {
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("Transfer-Encoding", "chunked")
-
-	circuitBreakers := resolver.GetCircuitBreaker()
-	providerName := provider.GetProviderName()
-	circuitBreaker := circuitBreakers[providerName]
-
-	chunks, err := provider.CompleteStream(context.Background(), &types.StreamCompletionInput{
-		Model:    model,
-		Messages: request.Messages,
-	})
+func Completions(resolver app.ConfigResolver, c *gin.Context) {
+	var request types.Completion
+	format := openAIFormat{}
 
-	if err != nil {
-		resolver.GetLogger().Error("Provider streaming failed", zap.Error(err))
-		c.SSEvent("error", gin.H{
-			"error": "Failed to start streaming completion",
-		})
+	if err := c.ShouldBindJSON(&request); err != nil {
+		format.writeValidationError(c, err)
 		return
 	}
 
-	for chunk := range chunks {
-
-		circuitBreaker.Execute(chunk.Error)
-
-		if chunk.Error != nil {
-			c.SSEvent("error", gin.H{
-				"error": chunk.Error.Error(),
-			})
-			c.Writer.Flush()
-			break
-		}
+	runCompletion(resolver, c, request, format)
+}
 
-		if chunk.Done && chunk.Usage.TotalTokens > 0 {
-			if budgetManager := resolver.GetRouter().GetBudgetManager(); budgetManager != nil {
-				budgetManager.TrackUsage(providerName, chunk.CostUSD)
-			}
-			if usageHistory := resolver.GetRouter().GetUsageHistoryManager(); usageHistory != nil {
-				usageHistory.RecordUsage(context.Background(), providerName, chunk.CostUSD, chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens)
-			}
-		}
+// runCompletion takes a bound request through rate limiting, the cache, provider selection
+// and the fallback chain, writing the result in the given format.
+func runCompletion(resolver app.ConfigResolver, c *gin.Context, request types.Completion, format completionFormat) {
+	ctx := c.Request.Context()
 
-		if chunk.Content != "" {
-			c.SSEvent("message", chunk)
-			c.Writer.Flush()
-		}
+	retry := resolver.GetRetry()
+	circuitBreakers := resolver.GetCircuitBreaker()
 
-		if chunk.Done {
-			break
-		}
+	if err := validateCompletionRequest(&request); err != nil {
+		format.writeError(c, http.StatusBadRequest, errorInvalidRequest, err.Error())
+		return
 	}
-}
 
-func Completions(resolver app.ConfigResolver, c *gin.Context) {
+	maxTokens := 0
+	if request.MaxTokens != nil {
+		maxTokens = *request.MaxTokens
+	}
+	request.Tokens = types.NewRequestTokens(request.Messages, maxTokens)
 
-	ctx := c.Request.Context()
+	consumer := middleware.Consumer(c)
+	if err := restrictToConsumer(consumer, &request); err != nil {
+		format.writeError(c, http.StatusForbidden, errorPermission, err.Error())
+		return
+	}
 
-	var request types.Completion
-	retry := resolver.GetRetry()
-	circuitBreakers := resolver.GetCircuitBreaker()
+	applyRoutingRule(ctx, resolver, c, &request, consumer)
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		validations.HandleValidationError(c, err)
+	pinned, err := pinnedModel(resolver, &request)
+	if err != nil {
+		format.writeError(c, http.StatusBadRequest, errorInvalidRequest, err.Error())
 		return
 	}
 
-	if err := validateCompletionRequest(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	if pinned != "" && request.AllowedModels != nil && !slices.Contains(request.AllowedModels, pinned) {
+		format.writeError(c, http.StatusForbidden, errorPermission, fmt.Sprintf("model %s is not allowed for consumer %s", pinned, consumer.Name))
 		return
 	}
 
@@ -96,48 +80,73 @@ func Completions(resolver app.ConfigResolver, c *gin.Context) {
 
 	router := resolver.GetRouter()
 
-	globalLimit := resolver.GetConfig().Limits.RequestsPerMinute
-	if globalLimit > 0 {
-		rateLimitManager := router.GetRateLimitManager()
-		if rateLimitManager != nil {
-			allowed, err := rateLimitManager.Allow(ctx, "global:rpm", globalLimit)
-			if err != nil {
-				resolver.GetLogger().Error("Global rate limit check failed", zap.Error(err))
-			} else if !allowed {
-				c.JSON(http.StatusTooManyRequests, gin.H{
-					"error": "Global rate limit exceeded",
-				})
-				return
-			}
-		}
+	if !checkConsumerLimits(ctx, resolver, c, consumer, format) {
+		return
+	}
+
+	if serveFromCache(ctx, resolver, c, request, format) {
+		return
+	}
+
+	maxCost := 0.0
+	if request.MaxCostUSD != nil {
+		maxCost = *request.MaxCostUSD
 	}
 
 	providerStruct, err := router.SelectProvider(ctx, &types.SelectProviderInput{
-		Messages: request.Messages,
-		Circuits: circuitBreakers,
-		Tier:     request.Tier,
+		Messages:     request.Messages,
+		Circuits:     circuitBreakers,
+		Tier:         request.Tier,
+		Capabilities: request.RequiredCapabilities(),
+		Model:        pinned,
+		Models:       request.AllowedModels,
+		Providers:    request.AllowedProviders,
+		Sticky:       stickyKeys(c, request),
+		Tokens:       request.Tokens,
+		MaxCost:      maxCost,
 	})
 
-	provider := providerStruct.Provider
-	model := providerStruct.Model
+	var tooLong *types.ContextWindowError
+	if errors.As(err, &tooLong) {
+		format.writeError(c, http.StatusBadRequest, errorInvalidRequest, tooLong.Error())
+		return
+	}
+
+	var tooExpensive *types.CostCeilingError
+	if errors.As(err, &tooExpensive) {
+		format.writeError(c, http.StatusBadRequest, errorInvalidRequest, tooExpensive.Error())
+		return
+	}
 
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "no available providers, cannot process requests",
-		})
+		if pinned != "" {
+			resolver.GetLogger().Warn("Pinned model unavailable", zap.String("model", pinned), zap.Error(err))
+			format.writeError(c, http.StatusServiceUnavailable, errorUnavailable, fmt.Sprintf("model %s is currently unavailable", pinned))
+			return
+		}
+		format.writeError(c, http.StatusServiceUnavailable, errorUnavailable, "no available providers, cannot process requests")
 		return
 	}
 
+	defer startExperiment(resolver, c, providerStruct)()
+
+	if providerStruct.EstimatedCost > 0 {
+		c.Header(octoEstimatedCostHeader, strconv.FormatFloat(providerStruct.EstimatedCost, 'f', -1, 64))
+	}
+	if providerStruct.Tier != "" {
+		c.Header(octoTierHeader, providerStruct.Tier)
+	}
+
+	provider := providerStruct.Provider
+	model := providerStruct.Model
+
 	if request.Stream {
-		HandleStreamingCompletion(resolver, c, provider, model, request)
+		chain := buildModelChain(ctx, resolver, provider, model, providerStruct.Candidates, request)
+		HandleStreamingCompletion(ctx, resolver, c, chain, circuitBreakers, retry, request, format)
 		return
 	}
 
-	if model != "" {
-		handleCompletionWithModelChain(ctx, resolver, c, provider, model, providerStruct.Candidates, circuitBreakers, retry, request)
-	} else {
-		handleCompletionWithProviderChain(ctx, resolver, c, provider, providerStruct.Candidates, circuitBreakers, retry, request)
-	}
+	handleCompletionWithModelChain(ctx, resolver, c, provider, model, providerStruct.Candidates, circuitBreakers, retry, request, format)
 }
 
 func handleCompletionWithModelChain(
@@ -150,15 +159,9 @@ func handleCompletionWithModelChain(
 	circuitBreakers map[string]types.CircuitBreaker,
 	retry *resilience.Retry,
 	request types.Completion,
+	format completionFormat,
 ) {
-	providerChain := buildProviderChainWithModels(
-		primaryModel,
-		primaryProvider,
-		resolver.GetFallbackChain(),
-		resolver.GetProviderManager(),
-		candidates,
-		resolver.GetLogger(),
-	)
+	providerChain := buildModelChain(ctx, resolver, primaryProvider, primaryModel, candidates, request)
 
 	resolver.GetLogger().Info("Model-aware provider chain built",
 		zap.Int("chain_length", len(providerChain)),
@@ -183,13 +186,21 @@ func handleCompletionWithModelChain(
 		)
 
 		response, err := resilience.Do(ctx, currentProviderName, retry, func(ctx context.Context) (*types.CompletionResponse, error) {
-			return currentProvider.Complete(ctx, &types.CompletionInput{
-				Model:    currentModel,
-				Messages: request.Messages,
+			response, err := currentProvider.Complete(ctx, &types.CompletionInput{
+				Model:          currentModel,
+				Messages:       request.Messages,
+				Params:         request.SamplingParams(),
+				Tools:          request.Tools,
+				ToolChoice:     request.ToolChoice,
+				ResponseFormat: request.ResponseFormat,
 			})
+			if err != nil {
+				return nil, err
+			}
+			return response, checkResponseFormat(currentProviderName, request.ResponseFormat, response)
 		})
 
-		currentCircuitBreaker.Execute(err)
+		resilience.RecordResult(currentCircuitBreaker, err)
 
 		if err != nil {
 			resolver.GetLogger().Warn("Provider failed, trying next in chain",
@@ -208,24 +219,16 @@ func handleCompletionWithModelChain(
 			zap.Int("attempt_number", i+1),
 		)
 
-		if budgetManager := resolver.GetRouter().GetBudgetManager(); budgetManager != nil {
-			budgetManager.TrackUsage(currentProviderName, response.CostUSD)
+		// A primary left on its provider's default model reports the model it ran
+		usedModel := currentModel
+		if usedModel == "" {
+			usedModel = response.Model
 		}
+		recordUsage(ctx, resolver, completedUsageTags(c, currentProviderName, usedModel), currentProviderName, response.CostUSD, response.Usage)
 
-		if usageHistory := resolver.GetRouter().GetUsageHistoryManager(); usageHistory != nil {
-			usageHistory.RecordUsage(ctx, currentProviderName, response.CostUSD, response.Usage.PromptTokens, response.Usage.CompletionTokens)
-		}
-
-		c.Header("X-Request-Cost", response.Headers["cost"])
+		storeInCache(ctx, resolver, c, request, response, currentProviderName, currentModel)
 
-		c.JSON(http.StatusOK, gin.H{
-			"message":  response.Message.Content,
-			"role":     response.Message.Role,
-			"provider": currentProviderName,
-			"model":    currentModel,
-			"usage":    response.Usage,
-			"cost_usd": response.CostUSD,
-		})
+		format.writeCompletion(c, response, currentProviderName, currentModel, false)
 		return
 	}
 
@@ -234,98 +237,153 @@ func handleCompletionWithModelChain(
 		zap.Error(lastErr),
 	)
 
-	c.JSON(http.StatusInternalServerError, gin.H{
-		"error":       "All providers in fallback chain failed",
-		"last_error":  lastErr.Error(),
-		"tried_count": len(providerChain),
-	})
+	format.writeError(c, http.StatusInternalServerError, errorUpstream, fallbackErrorMessage(len(providerChain), lastErr))
 }
 
-func handleCompletionWithProviderChain(
+// buildModelChain returns the providers and models to try, starting with the selected one. An
+// empty primary model leaves the primary on its provider's default model; fallbacks are always
+// given a model that can serve the request. A pinned model is tried on its own unless the request
+// allows falling back to equivalent models. Fallbacks estimated to cost more than the request's
+// cost ceiling are left out.
+func buildModelChain(
 	ctx context.Context,
 	resolver app.ConfigResolver,
-	c *gin.Context,
 	primaryProvider types.Provider,
+	primaryModel string,
 	candidates []types.Provider,
-	circuitBreakers map[string]types.CircuitBreaker,
-	retry *resilience.Retry,
 	request types.Completion,
-) {
-
-	providerChain := buildProviderChain(primaryProvider, resolver.GetFallbackChain(), resolver.GetProviderManager(), candidates)
+) []types.ProviderWithModel {
+	if primaryModel != "" && primaryModel == request.Model && !request.AllowFallback {
+		return []types.ProviderWithModel{{Provider: primaryProvider, Model: primaryModel}}
+	}
 
-	resolver.GetLogger().Info("Provider-only chain built",
-		zap.Int("chain_length", len(providerChain)),
-		zap.String("primary_provider", primaryProvider.GetProviderName()),
+	chain := buildProviderChainWithModels(
+		ctx,
+		primaryModel,
+		primaryProvider,
+		resolver.GetFallbackChain(),
+		resolver.GetProviderManager(),
+		candidates,
+		request.RequiredCapabilities(),
+		request.AllowedModels,
+		request.Tokens,
+		resolver.GetLogger(),
 	)
 
-	var lastErr error
+	estimator, ok := resolver.GetRouter().(router.CostEstimatingRouter)
+	if request.MaxCostUSD == nil || !ok || len(chain) == 0 {
+		return chain
+	}
 
-	for i, currentProvider := range providerChain {
-		currentProviderName := currentProvider.GetProviderName()
-		currentCircuitBreaker := circuitBreakers[currentProviderName]
+	// The primary model was already checked against the ceiling when it was selected
+	affordable := chain[:1]
+	for _, entry := range chain[1:] {
+		if cost, ok := estimator.EstimateCost(ctx, entry.Provider, entry.Model, request.Tokens); ok && cost > *request.MaxCostUSD {
+			resolver.GetLogger().Debug("Leaving fallback over the request's cost ceiling out of the chain",
+				zap.String("provider", entry.Provider.GetProviderName()),
+				zap.String("model", entry.Model),
+				zap.Float64("estimated_cost", cost),
+			)
+			continue
+		}
+		affordable = append(affordable, entry)
+	}
+	return affordable
+}
 
-		resolver.GetLogger().Debug("Trying provider",
-			zap.Int("attempt", i+1),
-			zap.Int("total", len(providerChain)),
-			zap.String("provider", currentProviderName),
-			zap.String("circuit_state", currentCircuitBreaker.GetState()),
-		)
+// applyRoutingRule applies the first routing rule matching the request, unless the client pinned
+// a model itself. The rule may pin a model, which can fall back to equivalent models, limit the
+// providers and replace the tier. Rules leaving the consumer no model it may use are skipped, so
+// the consumer's restrictions must already be applied.
+func applyRoutingRule(ctx context.Context, resolver app.ConfigResolver, c *gin.Context, req *types.Completion, consumer *types.ConsumerData) {
+	ruleRouter, ok := resolver.GetRouter().(router.RuleRouter)
+	if !ok || strings.Contains(req.Model, "/") {
+		return
+	}
 
-		response, err := resilience.Do(ctx, currentProviderName, retry, func(ctx context.Context) (*types.CompletionResponse, error) {
-			return currentProvider.Complete(ctx, &types.CompletionInput{
-				Model:    "",
-				Messages: request.Messages,
-			})
-		})
+	consumerName := ""
+	if consumer != nil {
+		consumerName = consumer.Name
+	}
 
-		currentCircuitBreaker.Execute(err)
+	rule := ruleRouter.MatchRule(ctx, &types.RuleInput{
+		Messages:     req.Messages,
+		Tier:         req.Tier,
+		Consumer:     consumerName,
+		Headers:      c.Request.Header,
+		Capabilities: req.RequiredCapabilities(),
+		Tokens: func() int {
+			return countPromptTokens(ctx, resolver, req.Tokens)
+		},
+		MaxTokens: req.Tokens.MaxTokens(),
+		Models:    req.AllowedModels,
+	})
+	if rule == nil {
+		return
+	}
 
-		if err != nil {
-			resolver.GetLogger().Warn("Provider failed, trying next in chain",
-				zap.String("provider", currentProviderName),
-				zap.Error(err),
-				zap.Int("remaining_providers", len(providerChain)-i-1),
-			)
-			lastErr = err
-			continue
-		}
+	resolver.GetLogger().Info("Routing rule matched",
+		zap.String("rule", rule.Name),
+		zap.String("model", rule.Use),
+		zap.Strings("providers", rule.Providers),
+		zap.String("tier", rule.Tier),
+	)
+	c.Header(octoRuleHeader, rule.Name)
 
-		resolver.GetLogger().Info("Provider succeeded",
-			zap.String("provider", currentProviderName),
-			zap.Int("attempt_number", i+1),
-		)
+	if rule.Tier != "" {
+		req.Tier = rule.Tier
+	}
+	if rule.Use != "" {
+		req.Model = rule.Use
+		req.AllowFallback = true
+	}
+	req.AllowedProviders = rule.Providers
+}
 
-		if budgetManager := resolver.GetRouter().GetBudgetManager(); budgetManager != nil {
-			budgetManager.TrackUsage(currentProviderName, response.CostUSD)
-		}
+// restrictToConsumer limits the request to the models its consumer may use. A tier outside the
+// consumer's tiers, or a consumer left with no model in the catalog, is refused.
+func restrictToConsumer(consumer *types.ConsumerData, req *types.Completion) error {
+	allowed, restricted := consumerModels(consumer)
+	if !restricted {
+		return nil
+	}
 
-		if usageHistory := resolver.GetRouter().GetUsageHistoryManager(); usageHistory != nil {
-			usageHistory.RecordUsage(ctx, currentProviderName, response.CostUSD, response.Usage.PromptTokens, response.Usage.CompletionTokens)
-		}
+	if req.Tier != "" && len(consumer.Tiers) > 0 && !slices.Contains(consumer.Tiers, req.Tier) {
+		return fmt.Errorf("tier %s is not allowed for consumer %s", req.Tier, consumer.Name)
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("consumer %s may not use any model in the catalog", consumer.Name)
+	}
 
-		c.Header("X-Request-Cost", response.Headers["cost"])
+	req.AllowedModels = allowed
+	return nil
+}
 
-		c.JSON(http.StatusOK, gin.H{
-			"message":  response.Message.Content,
-			"role":     response.Message.Role,
-			"provider": currentProviderName,
-			"usage":    response.Usage,
-			"cost_usd": response.CostUSD,
-		})
-		return
+// pinnedModel returns the catalog model ID the request pins with a provider/model value.
+// Model values without a provider prefix (e.g. an SDK's default "gpt-4o") don't pin anything.
+func pinnedModel(resolver app.ConfigResolver, req *types.Completion) (string, error) {
+	if !strings.Contains(req.Model, "/") {
+		return "", nil
 	}
 
-	resolver.GetLogger().Error("All providers in fallback chain failed",
-		zap.Int("providers_tried", len(providerChain)),
-		zap.Error(lastErr),
-	)
+	info, err := providers.GetModelInfo(req.Model)
+	if err != nil {
+		return "", fmt.Errorf("unknown model %s", req.Model)
+	}
 
-	c.JSON(http.StatusInternalServerError, gin.H{
-		"error":       "All providers in fallback chain failed",
-		"last_error":  lastErr.Error(),
-		"tried_count": len(providerChain),
-	})
+	if info.IsEmbedding() {
+		return "", fmt.Errorf("model %s is an embedding model; use /v1/embeddings", info.ID)
+	}
+
+	if _, err := resolver.GetProviderManager().GetProvider(info.Provider); err != nil {
+		return "", fmt.Errorf("provider %s for model %s is not configured", info.Provider, info.ID)
+	}
+
+	if capabilities := req.RequiredCapabilities(); !info.HasCapabilities(capabilities) {
+		return "", fmt.Errorf("model %s does not support %s", info.ID, strings.Join(capabilities, ", "))
+	}
+
+	return info.ID, nil
 }
 
 func validateCompletionRequest(req *types.Completion) error {
@@ -337,6 +395,40 @@ func validateCompletionRequest(req *types.Completion) error {
 		}
 	}
 
+	for i, msg := range req.Messages {
+		if msg.HasMedia() && msg.Role != "user" {
+			return fmt.Errorf("message %d: images, audio and files are only supported in user messages", i)
+		}
+		for _, part := range msg.Parts {
+			if err := part.Validate(); err != nil {
+				return fmt.Errorf("message %d: %w", i, err)
+			}
+		}
+	}
+
+	if req.ToolChoice != nil && req.ToolChoice.Mode != types.ToolChoiceNone && len(req.Tools) == 0 {
+		return fmt.Errorf("tool_choice requires tools")
+	}
+
+	if req.ToolChoice != nil && req.ToolChoice.Mode == types.ToolChoiceFunction {
+		found := false
+		for _, tool := range req.Tools {
+			if tool.Function.Name == req.ToolChoice.Function {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("tool_choice names unknown function %q", req.ToolChoice.Function)
+		}
+	}
+
+	if req.ResponseFormat != nil && req.ResponseFormat.Type == types.ResponseFormatJSONSchema {
+		if !responseFormatName.MatchString(req.ResponseFormat.JSONSchema.Name) {
+			return fmt.Errorf("response_format json_schema name may only contain letters, digits, underscores and dashes")
+		}
+	}
+
 	if req.Temperature != nil {
 		if *req.Temperature < 0 || *req.Temperature > 2 {
 			return fmt.Errorf("temperature must be between 0 and 2")
@@ -353,3 +445,20 @@ func validateCompletionRequest(req *types.Completion) error {
 
 	return nil
 }
+
+var responseFormatName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// checkResponseFormat verifies structured output before it is returned or cached. A response
+// that isn't valid JSON, or doesn't match the requested schema, fails the attempt with a retryable
+// error, so the fallback chain moves on rather than returning it. Responses that call a tool
+// instead of answering are left alone. Streamed responses are sent as they arrive and can't be checked.
+func checkResponseFormat(providerName string, format *types.ResponseFormat, response *types.CompletionResponse) error {
+	if !format.IsJSON() || len(response.Message.ToolCalls) > 0 {
+		return nil
+	}
+
+	if err := jsonschema.ValidateJSON(format.ResponseSchema(), response.Message.Content); err != nil {
+		return providererrors.NewInvalidResponseError(providerName, fmt.Sprintf("response does not match response_format: %v", err), err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"llm-router/cmd/internal/app"
+	"llm-router/cmd/internal/metrics"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/types"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// consumerBudgetKey is the BudgetManager key tracking a consumer's spend for the current day.
+func consumerBudgetKey(name string) string {
+	return fmt.Sprintf("%s%s:%s", router.ConsumerBudgetPrefix, name, time.Now().UTC().Format("2006-01-02"))
+}
+
+type rateLimitCheck struct {
+	key     string
+	limit   int
+	window  time.Duration
+	message string
+}
+
+// checkConsumerLimits enforces the authenticated consumer's request limits and daily budget,
+// writing an error and returning false once one is exhausted. A nil consumer (authentication
+// disabled) is only subject to the global limits.
+func checkConsumerLimits(ctx context.Context, resolver app.ConfigResolver, c *gin.Context, consumer *types.ConsumerData, format completionFormat) bool {
+	router := resolver.GetRouter()
+	limits := resolver.GetConfig().Limits
+
+	if rateLimitManager := router.GetRateLimitManager(); rateLimitManager != nil {
+		checks := []rateLimitCheck{
+			{"global:rpm", limits.RequestsPerMinute, time.Minute, "Global rate limit exceeded"},
+			{"global:rpd", limits.RequestsPerDay, 24 * time.Hour, "Global daily request limit exceeded"},
+		}
+		if consumer != nil {
+			checks = append(checks,
+				rateLimitCheck{"consumer:" + consumer.Name + ":rpm", consumer.RequestsPerMinute, time.Minute, "Rate limit exceeded"},
+				rateLimitCheck{"consumer:" + consumer.Name + ":rpd", consumer.RequestsPerDay, 24 * time.Hour, "Daily request limit exceeded"},
+			)
+		}
+
+		for _, check := range checks {
+			if check.limit <= 0 {
+				continue
+			}
+			allowed, err := rateLimitManager.AllowWithin(ctx, check.key, check.limit, check.window)
+			if err != nil {
+				resolver.GetLogger().Error("Rate limit check failed", zap.String("key", check.key), zap.Error(err))
+			} else if !allowed {
+				format.writeError(c, http.StatusTooManyRequests, errorRateLimit, check.message)
+				return false
+			}
+		}
+	}
+
+	if consumer != nil && consumer.DailyBudget > 0 {
+		if budgetManager := router.GetBudgetManager(); budgetManager != nil && budgetManager.GetUsage(consumerBudgetKey(consumer.Name)) >= consumer.DailyBudget {
+			format.writeError(c, http.StatusTooManyRequests, errorQuota, fmt.Sprintf("Daily budget of $%.2f exhausted", consumer.DailyBudget))
+			return false
+		}
+	}
+
+	return true
+}
+
+// consumerModels returns the catalog IDs of the models the consumer may use, and false when it
+// may use any model. Tiers only restrict chat models, since embedding models have no tier.
+func consumerModels(consumer *types.ConsumerData) ([]string, bool) {
+	if consumer == nil || (len(consumer.Tiers) == 0 && len(consumer.Models) == 0) {
+		return nil, false
+	}
+
+	var allowed []string
+	for _, model := range providers.ListModels() {
+		if len(consumer.Models) > 0 && !slices.Contains(consumer.Models, model.ID) {
+			continue
+		}
+		if len(consumer.Tiers) > 0 && !model.IsEmbedding() && !slices.Contains(consumer.Tiers, string(model.Tier)) {
+			continue
+		}
+		allowed = append(allowed, model.ID)
+	}
+
+	return allowed, true
+}
+
+// recordUsage tracks a served request's cost against its provider's and consumer's budgets and
+// adds it to the usage history, and to its experiment variant's cost if it is in an experiment.
+func recordUsage(ctx context.Context, resolver app.ConfigResolver, tags router.UsageTags, providerName string, cost float64, usage types.Usage) {
+	if budgetManager := resolver.GetRouter().GetBudgetManager(); budgetManager != nil {
+		budgetManager.TrackUsage(providerName, cost)
+		if tags.Consumer != "" {
+			budgetManager.TrackUsage(consumerBudgetKey(tags.Consumer), cost)
+		}
+	}
+
+	if tags.Experiment != "" {
+		metrics.ExperimentCostTotal.WithLabelValues(tags.Experiment, tags.Variant).Add(cost)
+	}
+
+	if usageHistory := resolver.GetRouter().GetUsageHistoryManager(); usageHistory != nil {
+		usageHistory.RecordUsage(ctx, providerName, tags, cost, usage.PromptTokens, usage.CompletionTokens)
+	}
+}
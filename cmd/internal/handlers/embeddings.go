@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"llm-router/cmd/internal/app"
+	"llm-router/cmd/internal/middleware"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/resilience"
+	"llm-router/cmd/internal/router"
+	"llm-router/types"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func Embeddings(resolver app.ConfigResolver, c *gin.Context) {
+	var request types.EmbeddingRequest
+	format := openAIFormat{}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		format.writeValidationError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	pinned, err := pinnedEmbeddingModel(resolver, &request)
+	if err != nil {
+		format.writeError(c, http.StatusBadRequest, errorInvalidRequest, err.Error())
+		return
+	}
+
+	consumer := middleware.Consumer(c)
+	allowedModels, restricted := consumerModels(consumer)
+	if restricted && (len(allowedModels) == 0 || (pinned != "" && !slices.Contains(allowedModels, pinned))) {
+		format.writeError(c, http.StatusForbidden, errorPermission, fmt.Sprintf("consumer %s may not use this embedding model", consumer.Name))
+		return
+	}
+
+	resolver.GetLogger().Info("Embedding request received",
+		zap.Int("input_count", len(request.Input)),
+		zap.String("model", request.Model),
+	)
+
+	embeddingRouter, ok := resolver.GetRouter().(router.EmbeddingRouter)
+	if !ok {
+		format.writeError(c, http.StatusServiceUnavailable, errorUnavailable, "embeddings are not supported by the configured router")
+		return
+	}
+
+	if !checkConsumerLimits(ctx, resolver, c, consumer, format) {
+		return
+	}
+
+	dimensions := 0
+	if request.Dimensions != nil {
+		dimensions = *request.Dimensions
+	}
+
+	circuitBreakers := resolver.GetCircuitBreaker()
+	selected, err := embeddingRouter.SelectEmbeddingProvider(ctx, &types.SelectProviderInput{
+		Circuits:   circuitBreakers,
+		Model:      pinned,
+		Dimensions: dimensions,
+		Models:     allowedModels,
+	})
+	if err != nil {
+		if pinned != "" {
+			resolver.GetLogger().Warn("Pinned embedding model unavailable", zap.String("model", pinned), zap.Error(err))
+			format.writeError(c, http.StatusServiceUnavailable, errorUnavailable, fmt.Sprintf("model %s is currently unavailable", pinned))
+			return
+		}
+		format.writeError(c, http.StatusServiceUnavailable, errorUnavailable, "no available embedding providers, cannot process requests")
+		return
+	}
+
+	chain := []types.ProviderWithModel{{Provider: selected.Provider, Model: selected.Model}}
+	if pinned == "" || request.AllowFallback {
+		chain = buildEmbeddingChain(chain[0], resolver.GetFallbackChain(), resolver.GetProviderManager(), selected.Candidates, dimensions, allowedModels)
+	}
+
+	retry := resolver.GetRetry()
+	var lastErr error
+
+	for i, providerWithModel := range chain {
+		currentProvider := providerWithModel.Provider
+		currentModel := providerWithModel.Model
+		currentProviderName := currentProvider.GetProviderName()
+		currentCircuitBreaker := circuitBreakers[currentProviderName]
+
+		embedder, ok := providers.AsEmbeddingProvider(currentProvider)
+		if !ok {
+			continue
+		}
+
+		resolver.GetLogger().Debug("Trying embedding provider",
+			zap.Int("attempt", i+1),
+			zap.Int("total", len(chain)),
+			zap.String("provider", currentProviderName),
+			zap.String("model", currentModel),
+		)
+
+		response, err := resilience.Do(ctx, currentProviderName, retry, func(ctx context.Context) (*types.EmbeddingResponse, error) {
+			return embedder.Embed(ctx, &types.EmbeddingInput{
+				Model:      currentModel,
+				Input:      request.Input,
+				Dimensions: request.Dimensions,
+			})
+		})
+
+		resilience.RecordResult(currentCircuitBreaker, err)
+
+		if err != nil {
+			resolver.GetLogger().Warn("Embedding provider failed, trying next in chain",
+				zap.String("provider", currentProviderName),
+				zap.String("model", currentModel),
+				zap.Error(err),
+				zap.Int("remaining_providers", len(chain)-i-1),
+			)
+			lastErr = err
+			continue
+		}
+
+		recordUsage(ctx, resolver, usageTags(c), currentProviderName, response.CostUSD, response.Usage)
+
+		writeEmbeddings(c, response, currentProviderName, request.EncodingFormat)
+		return
+	}
+
+	resolver.GetLogger().Error("All providers in embedding fallback chain failed",
+		zap.Int("providers_tried", len(chain)),
+		zap.Error(lastErr),
+	)
+
+	format.writeError(c, http.StatusInternalServerError, errorUpstream, fallbackErrorMessage(len(chain), lastErr))
+}
+
+// pinnedEmbeddingModel returns the catalog embedding model the request names, either by its
+// provider/model ID or by its upstream name (e.g. an OpenAI SDK's "text-embedding-3-small").
+// Unlike chat completions, an unknown model is rejected rather than ignored: vectors from
+// different models can't be compared, so silently swapping the model would be wrong.
+func pinnedEmbeddingModel(resolver app.ConfigResolver, req *types.EmbeddingRequest) (string, error) {
+	if req.Model == "" {
+		return "", nil
+	}
+
+	manager := resolver.GetProviderManager()
+
+	var info providers.ModelInfo
+	if strings.Contains(req.Model, "/") {
+		found, err := providers.GetModelInfo(req.Model)
+		if err != nil {
+			return "", fmt.Errorf("unknown model %s", req.Model)
+		}
+		if _, err := manager.GetProvider(found.Provider); err != nil {
+			return "", fmt.Errorf("provider %s for model %s is not configured", found.Provider, found.ID)
+		}
+		info = found
+	} else {
+		for _, p := range manager.GetProviders() {
+			for _, model := range providers.ListEmbeddingModels(p.GetProviderName()) {
+				if model.UpstreamName == req.Model {
+					info = model
+					break
+				}
+			}
+			if info.ID != "" {
+				break
+			}
+		}
+		if info.ID == "" {
+			return "", fmt.Errorf("unknown embedding model %s", req.Model)
+		}
+	}
+
+	if !info.IsEmbedding() {
+		return "", fmt.Errorf("model %s is not an embedding model", info.ID)
+	}
+
+	if req.Dimensions != nil && info.Dimensions > 0 && *req.Dimensions > info.Dimensions {
+		return "", fmt.Errorf("model %s produces at most %d dimensions", info.ID, info.Dimensions)
+	}
+
+	return info.ID, nil
+}
+
+// buildEmbeddingChain returns the providers and models to try: the primary model, then each
+// fallback provider that survived selection with its cheapest allowed embedding model of the requested size.
+func buildEmbeddingChain(
+	primary types.ProviderWithModel,
+	fallbackNames []string,
+	manager *providers.ProviderManager,
+	candidates []types.Provider,
+	dimensions int,
+	allowedModels []string,
+) []types.ProviderWithModel {
+	chain := []types.ProviderWithModel{primary}
+	seen := map[string]bool{primary.Provider.GetProviderName(): true}
+
+	allowed := make(map[string]bool)
+	for _, c := range candidates {
+		allowed[c.GetProviderName()] = true
+	}
+
+	for _, fallbackName := range fallbackNames {
+		if seen[fallbackName] || !allowed[fallbackName] {
+			continue
+		}
+
+		fallbackProvider, err := manager.GetProvider(fallbackName)
+		if err != nil {
+			continue
+		}
+
+		models := providers.FilterModelsByDimensions(providers.ListEmbeddingModels(fallbackName), dimensions)
+		models = providers.FilterAllowedModels(models, allowedModels)
+		cheapestModel, err := providers.FindCheapestModel(models)
+		if err != nil {
+			continue
+		}
+
+		chain = append(chain, types.ProviderWithModel{
+			Provider: fallbackProvider,
+			Model:    cheapestModel.ID,
+		})
+		seen[fallbackName] = true
+	}
+
+	return chain
+}
+
+// writeEmbeddings sends the vectors in the OpenAI embeddings list format.
+func writeEmbeddings(c *gin.Context, response *types.EmbeddingResponse, providerName string, encodingFormat string) {
+	data := make([]types.EmbeddingData, len(response.Embeddings))
+	for i, vector := range response.Embeddings {
+		var embedding any = vector
+		if encodingFormat == "base64" {
+			embedding = encodeEmbedding(vector)
+		}
+		data[i] = types.EmbeddingData{
+			Object:    types.ObjectEmbedding,
+			Index:     i,
+			Embedding: embedding,
+		}
+	}
+
+	setOctoHeaders(c, providerName, response.Model)
+	c.Header(octoCostHeader, strconv.FormatFloat(response.CostUSD, 'f', -1, 64))
+
+	c.JSON(http.StatusOK, types.EmbeddingList{
+		Object: types.ObjectList,
+		Data:   data,
+		Model:  response.Model,
+		Usage: types.EmbeddingUsage{
+			PromptTokens: response.Usage.PromptTokens,
+			TotalTokens:  response.Usage.PromptTokens,
+		},
+		Octo: &types.OctoExtension{
+			Provider: providerName,
+			CostUSD:  response.CostUSD,
+		},
+	})
+}
+
+// encodeEmbedding packs a vector as base64 little-endian float32s, as OpenAI does.
+func encodeEmbedding(vector []float32) string {
+	buf := make([]byte, 4*len(vector))
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(value))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"llm-router/cmd/internal/app"
+	"llm-router/cmd/internal/metrics"
+	"llm-router/cmd/internal/middleware"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/types"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const experimentCtxKey = "experiment_assignment"
+
+type experimentAssignment struct {
+	experiment string
+	variant    string
+}
+
+// stickyKeys returns the request values experiments may assign it by.
+func stickyKeys(c *gin.Context, request types.Completion) types.StickyKeys {
+	// Without authentication there is no API key, and the request is assigned by the other keys only
+	apiKey, _ := middleware.APIKey(c)
+
+	return types.StickyKeys{
+		APIKey:  apiKey,
+		User:    request.User,
+		Headers: c.Request.Header,
+	}
+}
+
+// usageTags attributes the request's usage to its consumer and, once selected, its experiment variant.
+func usageTags(c *gin.Context) router.UsageTags {
+	var tags router.UsageTags
+	if consumer := middleware.Consumer(c); consumer != nil {
+		tags.Consumer = consumer.Name
+	}
+	if value, ok := c.Get(experimentCtxKey); ok {
+		assignment := value.(experimentAssignment)
+		tags.Experiment = assignment.experiment
+		tags.Variant = assignment.variant
+	}
+	return tags
+}
+
+// completedUsageTags attributes the usage of a complete response from model, whose output length
+// then informs cost estimates. Models outside the catalog aren't recorded.
+func completedUsageTags(c *gin.Context, providerName string, model string) router.UsageTags {
+	tags := usageTags(c)
+	if model != "" && !strings.Contains(model, "/") {
+		model = providerName + "/" + model
+	}
+	if _, err := providers.GetModelInfo(model); err == nil {
+		tags.Model = model
+	}
+	return tags
+}
+
+// startExperiment records the experiment variant the router assigned the request to, if any, and
+// returns a function to call once the response has been written. It records the request's
+// latency and whether it failed; a stream failing after content was sent still counts as served.
+func startExperiment(resolver app.ConfigResolver, c *gin.Context, selected *types.SelectedProviderOutput) func() {
+	if selected.Experiment == "" {
+		return func() {}
+	}
+
+	assignment := experimentAssignment{experiment: selected.Experiment, variant: selected.Variant}
+	c.Set(experimentCtxKey, assignment)
+	c.Header(octoExperimentHeader, assignment.experiment+"/"+assignment.variant)
+
+	start := time.Now()
+
+	return func() {
+		latency := time.Since(start)
+		failed := c.Writer.Status() >= 400
+
+		status := "success"
+		if failed {
+			status = "error"
+		}
+		metrics.ExperimentRequestsTotal.WithLabelValues(assignment.experiment, assignment.variant, status).Inc()
+		metrics.ExperimentRequestDuration.WithLabelValues(assignment.experiment, assignment.variant).Observe(latency.Seconds())
+
+		usageHistory := resolver.GetRouter().GetUsageHistoryManager()
+		if usageHistory == nil {
+			return
+		}
+		// The request context may already be cancelled, so the result is written independently of it
+		if err := usageHistory.RecordExperimentResult(context.Background(), assignment.experiment, assignment.variant, latency, failed); err != nil {
+			resolver.GetLogger().Warn("Failed to record experiment result", zap.String("experiment", assignment.experiment), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"llm-router/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorKind classifies router errors independently of the API format they are reported in.
+type errorKind int
+
+const (
+	errorInvalidRequest errorKind = iota
+	errorRateLimit
+	errorUnavailable
+	errorUpstream
+	errorPermission
+	errorQuota
+)
+
+// completionFormat renders the result of the shared completion pipeline in a client-facing
+// API format, so the same routing, fallback and budget tracking serve every endpoint.
+type completionFormat interface {
+	writeError(c *gin.Context, status int, kind errorKind, message string)
+	writeCompletion(c *gin.Context, response *types.CompletionResponse, providerName string, model string, cached bool)
+	newStream(c *gin.Context, request types.Completion, providerName string, model string) completionStream
+}
+
+// completionStream writes a streamed completion. start is called once before any content,
+// then delta and toolCalls for each piece of content, and finally either finish or fail.
+type completionStream interface {
+	start()
+	delta(content string)
+	toolCalls(deltas []types.ToolCallDelta)
+	finish(chunk *types.StreamChunk)
+	fail(err error)
+}
@@ -7,7 +7,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// Health needs no API key, so load balancers can probe it. In multi-tenant mode the request has no
+// tenant (resolver is nil), and only the status is reported.
 func Health(resolver app.ConfigResolver, c *gin.Context) {
+	if resolver == nil {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "healthy",
 		"providers": len(resolver.GetConfig().GetEnabledProviders()),
@@ -1,56 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"llm-router/cmd/internal/providers"
 	"llm-router/types"
 
 	"go.uber.org/zap"
 )
 
-func buildProviderChain(primaryProvider types.Provider, fallbackNames []string, manager *providers.ProviderManager, candidates []types.Provider) []types.Provider {
-	providerChain := make([]types.Provider, 0, len(fallbackNames)+1)
-	seen := make(map[string]bool)
-
-	// Create a map for fast candidate lookup if candidates are provided
-	allowed := make(map[string]bool)
-	useFilter := len(candidates) > 0
-	if useFilter {
-		for _, c := range candidates {
-			allowed[c.GetProviderName()] = true
-		}
-	}
-
-	primaryName := primaryProvider.GetProviderName()
-	providerChain = append(providerChain, primaryProvider)
-	seen[primaryName] = true
-
-	for _, fallbackName := range fallbackNames {
-		if seen[fallbackName] {
-			continue
-		}
-
-		if useFilter && !allowed[fallbackName] {
-			continue
-		}
-
-		fallbackProvider, err := manager.GetProvider(fallbackName)
-		if err != nil {
-			continue
-		}
-
-		providerChain = append(providerChain, fallbackProvider)
-		seen[fallbackName] = true
-	}
-
-	return providerChain
-}
-
 func buildProviderChainWithModels(
+	ctx context.Context,
 	primaryModel string,
 	primaryProvider types.Provider,
 	fallbackNames []string,
 	manager *providers.ProviderManager,
 	candidates []types.Provider,
+	capabilities []string,
+	allowedModels []string,
+	tokens *types.RequestTokens,
 	logger *zap.Logger,
 ) []types.ProviderWithModel {
 	chain := make([]types.ProviderWithModel, 0, len(fallbackNames)+1)
@@ -67,12 +34,15 @@ func buildProviderChainWithModels(
 
 	primaryModelInfo, err := providers.GetModelInfo(primaryModel)
 	if err != nil {
-		logger.Warn("Failed to get primary model info, building simple chain",
-			zap.String("model", primaryModel),
-			zap.Error(err),
-		)
+		// A primary left on its provider's default model has no tier to match
+		if primaryModel != "" {
+			logger.Warn("Failed to get primary model info, building simple chain",
+				zap.String("model", primaryModel),
+				zap.Error(err),
+			)
+		}
 
-		return buildSimpleChainWithModels(primaryModel, primaryProvider, fallbackNames, manager, candidates)
+		return buildSimpleChainWithModels(ctx, primaryModel, primaryProvider, fallbackNames, manager, candidates, capabilities, allowedModels, tokens)
 	}
 
 	primaryTier := primaryModelInfo.Tier
@@ -103,7 +73,9 @@ func buildProviderChainWithModels(
 			continue
 		}
 
-		models := providers.ListModelsByProviderAndTier(fallbackName, primaryTier)
+		models := providers.FilterModelsByCapabilities(providers.ListModelsByProviderAndTier(fallbackName, primaryTier), capabilities)
+		models = providers.FilterAllowedModels(models, allowedModels)
+		models = providers.FilterModelsByContext(ctx, models, fallbackProvider, tokens)
 		if len(models) == 0 {
 			logger.Debug("No models in tier for provider, skipping",
 				zap.String("provider", fallbackName),
@@ -134,11 +106,15 @@ func buildProviderChainWithModels(
 }
 
 func buildSimpleChainWithModels(
+	ctx context.Context,
 	primaryModel string,
 	primaryProvider types.Provider,
 	fallbackNames []string,
 	manager *providers.ProviderManager,
 	candidates []types.Provider,
+	capabilities []string,
+	allowedModels []string,
+	tokens *types.RequestTokens,
 ) []types.ProviderWithModel {
 	chain := make([]types.ProviderWithModel, 0, len(fallbackNames)+1)
 	seen := make(map[string]bool)
@@ -173,7 +149,9 @@ func buildSimpleChainWithModels(
 			continue
 		}
 
-		models := providers.ListModelsByProvider(fallbackName)
+		models := providers.FilterModelsByCapabilities(providers.ListModelsByProvider(fallbackName), capabilities)
+		models = providers.FilterAllowedModels(models, allowedModels)
+		models = providers.FilterModelsByContext(ctx, models, fallbackProvider, tokens)
 		if len(models) == 0 {
 			continue
 		}
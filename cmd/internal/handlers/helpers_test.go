@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"llm-router/cmd/internal/app"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/cmd/internal/router/filters"
+	"llm-router/types"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// lengthProvider counts one token per character of the messages.
+type lengthProvider struct {
+	name string
+}
+
+func (p *lengthProvider) Complete(ctx context.Context, input *types.CompletionInput) (*types.CompletionResponse, error) {
+	return &types.CompletionResponse{}, nil
+}
+func (p *lengthProvider) CompleteStream(ctx context.Context, input *types.StreamCompletionInput) (<-chan *types.StreamChunk, error) {
+	return nil, nil
+}
+func (p *lengthProvider) CountTokens(ctx context.Context, messages []types.Message) (int, error) {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += len(msg.Content)
+	}
+	return tokens, nil
+}
+func (p *lengthProvider) GetProviderName() string {
+	return p.name
+}
+
+func TestBuildModelChain_RoundRobinFallbackModels(t *testing.T) {
+	providers.InitializeModelRegistry([]types.ModelConfig{
+		{ID: "long-ai/long", Provider: "long-ai", Name: "long", InputCostPer1M: 1, OutputCostPer1M: 1, ContextWindow: 200000, Tier: "budget", Capabilities: []string{"chat"}},
+		{ID: "mixed-ai/mini", Provider: "mixed-ai", Name: "mini", InputCostPer1M: 0.1, OutputCostPer1M: 0.1, ContextWindow: 16000, Tier: "budget", Capabilities: []string{"chat"}},
+		{ID: "mixed-ai/long", Provider: "mixed-ai", Name: "long", InputCostPer1M: 1, OutputCostPer1M: 1, ContextWindow: 200000, Tier: "budget", Capabilities: []string{"chat"}},
+	}, nil)
+	t.Cleanup(func() {
+		providers.InitializeModelRegistry(providers.GetDefaultCatalog(), nil)
+	})
+
+	manager := providers.NewProviderManager(providers.NewProviderFactory())
+	manager.SetProviders([]types.Provider{&lengthProvider{name: "long-ai"}, &lengthProvider{name: "mixed-ai"}})
+
+	base, err := router.NewRoundRobinRouter(manager, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRoundRobinRouter() error = %v", err)
+	}
+	pipeline := router.NewPipelineRouter(base, manager, nil, nil, nil)
+	pipeline.AddFilter(filters.NewContextWindowFilter(zap.NewNop()))
+	// mixed-ai's default model is too small for the prompt, but it has one that fits
+	pipeline.SetDefaultModels(map[string]string{"long-ai": "long-ai/long", "mixed-ai": "mixed-ai/mini"})
+
+	resolver := &app.SingleTenantResolver{}
+	resolver.App.Store(&app.App{
+		Router:          pipeline,
+		Logger:          zap.NewNop(),
+		ProviderManager: manager,
+		FallbackChain:   []string{"long-ai", "mixed-ai"},
+	})
+
+	messages := []types.Message{{Role: "user", Content: strings.Repeat("a", 50000)}}
+	request := types.Completion{Messages: messages, Tokens: types.NewRequestTokens(messages, 0)}
+
+	for i := 0; i < 2; i++ {
+		selected, err := pipeline.SelectProvider(context.Background(), &types.SelectProviderInput{
+			Messages: request.Messages,
+			Tokens:   request.Tokens,
+		})
+		if err != nil {
+			t.Fatalf("SelectProvider() error = %v", err)
+		}
+		if selected.Provider.GetProviderName() != "long-ai" {
+			continue
+		}
+		if selected.Model != "" {
+			t.Fatalf("SelectProvider() model = %q, want long-ai left on its default model", selected.Model)
+		}
+
+		chain := buildModelChain(context.Background(), resolver, selected.Provider, selected.Model, selected.Candidates, request)
+		if len(chain) != 2 {
+			t.Fatalf("buildModelChain() returned %d entries, want 2", len(chain))
+		}
+		if fallback := chain[1]; fallback.Provider.GetProviderName() != "mixed-ai" || fallback.Model != "mixed-ai/long" {
+			t.Errorf("fallback = %s (%q), want mixed-ai on the model that fits", fallback.Provider.GetProviderName(), fallback.Model)
+		}
+		return
+	}
+
+	t.Fatal("round-robin never selected long-ai")
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"llm-router/cmd/internal/validations"
+	"llm-router/types"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Router extras that have no place in the client API schema are sent as headers.
+const (
+	octoProviderHeader   = "X-Octo-Provider"
+	octoModelHeader      = "X-Octo-Model"
+	octoCostHeader       = "X-Octo-Cost-Usd"
+	octoRuleHeader       = "X-Octo-Rule"
+	octoExperimentHeader = "X-Octo-Experiment"
+	// Estimated cost of the selected model, before the request is sent upstream
+	octoEstimatedCostHeader = "X-Octo-Estimated-Cost-Usd"
+	// Tier of the selected model, which may be below the requested tier
+	octoTierHeader = "X-Octo-Tier"
+)
+
+// openAIFormat renders completions in the OpenAI chat completions format.
+type openAIFormat struct{}
+
+func newResponseID(prefix string) string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%s%d", prefix, time.Now().UnixNano())
+	}
+	return prefix + hex.EncodeToString(buf)
+}
+
+func openAIError(kind errorKind, message string) types.ErrorResponse {
+	switch kind {
+	case errorRateLimit:
+		return types.NewErrorResponse(types.ErrorTypeRateLimit, "rate_limit_exceeded", message)
+	case errorUnavailable:
+		return types.NewErrorResponse(types.ErrorTypeServer, "no_available_providers", message)
+	case errorUpstream:
+		return types.NewErrorResponse(types.ErrorTypeServer, "upstream_error", message)
+	case errorPermission:
+		return types.NewErrorResponse(types.ErrorTypePermission, "model_not_allowed", message)
+	case errorQuota:
+		return types.NewErrorResponse(types.ErrorTypeQuota, "insufficient_quota", message)
+	default:
+		return types.NewErrorResponse(types.ErrorTypeInvalidRequest, "", message)
+	}
+}
+
+// writeError sends an OpenAI-shaped error body.
+func (openAIFormat) writeError(c *gin.Context, status int, kind errorKind, message string) {
+	c.JSON(status, openAIError(kind, message))
+}
+
+// writeValidationError reports binding failures as a single invalid_request_error.
+// The first offending field is reported as the error param.
+func (openAIFormat) writeValidationError(c *gin.Context, err error) {
+	message, field := describeValidationError(err)
+
+	body := types.NewErrorResponse(types.ErrorTypeInvalidRequest, "", message)
+	if field != "" {
+		body.Error.Param = field
+	}
+	c.JSON(http.StatusBadRequest, body)
+}
+
+// describeValidationError flattens binding errors into one message and returns the first offending field.
+func describeValidationError(err error) (string, string) {
+	fieldErrors := validations.FormatValidationErrors(err)
+	if len(fieldErrors) == 0 {
+		return err.Error(), ""
+	}
+
+	messages := make([]string, len(fieldErrors))
+	for i, fieldErr := range fieldErrors {
+		messages[i] = fieldErr.Message
+	}
+
+	return "Validation failed: " + strings.Join(messages, "; "), fieldErrors[0].Field
+}
+
+func fallbackErrorMessage(triedCount int, lastErr error) string {
+	message := fmt.Sprintf("All providers in fallback chain failed (tried %d)", triedCount)
+	if lastErr != nil {
+		message += ": " + lastErr.Error()
+	}
+	return message
+}
+
+func setOctoHeaders(c *gin.Context, providerName string, model string) {
+	c.Header(octoProviderHeader, providerName)
+	if model != "" {
+		c.Header(octoModelHeader, model)
+	}
+}
+
+func setSSEHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Transfer-Encoding", "chunked")
+}
+
+// writeCompletion sends a non-streaming completion in the OpenAI chat.completion format.
+func (openAIFormat) writeCompletion(c *gin.Context, response *types.CompletionResponse, providerName string, model string, cached bool) {
+	if response.Model != "" {
+		model = response.Model
+	}
+
+	finishReason := response.FinishReason
+	if finishReason == "" {
+		finishReason = types.FinishReasonStop
+	}
+
+	message := response.Message
+	if message.Role == "" {
+		message.Role = "assistant"
+	}
+
+	setOctoHeaders(c, providerName, model)
+	c.Header(octoCostHeader, strconv.FormatFloat(response.CostUSD, 'f', -1, 64))
+
+	c.JSON(http.StatusOK, types.ChatCompletion{
+		ID:      newResponseID("chatcmpl-"),
+		Object:  types.ObjectChatCompletion,
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []types.ChatCompletionChoice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: finishReason,
+		}},
+		Usage: response.Usage,
+		Octo: &types.OctoExtension{
+			Provider: providerName,
+			CostUSD:  response.CostUSD,
+			Cached:   cached,
+		},
+	})
+}
+
+func (openAIFormat) newStream(c *gin.Context, request types.Completion, providerName string, model string) completionStream {
+	return &openAIStream{
+		c:            c,
+		id:           newResponseID("chatcmpl-"),
+		created:      time.Now().Unix(),
+		model:        model,
+		providerName: providerName,
+		includeUsage: request.StreamOptions != nil && request.StreamOptions.IncludeUsage,
+	}
+}
+
+// openAIStream writes chat.completion.chunk server-sent events. All chunks of a stream share an id.
+type openAIStream struct {
+	c            *gin.Context
+	id           string
+	created      int64
+	model        string
+	providerName string
+	includeUsage bool
+}
+
+func (s *openAIStream) chunk(delta types.ChatCompletionDelta, finishReason *string) types.ChatCompletionChunk {
+	return types.ChatCompletionChunk{
+		ID:      s.id,
+		Object:  types.ObjectChatCompletionChunk,
+		Created: s.created,
+		Model:   s.model,
+		Choices: []types.ChatCompletionChunkChoice{{
+			Index:        0,
+			Delta:        delta,
+			FinishReason: finishReason,
+		}},
+	}
+}
+
+// write sends a single `data:` frame. OpenAI streams carry no `event:` field.
+func (s *openAIStream) write(payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.c.Writer, "data: %s\n\n", data)
+	s.c.Writer.Flush()
+}
+
+func (s *openAIStream) start() {
+	setSSEHeaders(s.c)
+	setOctoHeaders(s.c, s.providerName, s.model)
+	s.write(s.chunk(types.ChatCompletionDelta{Role: "assistant"}, nil))
+}
+
+func (s *openAIStream) delta(content string) {
+	s.write(s.chunk(types.ChatCompletionDelta{Content: content}, nil))
+}
+
+func (s *openAIStream) toolCalls(deltas []types.ToolCallDelta) {
+	s.write(s.chunk(types.ChatCompletionDelta{ToolCalls: deltas}, nil))
+}
+
+func (s *openAIStream) finish(chunk *types.StreamChunk) {
+	finishReason := chunk.FinishReason
+	if finishReason == "" {
+		finishReason = types.FinishReasonStop
+	}
+
+	final := s.chunk(types.ChatCompletionDelta{}, &finishReason)
+	final.Octo = &types.OctoExtension{Provider: s.providerName, CostUSD: chunk.CostUSD}
+	s.write(final)
+
+	if s.includeUsage {
+		usage := chunk.Usage
+		usageChunk := s.chunk(types.ChatCompletionDelta{}, nil)
+		usageChunk.Choices = []types.ChatCompletionChunkChoice{}
+		usageChunk.Usage = &usage
+		s.write(usageChunk)
+	}
+
+	fmt.Fprint(s.c.Writer, "data: [DONE]\n\n")
+	s.c.Writer.Flush()
+}
+
+// fail sends an OpenAI error object, which the SDKs raise as an API error. The stream ends without [DONE].
+func (s *openAIStream) fail(err error) {
+	s.write(openAIError(errorUpstream, err.Error()))
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"llm-router/cmd/internal/app"
+	"llm-router/cmd/internal/resilience"
+	"llm-router/cmd/internal/router"
+	"llm-router/types"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// openedStream is a provider stream that has produced its first content (or its final chunk),
+// so the client can be committed to it.
+type openedStream struct {
+	first  *types.StreamChunk
+	chunks <-chan *types.StreamChunk
+	cancel context.CancelFunc
+}
+
+// HandleStreamingCompletion streams a completion from the first provider in the chain that
+// starts successfully. Nothing is written to the client until a provider yields content, so
+// failures up to that point are retried and then fall through to the next provider. Once content
+// has been sent the response is committed, and a later failure ends the stream with an error event.
+func HandleStreamingCompletion(
+	ctx context.Context,
+	resolver app.ConfigResolver,
+	c *gin.Context,
+	chain []types.ProviderWithModel,
+	circuitBreakers map[string]types.CircuitBreaker,
+	retry *resilience.Retry,
+	request types.Completion,
+	format completionFormat,
+) {
+	var lastErr error
+
+	for i, providerWithModel := range chain {
+		currentProvider := providerWithModel.Provider
+		currentModel := providerWithModel.Model
+		currentProviderName := currentProvider.GetProviderName()
+		currentCircuitBreaker := circuitBreakers[currentProviderName]
+
+		resolver.GetLogger().Debug("Trying streaming provider",
+			zap.Int("attempt", i+1),
+			zap.Int("total", len(chain)),
+			zap.String("provider", currentProviderName),
+			zap.String("model", currentModel),
+			zap.String("circuit_state", currentCircuitBreaker.GetState()),
+		)
+
+		stream, err := resilience.Do(ctx, currentProviderName, retry, func(ctx context.Context) (*openedStream, error) {
+			return openStream(ctx, resolver, usageTags(c), currentProvider, currentModel, request)
+		})
+
+		if err != nil && ctx.Err() != nil {
+			resolver.GetLogger().Info("Client disconnected before the stream started",
+				zap.String("provider", currentProviderName),
+				zap.String("model", currentModel),
+			)
+			return
+		}
+
+		resilience.RecordResult(currentCircuitBreaker, err)
+
+		if err != nil {
+			resolver.GetLogger().Warn("Streaming provider failed before first token, trying next in chain",
+				zap.String("provider", currentProviderName),
+				zap.String("model", currentModel),
+				zap.Error(err),
+				zap.Int("remaining_providers", len(chain)-i-1),
+			)
+			lastErr = err
+			continue
+		}
+
+		if currentModel == "" {
+			currentModel = request.Model
+		}
+
+		relayStream(ctx, resolver, c, stream, currentProviderName, currentModel, currentCircuitBreaker, request, format)
+		return
+	}
+
+	resolver.GetLogger().Error("All providers in streaming fallback chain failed",
+		zap.Int("providers_tried", len(chain)),
+		zap.Error(lastErr),
+	)
+
+	format.writeError(c, http.StatusBadGateway, errorUpstream, fallbackErrorMessage(len(chain), lastErr))
+}
+
+// openStream starts a provider stream and waits for its first content. An error before
+// that point abandons the stream and is returned so the attempt can be retried.
+// The stream's context is derived from ctx, so cancelling ctx stops the upstream call.
+func openStream(ctx context.Context, resolver app.ConfigResolver, tags router.UsageTags, provider types.Provider, model string, request types.Completion) (*openedStream, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+
+	chunks, err := provider.CompleteStream(streamCtx, &types.StreamCompletionInput{
+		Model:          model,
+		Messages:       request.Messages,
+		Params:         request.SamplingParams(),
+		Tools:          request.Tools,
+		ToolChoice:     request.ToolChoice,
+		ResponseFormat: request.ResponseFormat,
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	for chunk := range chunks {
+		if chunk.Error != nil {
+			recordStreamUsage(resolver, tags, provider.GetProviderName(), chunk)
+			abandonStream(chunks, cancel)
+			return nil, chunk.Error
+		}
+
+		if chunk.Content != "" || len(chunk.ToolCalls) > 0 || chunk.Done {
+			return &openedStream{first: chunk, chunks: chunks, cancel: cancel}, nil
+		}
+	}
+
+	// The provider closed the stream without content or a final chunk
+	return &openedStream{first: &types.StreamChunk{Done: true}, chunks: chunks, cancel: cancel}, nil
+}
+
+// abandonStream cancels a provider stream and drains it so the provider's goroutine can exit.
+func abandonStream(chunks <-chan *types.StreamChunk, cancel context.CancelFunc) {
+	cancel()
+	go func() {
+		for range chunks {
+		}
+	}()
+}
+
+// recordStreamUsage tracks the usage reported on a stream's final chunk. Cancelled and failed
+// streams report what upstream generated before they stopped, since that is still billed.
+func recordStreamUsage(resolver app.ConfigResolver, tags router.UsageTags, providerName string, chunk *types.StreamChunk) {
+	if !chunk.Done || chunk.Usage.TotalTokens == 0 {
+		return
+	}
+
+	// The request context may already be cancelled, so usage is written independently of it
+	recordUsage(context.Background(), resolver, tags, providerName, chunk.CostUSD, chunk.Usage)
+}
+
+// relayStream writes an opened provider stream to the client. If the client disconnects
+// (ctx is cancelled), the provider stream is cancelled and drained so its partial usage is recorded.
+func relayStream(
+	ctx context.Context,
+	resolver app.ConfigResolver,
+	c *gin.Context,
+	opened *openedStream,
+	providerName string,
+	model string,
+	circuitBreaker types.CircuitBreaker,
+	request types.Completion,
+	format completionFormat,
+) {
+	defer opened.cancel()
+
+	stream := format.newStream(c, request, providerName, model)
+	stream.start()
+
+	handle := func(chunk *types.StreamChunk) bool {
+		// Only complete streams tell how long the model's outputs are
+		tags := usageTags(c)
+		if chunk.Error == nil {
+			tags = completedUsageTags(c, providerName, model)
+		}
+		recordStreamUsage(resolver, tags, providerName, chunk)
+
+		if chunk.Error != nil {
+			if ctx.Err() != nil {
+				// Cancelled because the client went away, not a provider failure
+				return false
+			}
+
+			resilience.RecordResult(circuitBreaker, chunk.Error)
+			resolver.GetLogger().Error("Provider stream failed after content was sent",
+				zap.String("provider", providerName),
+				zap.String("model", model),
+				zap.Error(chunk.Error),
+			)
+			stream.fail(chunk.Error)
+			return false
+		}
+
+		if chunk.Content != "" {
+			stream.delta(chunk.Content)
+		}
+
+		if len(chunk.ToolCalls) > 0 {
+			stream.toolCalls(chunk.ToolCalls)
+		}
+
+		if chunk.Done {
+			stream.finish(chunk)
+			return false
+		}
+
+		return true
+	}
+
+	if !handle(opened.first) {
+		abandonStream(opened.chunks, opened.cancel)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			resolver.GetLogger().Info("Client disconnected, cancelling provider stream",
+				zap.String("provider", providerName),
+				zap.String("model", model),
+			)
+			opened.cancel()
+
+			// The provider finishes with a chunk carrying the usage generated so far
+			for chunk := range opened.chunks {
+				recordStreamUsage(resolver, usageTags(c), providerName, chunk)
+			}
+			return
+
+		case chunk, ok := <-opened.chunks:
+			if !ok {
+				// The provider closed the stream without a final chunk
+				stream.finish(&types.StreamChunk{Done: true})
+				return
+			}
+
+			if !handle(chunk) {
+				abandonStream(opened.chunks, opened.cancel)
+				return
+			}
+		}
+	}
+}
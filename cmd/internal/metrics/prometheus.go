@@ -110,6 +110,31 @@ var (
 		},
 		[]string{"provider"},
 	)
+
+	ExperimentRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_router_experiment_requests_total",
+			Help: "Total requests assigned to each experiment variant",
+		},
+		[]string{"experiment", "variant", "status"},
+	)
+
+	ExperimentRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "llm_router_experiment_request_duration_seconds",
+			Help:    "Request latency per experiment variant",
+			Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30},
+		},
+		[]string{"experiment", "variant"},
+	)
+
+	ExperimentCostTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_router_experiment_cost_usd_total",
+			Help: "Total cost in USD per experiment variant",
+		},
+		[]string{"experiment", "variant"},
+	)
 )
 
 func Metrics() error {
@@ -131,6 +156,9 @@ func Metrics() error {
 		CircuitBreakerTrips,
 		RetryAttemptsTotal,
 		ProviderEMALatency,
+		ExperimentRequestsTotal,
+		ExperimentRequestDuration,
+		ExperimentCostTotal,
 	)
 
 	mux.Handle("/metrics", promhttp.HandlerFor(
@@ -1,50 +1,108 @@
 package middleware
 
 import (
+	"errors"
+	"llm-router/types"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func APIKeyAuth(apiKeys []string) gin.HandlerFunc {
-	validKeys := make(map[string]bool)
-	for _, key := range apiKeys {
-		if key != "" {
-			validKeys[key] = true
-		}
-	}
+// ConsumerKey is the gin context key holding the *types.ConsumerData of the authenticated API key.
+const ConsumerKey = "octo.consumer"
 
+// APIKeyAuth accepts requests carrying one of the configured API keys and records the
+// consumer the key belongs to. Keys under security.apiKeys belong to the default consumer,
+// which is an admin. consumers is called on every request, so the keys follow config reloads;
+// while it is empty, authentication is disabled.
+func APIKeyAuth(consumers func() map[string]*types.ConsumerData) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if len(validKeys) == 0 {
+		consumers := consumers()
+		if len(consumers) == 0 {
 			c.Next()
 			return
 		}
 
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header is required",
-			})
+		apiKey, err := APIKey(c)
+		if err != nil {
+			Unauthorized(c, err.Error())
+			return
+		}
+
+		consumer, ok := consumers[apiKey]
+		if !ok {
+			Unauthorized(c, "Invalid API key")
 			return
 		}
 
+		c.Set(ConsumerKey, consumer)
+		c.Next()
+	}
+}
+
+// ConsumerIndex maps each API key in the security config to the consumer it belongs to.
+func ConsumerIndex(security types.SecurityData) map[string]*types.ConsumerData {
+	consumers := make(map[string]*types.ConsumerData)
+
+	defaultConsumer := &types.ConsumerData{Name: types.DefaultConsumer, Admin: true}
+	for _, key := range security.APIKeys {
+		if key != "" {
+			consumers[key] = defaultConsumer
+		}
+	}
+	for i := range security.Consumers {
+		consumer := &security.Consumers[i]
+		for _, key := range consumer.APIKeys {
+			if key != "" {
+				consumers[key] = consumer
+			}
+		}
+	}
+
+	return consumers
+}
+
+// APIKey returns the API key sent as a bearer token or, as Anthropic SDKs do, in x-api-key.
+func APIKey(c *gin.Context) (string, error) {
+	authHeader := c.GetHeader("Authorization")
+	apiKey := c.GetHeader("X-Api-Key")
+
+	if authHeader == "" && apiKey == "" {
+		return "", errors.New("Authorization header is required")
+	}
+
+	if authHeader != "" {
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid authorization header format. Expected 'Bearer <token>'",
-			})
-			return
+			return "", errors.New("Invalid authorization header format. Expected 'Bearer <token>'")
 		}
+		apiKey = parts[1]
+	}
 
-		apiKey := parts[1]
-		if !validKeys[apiKey] {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid API key",
-			})
+	return apiKey, nil
+}
+
+// Unauthorized rejects the request with an OpenAI-style invalid_api_key error.
+func Unauthorized(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, types.NewErrorResponse(types.ErrorTypeInvalidRequest, "invalid_api_key", message))
+}
+
+// RequireAdmin rejects requests from consumers that aren't admins with a 403. With authentication
+// disabled every request is an admin's.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if consumer := Consumer(c); consumer != nil && !consumer.Admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, types.NewErrorResponse(types.ErrorTypePermission, "", "This API key is not allowed to use admin endpoints"))
 			return
 		}
-
 		c.Next()
 	}
 }
+
+// Consumer returns the consumer authenticated for the request, or nil when authentication is disabled.
+func Consumer(c *gin.Context) *types.ConsumerData {
+	value, _ := c.Get(ConsumerKey)
+	consumer, _ := value.(*types.ConsumerData)
+	return consumer
+}
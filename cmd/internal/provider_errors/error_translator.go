@@ -18,6 +18,11 @@ func TranslateOpenAIError(err error) error {
 		return nil
 	}
 
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return err
+	}
+
 	if errors.Is(err, context.Canceled) {
 		return &ProviderError{
 			Type:          ErrorTypeCanceled,
@@ -122,6 +127,11 @@ func TranslateAnthropicError(err error) error {
 		return nil
 	}
 
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return err
+	}
+
 	// Check for context errors first
 	if errors.Is(err, context.Canceled) {
 		return &ProviderError{
@@ -236,6 +246,11 @@ func TranslateGeminiError(err error) error {
 		return nil
 	}
 
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return err
+	}
+
 	// Check for context errors first
 	if errors.Is(err, context.Canceled) {
 		return &ProviderError{
@@ -20,6 +20,7 @@ const (
 	ErrorTypeServerError
 	ErrorTypeNetworkError
 	ErrorTypeUnavailable
+	ErrorTypeInvalidResponse
 	ErrorTypeUnknown
 )
 
@@ -63,6 +64,8 @@ func (t ErrorType) String() string {
 		return "network_error"
 	case ErrorTypeUnavailable:
 		return "unavailable"
+	case ErrorTypeInvalidResponse:
+		return "invalid_response"
 	default:
 		return "unknown"
 	}
@@ -128,6 +131,19 @@ func NewNetworkError(provider string, err error) *ProviderError {
 	}
 }
 
+// NewInvalidResponseError reports a response that doesn't match what the request asked for,
+// e.g. malformed structured output. Another attempt or provider may well get it right.
+func NewInvalidResponseError(provider string, message string, err error) *ProviderError {
+	return &ProviderError{
+		Type:          ErrorTypeInvalidResponse,
+		ProviderName:  provider,
+		StatusCode:    502,
+		Message:       message,
+		OriginalError: err,
+		Retryable:     true,
+	}
+}
+
 func IsRetryableError(err error) bool {
 	if err == nil {
 		return false
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"llm-router/cmd/internal/metrics"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
 	"github.com/pkoukk/tiktoken-go"
 	"go.uber.org/zap"
 )
@@ -20,6 +22,7 @@ var logger = utils.SetUpLogger()
 
 type AnthropicConfig struct {
 	APIKey    string
+	Keys      *KeyPool // Optional; several keys to use instead of APIKey
 	MaxTokens int64
 	Model     string
 	Timeout   time.Duration
@@ -27,10 +30,10 @@ type AnthropicConfig struct {
 
 type AnthropicProvider struct {
 	client          anthropic.Client
-	model           anthropic.Model // SDK model
-	standardModelID string          // Standardized model ID for cost calculation
+	standardModelID string // Default model's catalog ID
 	maxTokens       int64
 	timeout         time.Duration
+	keys            *KeyPool
 }
 
 func (a *AnthropicProvider) Complete(ctx context.Context, input *types.CompletionInput) (*types.CompletionResponse, error) {
@@ -40,23 +43,24 @@ func (a *AnthropicProvider) Complete(ctx context.Context, input *types.Completio
 	ctx, cancel := context.WithTimeout(ctx, a.timeout)
 	defer cancel()
 
-	modelToUse := a.model
-	standardModelID := a.standardModelID
-	if input.Model != "" {
-		sdkModel, err := MapToAnthropicModel(input.Model)
-		if err != nil {
-			return nil, fmt.Errorf("invalid anthropic model: %w", err)
-		}
-		modelToUse = sdkModel
-		standardModelID = input.Model
+	model, err := requestModel(ProviderAnthropic, a.standardModelID, input.Model)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anthropic model: %w", err)
 	}
+	standardModelID := model.ID
 
-	anthropicMessages := a.convertMessages(input.Messages)
+	params, err := a.buildParams(model, input.Messages, input.Params)
+	if err != nil {
+		return nil, err
+	}
+	a.applyTools(&params, input.Tools, input.ToolChoice)
+	if err := a.applyResponseFormat(&params, input.ResponseFormat); err != nil {
+		return nil, err
+	}
+	structured := input.ResponseFormat.IsJSON()
 
-	message, err := a.client.Messages.New(ctx, anthropic.MessageNewParams{
-		MaxTokens: a.maxTokens,
-		Messages:  anthropicMessages,
-		Model:     modelToUse,
+	message, err := withAPIKey(a.keys, providerName, providererrors.TranslateAnthropicError, func(key string) (*anthropic.Message, error) {
+		return a.client.Messages.New(ctx, params, option.WithAPIKey(key))
 	})
 
 	duration := time.Since(start).Seconds()
@@ -109,7 +113,7 @@ func (a *AnthropicProvider) Complete(ctx context.Context, input *types.Completio
 		)
 	}
 
-	response := a.convertToRouterMessage(message)
+	response := a.convertToRouterMessage(message, structured)
 	return &types.CompletionResponse{
 		Message: *response,
 		Usage: types.Usage{
@@ -117,39 +121,55 @@ func (a *AnthropicProvider) Complete(ctx context.Context, input *types.Completio
 			CompletionTokens: outputTokens,
 			TotalTokens:      inputTokens + outputTokens,
 		},
-		CostUSD: cost,
+		CostUSD:      cost,
+		Model:        standardModelID,
+		FinishReason: anthropicResponseFinishReason(message.StopReason, structured),
 	}, nil
 }
 
 func (a *AnthropicProvider) CompleteStream(ctx context.Context, input *types.StreamCompletionInput) (<-chan *types.StreamChunk, error) {
 	ctx, cancel := context.WithTimeout(ctx, a.timeout)
 
-	modelToUse := a.model
-	standardModelID := a.standardModelID
-	if input.Model != "" {
-		sdkModel, err := MapToAnthropicModel(input.Model)
-		if err != nil {
-			cancel()
-			return nil, fmt.Errorf("invalid anthropic model: %w", err)
-		}
-		modelToUse = sdkModel
-		standardModelID = input.Model
+	model, err := requestModel(ProviderAnthropic, a.standardModelID, input.Model)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid anthropic model: %w", err)
 	}
+	standardModelID := model.ID
 
-	anthropicMessages := a.convertMessages(input.Messages)
+	params, err := a.buildParams(model, input.Messages, input.Params)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	a.applyTools(&params, input.Tools, input.ToolChoice)
+	if err := a.applyResponseFormat(&params, input.ResponseFormat); err != nil {
+		cancel()
+		return nil, err
+	}
+	structured := input.ResponseFormat.IsJSON()
 
-	stream := a.client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
-		MaxTokens: a.maxTokens,
-		Messages:  anthropicMessages,
-		Model:     modelToUse,
+	// The request is sent before the first event is read, so a rejected key shows up here
+	stream, err := withAPIKey(a.keys, ProviderAnthropic, providererrors.TranslateAnthropicError, func(key string) (*ssestream.Stream[anthropic.MessageStreamEventUnion], error) {
+		stream := a.client.Messages.NewStreaming(ctx, params, option.WithAPIKey(key))
+		return stream, stream.Err()
 	})
+	if err != nil {
+		cancel()
+		return nil, providererrors.TranslateAnthropicError(err)
+	}
 
 	chunks := make(chan *types.StreamChunk)
+	state := newStreamState(ctx, chunks, standardModelID, input.Messages)
 	message := anthropic.Message{}
 	go func() {
 		defer cancel()
 		defer close(chunks)
 
+		// Tool calls are numbered separately from Anthropic's content block indexes
+		toolIndex := -1
+
+	events:
 		for stream.Next() {
 			event := stream.Current()
 
@@ -167,12 +187,41 @@ func (a *AnthropicProvider) CompleteStream(ctx context.Context, input *types.Str
 			}
 
 			switch eventVariant := event.AsAny().(type) {
+			case anthropic.ContentBlockStartEvent:
+				// A structured response arrives as the forced tool call's input, streamed as content
+				if eventVariant.ContentBlock.Type == "tool_use" && !structured {
+					toolIndex++
+					if !state.sendToolCalls([]types.ToolCallDelta{{
+						Index:    toolIndex,
+						ID:       eventVariant.ContentBlock.ID,
+						Type:     "function",
+						Function: types.FunctionCallDelta{Name: eventVariant.ContentBlock.Name},
+					}}) {
+						break events
+					}
+				}
+
 			case anthropic.ContentBlockDeltaEvent:
 				switch deltaVariant := eventVariant.Delta.AsAny().(type) {
 				case anthropic.TextDelta:
-					chunks <- &types.StreamChunk{
-						Content: deltaVariant.Text,
-						Done:    false,
+					if !state.sendContent(deltaVariant.Text) {
+						break events
+					}
+				case anthropic.InputJSONDelta:
+					if deltaVariant.PartialJSON == "" {
+						continue
+					}
+					if structured {
+						if !state.sendContent(deltaVariant.PartialJSON) {
+							break events
+						}
+						continue
+					}
+					if !state.sendToolCalls([]types.ToolCallDelta{{
+						Index:    toolIndex,
+						Function: types.FunctionCallDelta{Arguments: deltaVariant.PartialJSON},
+					}}) {
+						break events
 					}
 				}
 
@@ -188,11 +237,24 @@ func (a *AnthropicProvider) CompleteStream(ctx context.Context, input *types.Str
 						CompletionTokens: outputTokens,
 						TotalTokens:      inputTokens + outputTokens,
 					},
-					CostUSD: cost,
+					CostUSD:      cost,
+					FinishReason: anthropicResponseFinishReason(message.StopReason, structured),
 				}
 			}
 		}
 
+		if ctx.Err() != nil {
+			logger.Warn("Stream cancelled before completion", zap.Error(ctx.Err()))
+			inputTokens := int(message.Usage.InputTokens)
+			outputTokens := int(message.Usage.OutputTokens)
+			state.finishCancelled(types.Usage{
+				PromptTokens:     inputTokens,
+				CompletionTokens: outputTokens,
+				TotalTokens:      inputTokens + outputTokens,
+			}, providererrors.TranslateAnthropicError(ctx.Err()))
+			return
+		}
+
 		if err := stream.Err(); err != nil {
 			logger.Sugar().Errorf("An error occurred while streaming: %v", err)
 			providerErr := providererrors.TranslateAnthropicError(err)
@@ -207,42 +269,270 @@ func (a *AnthropicProvider) CompleteStream(ctx context.Context, input *types.Str
 	return chunks, nil
 }
 
-func (a *AnthropicProvider) convertMessages(messages []types.Message) []anthropic.MessageParam {
+// buildParams maps the request onto Anthropic's parameters.
+// System messages go to the top-level system field (see splitSystemPrompt for how they are merged).
+// Anthropic only accepts temperatures up to 1, so higher values are clamped, and it has no
+// frequency or presence penalties, so requests setting them are rejected rather than silently changed.
+func (a *AnthropicProvider) buildParams(model ModelInfo, messages []types.Message, params types.SamplingParams) (anthropic.MessageNewParams, error) {
+	system, conversation := splitSystemPrompt(messages)
+
+	anthropicMessages, err := a.convertMessages(conversation)
+	if err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+
+	request := anthropic.MessageNewParams{
+		MaxTokens: resolveMaxTokens(params, model.MaxTokensOr(a.maxTokens)),
+		Messages:  anthropicMessages,
+		Model:     anthropic.Model(model.UpstreamName),
+	}
+
+	if len(model.Params) > 0 {
+		request.SetExtraFields(model.Params)
+	}
+
+	if system != "" {
+		request.System = []anthropic.TextBlockParam{{Text: system}}
+	}
+
+	if params.FrequencyPenalty != nil && *params.FrequencyPenalty != 0 {
+		return request, unsupportedParam(ProviderAnthropic, "frequency_penalty")
+	}
+	if params.PresencePenalty != nil && *params.PresencePenalty != 0 {
+		return request, unsupportedParam(ProviderAnthropic, "presence_penalty")
+	}
+
+	if params.Temperature != nil {
+		request.Temperature = anthropic.Float(clamp(*params.Temperature, 0, 1))
+	}
+	if params.TopP != nil {
+		request.TopP = anthropic.Float(*params.TopP)
+	}
+
+	return request, nil
+}
+
+// applyTools adds the request's tools as Anthropic tool definitions. "required" maps to
+// Anthropic's "any" choice and a named function to a "tool" choice.
+func (a *AnthropicProvider) applyTools(request *anthropic.MessageNewParams, tools []types.Tool, choice *types.ToolChoice) {
+	for _, tool := range tools {
+		definition := anthropic.ToolParam{
+			Name:        tool.Function.Name,
+			InputSchema: anthropicInputSchema(tool.Function.Parameters),
+		}
+		if tool.Function.Description != "" {
+			definition.Description = anthropic.String(tool.Function.Description)
+		}
+		request.Tools = append(request.Tools, anthropic.ToolUnionParam{OfTool: &definition})
+	}
+
+	if choice == nil {
+		return
+	}
+
+	switch choice.Mode {
+	case types.ToolChoiceNone:
+		request.ToolChoice = anthropic.ToolChoiceUnionParam{OfNone: &anthropic.ToolChoiceNoneParam{}}
+	case types.ToolChoiceRequired:
+		request.ToolChoice = anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}}
+	case types.ToolChoiceFunction:
+		request.ToolChoice = anthropic.ToolChoiceUnionParam{OfTool: &anthropic.ToolChoiceToolParam{Name: choice.Function}}
+	default:
+		request.ToolChoice = anthropic.ToolChoiceUnionParam{OfAuto: &anthropic.ToolChoiceAutoParam{}}
+	}
+}
+
+// anthropicInputSchema splits a JSON schema into the fields Anthropic's input schema names explicitly
+// and passes everything else through unchanged.
+func anthropicInputSchema(parameters map[string]any) anthropic.ToolInputSchemaParam {
+	schema := anthropic.ToolInputSchemaParam{}
+	extra := map[string]any{}
+
+	for key, value := range parameters {
+		switch key {
+		case "type":
+		case "properties":
+			schema.Properties = value
+		case "required":
+			if required, ok := value.([]any); ok {
+				for _, name := range required {
+					if s, ok := name.(string); ok {
+						schema.Required = append(schema.Required, s)
+					}
+				}
+			}
+		default:
+			extra[key] = value
+		}
+	}
+
+	if len(extra) > 0 {
+		schema.ExtraFields = extra
+	}
+	return schema
+}
+
+// anthropicResponseTool names the tool that carries a json_object response.
+const anthropicResponseTool = "json_response"
+
+// applyResponseFormat forces structured output through a tool call, as Anthropic has no JSON mode:
+// the model is given one tool whose input schema is the response schema and must call it, and the
+// call's input becomes the response content. The request's own tools can't be offered alongside it.
+func (a *AnthropicProvider) applyResponseFormat(request *anthropic.MessageNewParams, format *types.ResponseFormat) error {
+	if !format.IsJSON() {
+		return nil
+	}
+	if len(request.Tools) > 0 {
+		return unsupportedParam(ProviderAnthropic, "response_format together with tools")
+	}
+
+	definition := anthropic.ToolParam{
+		Name:        anthropicResponseTool,
+		Description: anthropic.String("Respond with a JSON object."),
+		InputSchema: anthropicInputSchema(format.ResponseSchema()),
+	}
+	if format.Type == types.ResponseFormatJSONSchema {
+		definition.Name = format.JSONSchema.Name
+		if format.JSONSchema.Description != "" {
+			definition.Description = anthropic.String(format.JSONSchema.Description)
+		}
+	}
+
+	request.Tools = []anthropic.ToolUnionParam{{OfTool: &definition}}
+	request.ToolChoice = anthropic.ToolChoiceUnionParam{OfTool: &anthropic.ToolChoiceToolParam{Name: definition.Name}}
+	return nil
+}
+
+// anthropicResponseFinishReason reports a structured response's forced tool call as a normal stop.
+func anthropicResponseFinishReason(reason anthropic.StopReason, structured bool) string {
+	if structured && reason == anthropic.StopReasonToolUse {
+		return types.FinishReasonStop
+	}
+	return anthropicFinishReason(reason)
+}
+
+// convertMessages maps the conversation onto Anthropic messages. Assistant tool calls become
+// tool_use blocks, and tool results become tool_result blocks in a user message; consecutive
+// results answering the same turn share one message, as Anthropic expects.
+func (a *AnthropicProvider) convertMessages(messages []types.Message) ([]anthropic.MessageParam, error) {
 	var anthropicMessages []anthropic.MessageParam
+	lastWasToolResult := false
 
 	for _, message := range messages {
 		var toAppend anthropic.MessageParam
 
-		if message.Role == "user" {
-			toAppend = anthropic.NewUserMessage(anthropic.NewTextBlock(message.Content))
-		} else {
-			toAppend = anthropic.NewAssistantMessage(anthropic.NewTextBlock(message.Content))
+		switch message.Role {
+		case "user":
+			if len(message.Parts) == 0 {
+				toAppend = anthropic.NewUserMessage(anthropic.NewTextBlock(message.Content))
+				break
+			}
+			blocks, err := anthropicContentBlocks(message.Parts)
+			if err != nil {
+				return nil, err
+			}
+			toAppend = anthropic.NewUserMessage(blocks...)
+
+		case "tool":
+			result := anthropic.NewToolResultBlock(message.ToolCallID, message.Content, false)
+			if lastWasToolResult {
+				last := &anthropicMessages[len(anthropicMessages)-1]
+				last.Content = append(last.Content, result)
+				continue
+			}
+			toAppend = anthropic.NewUserMessage(result)
+
+		default:
+			var blocks []anthropic.ContentBlockParamUnion
+			if message.Content != "" {
+				blocks = append(blocks, anthropic.NewTextBlock(message.Content))
+			}
+			for _, call := range message.ToolCalls {
+				blocks = append(blocks, anthropic.NewToolUseBlock(call.ID, parseToolArguments(call.Function.Arguments), call.Function.Name))
+			}
+			toAppend = anthropic.NewAssistantMessage(blocks...)
 		}
 
+		lastWasToolResult = message.Role == "tool"
 		anthropicMessages = append(anthropicMessages, toAppend)
 	}
-	return anthropicMessages
+	return anthropicMessages, nil
 }
 
-func (a *AnthropicProvider) convertToRouterMessage(message *anthropic.Message) *types.Message {
+// anthropicContentBlocks maps content parts onto Anthropic blocks. Images become image blocks
+// and PDF or plain text files become document blocks; Anthropic doesn't accept audio.
+func anthropicContentBlocks(parts []types.ContentPart) ([]anthropic.ContentBlockParamUnion, error) {
+	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case types.ContentPartImageURL:
+			if dataURL, err := types.ParseDataURL(part.ImageURL.URL); err == nil {
+				blocks = append(blocks, anthropic.NewImageBlockBase64(dataURL.MediaType, dataURL.Data))
+			} else {
+				blocks = append(blocks, anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: part.ImageURL.URL}))
+			}
+
+		case types.ContentPartFile:
+			dataURL, err := types.ParseDataURL(part.File.FileData)
+			if err != nil {
+				return nil, providererrors.NewValidationError(ProviderAnthropic, err.Error(), err)
+			}
+			switch dataURL.MediaType {
+			case "application/pdf":
+				blocks = append(blocks, anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{Data: dataURL.Data}))
+			case "text/plain":
+				text, _ := base64.StdEncoding.DecodeString(dataURL.Data)
+				blocks = append(blocks, anthropic.NewDocumentBlock(anthropic.PlainTextSourceParam{Data: string(text)}))
+			default:
+				return nil, unsupportedParam(ProviderAnthropic, dataURL.MediaType+" files")
+			}
+
+		case types.ContentPartInputAudio:
+			return nil, unsupportedParam(ProviderAnthropic, "input_audio")
+
+		default:
+			blocks = append(blocks, anthropic.NewTextBlock(part.Text))
+		}
+	}
+	return blocks, nil
+}
+
+// convertToRouterMessage maps Anthropic's response onto a message. For a structured response
+// the forced tool call's input is the content.
+func (a *AnthropicProvider) convertToRouterMessage(message *anthropic.Message, structured bool) *types.Message {
 	var content string
+	var toolCalls []types.ToolCall
 
 	for _, block := range message.Content {
 		switch block.Type {
 		case "text":
-			content = block.Text
+			if content == "" {
+				content = block.Text
+			}
 		case "thinking":
-			content = block.Thinking
-		}
-
-		if content != "" {
-			break
+			if content == "" {
+				content = block.Thinking
+			}
+		case "tool_use":
+			if structured {
+				content = string(block.Input)
+				continue
+			}
+			toolCalls = append(toolCalls, types.ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: types.FunctionCall{
+					Name:      block.Name,
+					Arguments: string(block.Input),
+				},
+			})
 		}
 	}
 
 	return &types.Message{
-		Role:    string(message.Role),
-		Content: content,
+		Role:      string(message.Role),
+		Content:   content,
+		ToolCalls: toolCalls,
 	}
 }
 
@@ -255,7 +545,7 @@ func (a *AnthropicProvider) CountTokens(ctx context.Context, messages []types.Me
 	totalTokens := 0
 	for _, msg := range messages {
 		tokens := encoding.Encode(msg.Content, nil, nil)
-		totalTokens += len(tokens)
+		totalTokens += len(tokens) + mediaTokens(msg)
 
 		// Add overhead for role and message formatting
 		// Anthropic format: ~4 tokens per message for structure
@@ -269,17 +559,24 @@ func (a *AnthropicProvider) GetProviderName() string {
 	return ProviderAnthropic
 }
 
+// KeyUsage returns the usage and health of the provider's API keys.
+func (a *AnthropicProvider) KeyUsage() []KeyStats {
+	return a.keys.Stats()
+}
+
 func NewAnthropicProvider(config AnthropicConfig) (*AnthropicProvider, error) {
-	if config.APIKey == "" {
+	keys, err := resolveKeyPool(config.Keys, config.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
 		return nil, fmt.Errorf("API key cannot be empty")
 	}
 
-	client := anthropic.NewClient(
-		option.WithAPIKey(config.APIKey),
-	)
+	// The key is chosen per request
+	client := anthropic.NewClient()
 
-	model, err := MapToAnthropicModel(config.Model)
-	if err != nil {
+	if _, err := ResolveModel(ProviderAnthropic, config.Model); err != nil {
 		return nil, fmt.Errorf("invalid Anthropic model: %w", err)
 	}
 
@@ -290,9 +587,9 @@ func NewAnthropicProvider(config AnthropicConfig) (*AnthropicProvider, error) {
 
 	return &AnthropicProvider{
 		client:          client,
-		model:           model,
 		standardModelID: config.Model,
 		maxTokens:       int64(config.MaxTokens),
 		timeout:         timeout,
+		keys:            keys,
 	}, nil
 }
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// ProviderTypeAzureOpenAI is the provider type for Azure OpenAI resources.
+const ProviderTypeAzureOpenAI = "azure-openai"
+
+type AzureOpenAIConfig struct {
+	Name       string // Instance name, e.g. "azure-eastus"; prefixes the instance's catalog models
+	Endpoint   string // e.g. "https://my-resource.openai.azure.com"
+	APIVersion string // e.g. "2024-10-21"
+	APIKey     string
+	Keys       *KeyPool // Optional; several keys to use instead of APIKey
+	// Catalog model ID to deployment name. Models without one use their upstream name as the deployment.
+	Deployments map[string]string
+	Headers     map[string]string
+	MaxTokens   int64
+	Model       string
+	Timeout     time.Duration
+}
+
+// AzureOpenAIProvider calls models deployed on an Azure OpenAI resource. Azure addresses models
+// by deployment rather than by name, so each call is sent to the URL of the model's deployment.
+// Several resources can be configured side by side, each under its own name.
+type AzureOpenAIProvider struct {
+	*OpenAIProvider
+}
+
+func NewAzureOpenAIProvider(config AzureOpenAIConfig) (*AzureOpenAIProvider, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("name is required for %s providers", ProviderTypeAzureOpenAI)
+	}
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("baseURL is required for provider %s", config.Name)
+	}
+	if config.APIVersion == "" {
+		return nil, fmt.Errorf("apiVersion is required for provider %s", config.Name)
+	}
+	if config.APIKey == "" && config.Keys == nil {
+		return nil, fmt.Errorf("API key is required for provider %s", config.Name)
+	}
+
+	endpoint := strings.TrimSuffix(config.Endpoint, "/")
+
+	opts := []option.RequestOption{
+		option.WithQueryAdd("api-version", config.APIVersion),
+		// The SDK sends OPENAI_* credentials from the environment by default; Azure authenticates
+		// with an api-key header, added per request
+		option.WithHeaderDel("Authorization"),
+		option.WithHeaderDel("OpenAI-Organization"),
+		option.WithHeaderDel("OpenAI-Project"),
+	}
+	for name, value := range config.Headers {
+		opts = append(opts, option.WithHeader(name, value))
+	}
+
+	provider, err := newOpenAIProvider(config.Name, OpenAIConfig{
+		APIKey:    config.APIKey,
+		Keys:      config.Keys,
+		MaxTokens: config.MaxTokens,
+		Model:     config.Model,
+		Timeout:   config.Timeout,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.keyOption = func(key string) option.RequestOption {
+		return option.WithHeader("api-key", key)
+	}
+	provider.modelOptions = func(model ModelInfo) []option.RequestOption {
+		deployment := config.Deployments[model.ID]
+		if deployment == "" {
+			deployment = model.UpstreamName
+		}
+		return []option.RequestOption{
+			option.WithBaseURL(endpoint + "/openai/deployments/" + url.PathEscape(deployment) + "/"),
+		}
+	}
+
+	return &AzureOpenAIProvider{OpenAIProvider: provider}, nil
+}
@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"context"
+	"llm-router/types"
+	"testing"
+)
+
+func TestAzureOpenAIProvider_Complete(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+
+	server := newFakeOpenAIServer(t)
+
+	provider, err := NewAzureOpenAIProvider(AzureOpenAIConfig{
+		Name:        "azure-eastus",
+		Endpoint:    server.URL + "/",
+		APIVersion:  "2024-10-21",
+		APIKey:      "azure-key",
+		Deployments: map[string]string{"azure-eastus/gpt-4o": "gpt4o-prod"},
+		MaxTokens:   256,
+		Model:       "azure-eastus/gpt-4o",
+	})
+	if err != nil {
+		t.Fatalf("NewAzureOpenAIProvider() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		model    string
+		wantPath string
+		wantCost bool
+	}{
+		{name: "mapped deployment", model: "", wantPath: "/openai/deployments/gpt4o-prod/chat/completions", wantCost: true},
+		{name: "unmapped model uses its upstream name", model: "azure-eastus/gpt-4o-mini", wantPath: "/openai/deployments/gpt-4o-mini/chat/completions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := provider.Complete(context.Background(), &types.CompletionInput{
+				Model:    tt.model,
+				Messages: []types.Message{{Role: "user", Content: "Hi"}},
+			})
+			if err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+
+			if server.lastURL.Path != tt.wantPath {
+				t.Errorf("path = %s, want %s", server.lastURL.Path, tt.wantPath)
+			}
+			if got := server.lastURL.Query().Get("api-version"); got != "2024-10-21" {
+				t.Errorf("api-version = %q, want the configured version", got)
+			}
+			if got := server.lastHeaders.Get("api-key"); got != "azure-key" {
+				t.Errorf("api-key header = %q, want the configured key", got)
+			}
+			if got := server.lastHeaders.Get("Authorization"); got != "" {
+				t.Errorf("Authorization header = %q, want none", got)
+			}
+			if (response.CostUSD > 0) != tt.wantCost {
+				t.Errorf("CostUSD = %v, want cost from the instance's catalog entry: %v", response.CostUSD, tt.wantCost)
+			}
+		})
+	}
+
+	if provider.GetProviderName() != "azure-eastus" {
+		t.Errorf("GetProviderName() = %s, want the instance name", provider.GetProviderName())
+	}
+}
+
+func TestProviderFactory_AzureOpenAI(t *testing.T) {
+	factory := NewProviderFactory()
+
+	config := types.ProviderConfigWithExtras{
+		Name:       "azure-eastus",
+		Type:       ProviderTypeAzureOpenAI,
+		APIKey:     "azure-key",
+		BaseURL:    "https://example.openai.azure.com",
+		APIVersion: "2024-10-21",
+		Enabled:    true,
+		Defaults: &types.ProviderExtra{
+			Model:     "azure-eastus/gpt-4o",
+			MaxTokens: 1024,
+		},
+	}
+
+	provider, err := factory.CreateProvider(config)
+	if err != nil {
+		t.Fatalf("CreateProvider() error = %v", err)
+	}
+	if provider.GetProviderName() != "azure-eastus" {
+		t.Errorf("GetProviderName() = %s, want azure-eastus", provider.GetProviderName())
+	}
+
+	config.APIVersion = ""
+	if _, err := factory.CreateProvider(config); err == nil {
+		t.Error("expected an error without an API version")
+	}
+}
@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"encoding/base64"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"llm-router/types"
+	"math"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Image token estimates follow OpenAI's accounting, which the other providers are close to:
+// a low-detail image costs a flat 85 tokens, while other images are scaled to fit 2048x2048,
+// then down to 768px on the shortest side, and cost 170 tokens per 512px tile plus 85.
+const (
+	imageBaseTokens = 85
+	imageTileTokens = 170
+	imageTileSize   = 512
+	// Used when the image's size is unknown, e.g. for URLs: a 1024x1024 image
+	defaultImageTokens = imageBaseTokens + 4*imageTileTokens
+)
+
+// mediaTokens estimates the prompt tokens taken by the images in a message.
+func mediaTokens(message types.Message) int {
+	tokens := 0
+	for _, part := range message.Parts {
+		if part.Type == types.ContentPartImageURL {
+			tokens += estimateImageTokens(part.ImageURL)
+		}
+	}
+	return tokens
+}
+
+func estimateImageTokens(img *types.ImageURL) int {
+	if img.Detail == "low" {
+		return imageBaseTokens
+	}
+
+	dataURL, err := types.ParseDataURL(img.URL)
+	if err != nil {
+		return defaultImageTokens
+	}
+
+	config, _, err := image.DecodeConfig(base64.NewDecoder(base64.StdEncoding, strings.NewReader(dataURL.Data)))
+	if err != nil || config.Width == 0 || config.Height == 0 {
+		return defaultImageTokens
+	}
+
+	width, height := float64(config.Width), float64(config.Height)
+	if longest := math.Max(width, height); longest > 2048 {
+		width, height = width*2048/longest, height*2048/longest
+	}
+	if shortest := math.Min(width, height); shortest > 768 {
+		width, height = width*768/shortest, height*768/shortest
+	}
+
+	tiles := math.Ceil(width/imageTileSize) * math.Ceil(height/imageTileSize)
+	return imageBaseTokens + int(tiles)*imageTileTokens
+}
+
+// imageMediaType guesses an image's media type from its URL, for providers that need one.
+func imageMediaType(imageURL string) string {
+	if parsed, err := url.Parse(imageURL); err == nil {
+		if mediaType := mime.TypeByExtension(strings.ToLower(path.Ext(parsed.Path))); strings.HasPrefix(mediaType, "image/") {
+			return mediaType
+		}
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,118 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"llm-router/types"
+	"reflect"
+	"testing"
+)
+
+func pngDataURL(t *testing.T, width, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func imageMessage(url string, detail string) types.Message {
+	return types.Message{Role: "user", Content: "What's this?", Parts: []types.ContentPart{
+		{Type: types.ContentPartText, Text: "What's this?"},
+		{Type: types.ContentPartImageURL, ImageURL: &types.ImageURL{URL: url, Detail: detail}},
+	}}
+}
+
+func TestMessage_ContentParts(t *testing.T) {
+	var message types.Message
+	body := `{"role": "user", "content": [{"type": "text", "text": "What's this?"}, {"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}}]}`
+	if err := json.Unmarshal([]byte(body), &message); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if want := imageMessage("https://example.com/cat.png", ""); !reflect.DeepEqual(message, want) {
+		t.Errorf("message = %+v, want %+v", message, want)
+	}
+	if !message.HasMedia() {
+		t.Error("HasMedia() = false, want true")
+	}
+
+	request := types.Completion{Messages: []types.Message{message}}
+	if got := request.RequiredCapabilities(); !reflect.DeepEqual(got, []string{types.CapabilityVision}) {
+		t.Errorf("RequiredCapabilities() = %v, want [%s]", got, types.CapabilityVision)
+	}
+
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var roundTrip types.Message
+	if err := json.Unmarshal(encoded, &roundTrip); err != nil || !reflect.DeepEqual(roundTrip, message) {
+		t.Errorf("round trip = %+v (err %v), want %+v", roundTrip, err, message)
+	}
+}
+
+func TestMediaTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		message types.Message
+		want    int
+	}{
+		{name: "text only", message: types.Message{Role: "user", Content: "hi"}, want: 0},
+		{name: "low detail", message: imageMessage(pngDataURL(t, 1024, 1024), "low"), want: 85},
+		{name: "small image", message: imageMessage(pngDataURL(t, 100, 100), ""), want: 255},
+		{name: "large image", message: imageMessage(pngDataURL(t, 1024, 1024), "high"), want: 765},
+		{name: "remote image", message: imageMessage("https://example.com/cat.png", ""), want: defaultImageTokens},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mediaTokens(tt.message); got != tt.want {
+				t.Errorf("mediaTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentPartMapping(t *testing.T) {
+	dataURL := pngDataURL(t, 10, 10)
+	parts := []types.ContentPart{
+		{Type: types.ContentPartText, Text: "Compare these"},
+		{Type: types.ContentPartImageURL, ImageURL: &types.ImageURL{URL: dataURL}},
+		{Type: types.ContentPartImageURL, ImageURL: &types.ImageURL{URL: "https://example.com/cat.png"}},
+	}
+
+	openAIParts := openAIContentParts(parts)
+	if len(openAIParts) != 3 || openAIParts[1].OfImageURL == nil || openAIParts[1].OfImageURL.ImageURL.URL != dataURL {
+		t.Errorf("OpenAI parts = %+v, want text and two image_url parts", openAIParts)
+	}
+
+	blocks, err := anthropicContentBlocks(parts)
+	if err != nil {
+		t.Fatalf("anthropicContentBlocks() error = %v", err)
+	}
+	if len(blocks) != 3 || blocks[1].OfImage == nil || blocks[1].OfImage.Source.OfBase64 == nil || blocks[2].OfImage.Source.OfURL == nil {
+		t.Errorf("Anthropic blocks = %+v, want a base64 and a URL image block", blocks)
+	}
+
+	if _, err := anthropicContentBlocks([]types.ContentPart{
+		{Type: types.ContentPartInputAudio, InputAudio: &types.InputAudio{Data: "AAAA", Format: "wav"}},
+	}); err == nil {
+		t.Error("expected Anthropic to reject audio parts")
+	}
+
+	geminiParts := geminiContentParts(parts)
+	if len(geminiParts) != 3 {
+		t.Fatalf("expected 3 Gemini parts, got %d", len(geminiParts))
+	}
+	if inline := geminiParts[1].InlineData; inline == nil || inline.MIMEType != "image/png" || len(inline.Data) == 0 {
+		t.Errorf("InlineData = %+v, want decoded PNG bytes", inline)
+	}
+	if file := geminiParts[2].FileData; file == nil || file.FileURI != "https://example.com/cat.png" || file.MIMEType != "image/png" {
+		t.Errorf("FileData = %+v, want the image URL as image/png", file)
+	}
+}
@@ -9,50 +9,56 @@ func GetDefaultCatalog() []types.ModelConfig {
 			ID:              "openai/gpt-5.1",
 			Provider:        "openai",
 			Name:            "GPT-5.1",
+			UpstreamName:    "gpt-5.1-chat-latest",
 			InputCostPer1M:  7.50,
 			OutputCostPer1M: 22.50,
 			ContextWindow:   200000,
 			Tier:            "ultra-premium",
-			Capabilities:    []string{"coding", "reasoning", "creative"},
+			Capabilities:    []string{"coding", "reasoning", "creative", "tools", "vision", "files"},
 		},
 		{
 			ID:              "openai/gpt-5",
 			Provider:        "openai",
 			Name:            "GPT-5",
+			UpstreamName:    "gpt-5-2025-08-07",
 			InputCostPer1M:  5.00,
 			OutputCostPer1M: 15.00,
 			ContextWindow:   200000,
 			Tier:            "ultra-premium",
-			Capabilities:    []string{"coding", "reasoning", "creative"},
+			Capabilities:    []string{"coding", "reasoning", "creative", "tools", "vision", "files"},
 		},
 		{
 			ID:              "openai/gpt-4o",
 			Provider:        "openai",
 			Name:            "GPT-4o",
+			UpstreamName:    "chatgpt-4o-latest",
 			InputCostPer1M:  2.50,
 			OutputCostPer1M: 10.00,
 			ContextWindow:   128000,
 			Tier:            "premium",
-			Capabilities:    []string{"coding", "reasoning", "creative"},
+			Capabilities:    []string{"coding", "reasoning", "creative", "tools", "vision", "files"},
 		},
 		{
 			ID:              "openai/gpt-3.5-turbo",
 			Provider:        "openai",
 			Name:            "GPT-3.5 Turbo",
+			UpstreamName:    "gpt-3.5-turbo",
 			InputCostPer1M:  0.50,
 			OutputCostPer1M: 1.50,
 			ContextWindow:   16385,
 			Tier:            "standard",
+			Capabilities:    []string{"tools"},
 		},
 		{
 			ID:              "openai/gpt-4o-mini",
 			Provider:        "openai",
 			Name:            "GPT-4o Mini",
+			UpstreamName:    "gpt-4o-mini",
 			InputCostPer1M:  0.15,
 			OutputCostPer1M: 0.60,
 			ContextWindow:   128000,
 			Tier:            "budget",
-			Capabilities:    []string{"fast-chat", "extraction"},
+			Capabilities:    []string{"fast-chat", "extraction", "tools", "vision", "files"},
 		},
 
 		// Anthropic Models
@@ -60,40 +66,45 @@ func GetDefaultCatalog() []types.ModelConfig {
 			ID:              "anthropic/claude-opus-4.5",
 			Provider:        "anthropic",
 			Name:            "Claude Opus 4.5",
+			UpstreamName:    "claude-opus-4-5-20251101",
 			InputCostPer1M:  15.00,
 			OutputCostPer1M: 75.00,
 			ContextWindow:   200000,
 			Tier:            "ultra-premium",
-			Capabilities:    []string{"coding", "reasoning", "creative"},
+			Capabilities:    []string{"coding", "reasoning", "creative", "tools", "vision", "files"},
 		},
 		{
 			ID:              "anthropic/claude-sonnet-4",
 			Provider:        "anthropic",
 			Name:            "Claude Sonnet 4",
+			UpstreamName:    "claude-4-sonnet-20250514",
 			InputCostPer1M:  3.00,
 			OutputCostPer1M: 15.00,
 			ContextWindow:   200000,
 			Tier:            "premium",
-			Capabilities:    []string{"coding", "reasoning", "creative"},
+			Capabilities:    []string{"coding", "reasoning", "creative", "tools", "vision", "files"},
 		},
 		{
 			ID:              "anthropic/claude-haiku-4.5",
 			Provider:        "anthropic",
 			Name:            "Claude Haiku 4.5",
+			UpstreamName:    "claude-haiku-4-5-20251001",
 			InputCostPer1M:  0.80,
 			OutputCostPer1M: 4.00,
 			ContextWindow:   200000,
 			Tier:            "standard",
+			Capabilities:    []string{"tools", "vision", "files"},
 		},
 		{
 			ID:              "anthropic/claude-haiku-3",
 			Provider:        "anthropic",
 			Name:            "Claude Haiku 3",
+			UpstreamName:    "claude-3-haiku-20240307",
 			InputCostPer1M:  0.25,
 			OutputCostPer1M: 1.25,
 			ContextWindow:   200000,
 			Tier:            "standard",
-			Capabilities:    []string{"fast-chat", "extraction"},
+			Capabilities:    []string{"fast-chat", "extraction", "tools", "vision"},
 		},
 
 		// Gemini Models
@@ -101,48 +112,88 @@ func GetDefaultCatalog() []types.ModelConfig {
 			ID:              "gemini/gemini-3-pro",
 			Provider:        "gemini",
 			Name:            "Gemini 3.0 Pro",
+			UpstreamName:    "gemini-3-pro-preview",
 			InputCostPer1M:  2.00,
 			OutputCostPer1M: 12.00,
 			ContextWindow:   1000000,
 			Tier:            "premium",
-			Capabilities:    []string{"coding", "reasoning", "creative"},
+			Capabilities:    []string{"coding", "reasoning", "creative", "tools", "vision", "files", "audio"},
 		},
 		{
 			ID:              "gemini/gemini-2.5-pro",
 			Provider:        "gemini",
 			Name:            "Gemini 2.5 Pro",
+			UpstreamName:    "gemini-2.5-pro",
 			InputCostPer1M:  1.25,
 			OutputCostPer1M: 10.00,
 			ContextWindow:   1000000,
 			Tier:            "premium",
+			Capabilities:    []string{"tools", "vision", "files", "audio"},
 		},
 		{
 			ID:              "gemini/gemini-3-flash",
 			Provider:        "gemini",
 			Name:            "Gemini 3.0 Flash",
+			UpstreamName:    "gemini-3-flash-preview",
 			InputCostPer1M:  0.50,
 			OutputCostPer1M: 3.00,
 			ContextWindow:   1000000,
 			Tier:            "standard",
+			Capabilities:    []string{"tools", "vision", "files", "audio"},
 		},
 		{
 			ID:              "gemini/gemini-2.5-flash",
 			Provider:        "gemini",
 			Name:            "Gemini 2.5 Flash",
+			UpstreamName:    "gemini-2.5-flash",
 			InputCostPer1M:  0.30,
 			OutputCostPer1M: 2.50,
 			ContextWindow:   1000000,
 			Tier:            "standard",
+			Capabilities:    []string{"tools", "vision", "files", "audio"},
 		},
 		{
 			ID:              "gemini/gemini-2.5-flash-lite",
 			Provider:        "gemini",
 			Name:            "Gemini 2.5 Flash Lite",
+			UpstreamName:    "gemini-2.5-flash-lite",
 			InputCostPer1M:  0.10,
 			OutputCostPer1M: 0.40,
 			ContextWindow:   1000000,
 			Tier:            "budget",
-			Capabilities:    []string{"fast-chat", "extraction"},
+			Capabilities:    []string{"fast-chat", "extraction", "tools", "vision", "files", "audio"},
+		},
+
+		// Embedding Models
+		{
+			ID:             ModelOpenAIEmbedding3Small,
+			Provider:       "openai",
+			Name:           "Text Embedding 3 Small",
+			UpstreamName:   "text-embedding-3-small",
+			InputCostPer1M: 0.02,
+			ContextWindow:  8191,
+			Type:           types.ModelTypeEmbedding,
+			Dimensions:     1536,
+		},
+		{
+			ID:             ModelOpenAIEmbedding3Large,
+			Provider:       "openai",
+			Name:           "Text Embedding 3 Large",
+			UpstreamName:   "text-embedding-3-large",
+			InputCostPer1M: 0.13,
+			ContextWindow:  8191,
+			Type:           types.ModelTypeEmbedding,
+			Dimensions:     3072,
+		},
+		{
+			ID:             ModelGeminiEmbedding,
+			Provider:       "gemini",
+			Name:           "Gemini Embedding",
+			UpstreamName:   "gemini-embedding-001",
+			InputCostPer1M: 0.15,
+			ContextWindow:  2048,
+			Type:           types.ModelTypeEmbedding,
+			Dimensions:     3072,
 		},
 	}
 }
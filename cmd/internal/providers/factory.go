@@ -29,10 +29,17 @@ func (f *ProviderFactory) CreateProvider(config types.ProviderConfigWithExtras)
 
 	timeout := time.Duration(config.Timeout) * time.Millisecond
 
-	switch strings.ToLower(config.Name) {
+	keys, err := f.keyPool(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API keys for provider %s: %w", config.Name, err)
+	}
+
+	switch providerType(config) {
 	case ProviderOpenAI:
 		return NewOpenAIProvider(OpenAIConfig{
 			APIKey:    config.APIKey,
+			Keys:      keys,
+			BaseURL:   config.BaseURL,
 			MaxTokens: config.Defaults.MaxTokens,
 			Model:     config.Defaults.Model,
 			Timeout:   timeout,
@@ -41,6 +48,7 @@ func (f *ProviderFactory) CreateProvider(config types.ProviderConfigWithExtras)
 	case ProviderAnthropic:
 		return NewAnthropicProvider(AnthropicConfig{
 			APIKey:    config.APIKey,
+			Keys:      keys,
 			MaxTokens: config.Defaults.MaxTokens,
 			Model:     config.Defaults.Model,
 			Timeout:   timeout,
@@ -49,18 +57,66 @@ func (f *ProviderFactory) CreateProvider(config types.ProviderConfigWithExtras)
 	case ProviderGemini:
 		return NewGeminiProvider(GeminiConfig{
 			APIKey:    config.APIKey,
+			Keys:      keys,
+			MaxTokens: config.Defaults.MaxTokens,
+			Model:     config.Defaults.Model,
+			Timeout:   timeout,
+		})
+
+	case ProviderTypeOpenAICompatible:
+		return NewOpenAICompatibleProvider(OpenAICompatibleConfig{
+			Name:      strings.ToLower(config.Name),
+			BaseURL:   config.BaseURL,
+			APIKey:    config.APIKey,
+			Keys:      keys,
+			Headers:   config.Headers,
 			MaxTokens: config.Defaults.MaxTokens,
 			Model:     config.Defaults.Model,
 			Timeout:   timeout,
 		})
 
+	case ProviderTypeAzureOpenAI:
+		return NewAzureOpenAIProvider(AzureOpenAIConfig{
+			Name:        strings.ToLower(config.Name),
+			Endpoint:    config.BaseURL,
+			APIVersion:  config.APIVersion,
+			APIKey:      config.APIKey,
+			Keys:        keys,
+			Deployments: config.Deployments,
+			Headers:     config.Headers,
+			MaxTokens:   config.Defaults.MaxTokens,
+			Model:       config.Defaults.Model,
+			Timeout:     timeout,
+		})
+
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", config.Name)
 	}
 }
 
+// providerType returns the implementation to use for a provider. Built-in providers are
+// identified by name; others name their type explicitly so several instances can coexist.
+func providerType(config types.ProviderConfigWithExtras) string {
+	if config.Type != "" {
+		return strings.ToLower(config.Type)
+	}
+	return strings.ToLower(config.Name)
+}
+
+// keyPool builds the pool for a provider configured with several API keys. Providers with a
+// single key build their own.
+func (f *ProviderFactory) keyPool(config types.ProviderConfigWithExtras) (*KeyPool, error) {
+	if len(config.APIKeys) == 0 {
+		return nil, nil
+	}
+
+	keys := append([]string{config.APIKey}, config.APIKeys...)
+	return NewKeyPool(keys, config.KeySelection, time.Duration(config.KeyQuarantine)*time.Millisecond)
+}
+
 func (f *ProviderFactory) validateConfig(config types.ProviderConfigWithExtras) error {
-	if config.APIKey == "" {
+	// Local servers usually run without authentication
+	if config.APIKey == "" && len(config.APIKeys) == 0 && providerType(config) != ProviderTypeOpenAICompatible {
 		return fmt.Errorf("API key is required for provider %s", config.Name)
 	}
 
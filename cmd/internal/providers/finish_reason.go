@@ -0,0 +1,52 @@
+package providers
+
+import (
+	"llm-router/types"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"google.golang.org/genai"
+)
+
+// Each provider reports why generation stopped in its own vocabulary.
+// These helpers translate them into OpenAI's finish_reason values.
+
+func openAIFinishReason(reason string) string {
+	switch reason {
+	case "":
+		return types.FinishReasonStop
+	case "function_call":
+		return types.FinishReasonToolCalls
+	default:
+		return reason
+	}
+}
+
+func anthropicFinishReason(reason anthropic.StopReason) string {
+	switch reason {
+	case anthropic.StopReasonMaxTokens:
+		return types.FinishReasonLength
+	case anthropic.StopReasonToolUse:
+		return types.FinishReasonToolCalls
+	case anthropic.StopReasonRefusal:
+		return types.FinishReasonContentFilter
+	default:
+		return types.FinishReasonStop
+	}
+}
+
+func geminiFinishReason(reason genai.FinishReason) string {
+	switch reason {
+	case genai.FinishReasonMaxTokens:
+		return types.FinishReasonLength
+	case genai.FinishReasonSafety,
+		genai.FinishReasonRecitation,
+		genai.FinishReasonBlocklist,
+		genai.FinishReasonProhibitedContent,
+		genai.FinishReasonSPII,
+		genai.FinishReasonImageSafety,
+		genai.FinishReasonImageProhibitedContent:
+		return types.FinishReasonContentFilter
+	default:
+		return types.FinishReasonStop
+	}
+}
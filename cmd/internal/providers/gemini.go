@@ -2,11 +2,14 @@ package providers
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"iter"
 	"llm-router/cmd/internal/metrics"
 	providererrors "llm-router/cmd/internal/provider_errors"
 	"llm-router/types"
+	"strings"
 	"time"
 
 	"github.com/pkoukk/tiktoken-go"
@@ -16,70 +19,95 @@ import (
 
 type GeminiConfig struct {
 	APIKey    string
+	Keys      *KeyPool // Optional; several keys to use instead of APIKey
 	MaxTokens int64
 	Model     string
 	Timeout   time.Duration
 }
 
 type GeminiProvider struct {
-	client          *genai.Client
+	clients         map[string]*genai.Client // One per API key; the SDK doesn't take a key per request
+	keys            *KeyPool
 	maxTokens       int64
-	model           string
-	standardModelID string
+	standardModelID string // Default model's catalog ID
 	timeout         time.Duration
 }
 
-func (g *GeminiProvider) Complete(ctx context.Context, input *types.CompletionInput) (*types.CompletionResponse, error) {
-	start := time.Now()
-	providerName := g.GetProviderName()
-
-	ctx, cancel := context.WithTimeout(ctx, g.timeout)
-	defer cancel()
+// geminiStream is a streamed response whose first event has already been read.
+type geminiStream struct {
+	first *genai.GenerateContentResponse
+	next  func() (*genai.GenerateContentResponse, error, bool)
+	stop  func()
+}
 
-	modelToUse := g.model
-	standardModelID := g.standardModelID
-	if input.Model != "" {
+// startGeminiStream sends the request and reads the first event, returning the error if it failed.
+func startGeminiStream(events iter.Seq2[*genai.GenerateContentResponse, error]) (*geminiStream, error) {
+	next, stop := iter.Pull2(events)
 
-		sdkModel, err := MapToGeminiModel(input.Model)
-		if err != nil {
-			return nil, fmt.Errorf("invalid gemini model: %w", err)
-		}
-		modelToUse = sdkModel
-		standardModelID = input.Model
+	first, err, ok := next()
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	if !ok {
+		first = nil
 	}
 
-	geminiMessages, currentMessage := g.convertMessages(input.Messages)
-
-	chat, err := g.client.Chats.Create(
-		ctx,
-		modelToUse,
-		nil,
-		geminiMessages,
-	)
+	return &geminiStream{first: first, next: next, stop: stop}, nil
+}
 
-	status := "success"
+// all yields every event of the stream, starting with the one already read.
+func (s *geminiStream) all() iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		defer s.stop()
 
-	if err != nil {
-		status = "error"
+		if s.first == nil || !yield(s.first, nil) {
+			return
+		}
+		for {
+			chunk, err, ok := s.next()
+			if !ok || !yield(chunk, err) {
+				return
+			}
+		}
+	}
+}
 
-		translatedErr := providererrors.TranslateGeminiError(err)
+func (g *GeminiProvider) Complete(ctx context.Context, input *types.CompletionInput) (*types.CompletionResponse, error) {
+	start := time.Now()
+	providerName := g.GetProviderName()
 
-		var providerErr *providererrors.ProviderError
-		if errors.As(translatedErr, &providerErr) {
-			logger.Error("Gemini chat creation failed",
-				zap.String("error_type", providerErr.Type.String()),
-				zap.Int("status_code", providerErr.StatusCode),
-				zap.Bool("retryable", providerErr.Retryable),
-				zap.Error(providerErr.OriginalError),
-			)
-		}
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
 
-		return nil, translatedErr
+	model, err := requestModel(ProviderGemini, g.standardModelID, input.Model)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gemini model: %w", err)
 	}
+	standardModelID := model.ID
+
+	system, conversation := splitSystemPrompt(input.Messages)
+	geminiMessages, currentMessage := g.convertMessages(conversation)
+
+	config := g.buildConfig(model, system, input.Params)
+	g.applyTools(config, input.Tools, input.ToolChoice)
+	g.applyResponseFormat(config, input.ResponseFormat)
+
+	res, err := withAPIKey(g.keys, providerName, providererrors.TranslateGeminiError, func(key string) (*genai.GenerateContentResponse, error) {
+		chat, err := g.clients[key].Chats.Create(
+			ctx,
+			model.UpstreamName,
+			config,
+			geminiMessages,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return chat.SendMessage(ctx, currentMessage...)
+	})
 
 	duration := time.Since(start).Seconds()
-
-	res, err := chat.SendMessage(ctx, genai.Part{Text: currentMessage})
+	status := "success"
 
 	if err != nil {
 		status = "error"
@@ -142,54 +170,75 @@ func (g *GeminiProvider) Complete(ctx context.Context, input *types.CompletionIn
 		}
 	}
 
-	response = g.convertToRouterMessage(res.Candidates[0].Content.Parts[0].Text)
+	response = g.convertToRouterMessage(res.Candidates[0].Content)
+
+	finishReason := geminiFinishReason(res.Candidates[0].FinishReason)
+	if len(response.ToolCalls) > 0 && finishReason == types.FinishReasonStop {
+		// Gemini reports STOP after function calls
+		finishReason = types.FinishReasonToolCalls
+	}
+
 	return &types.CompletionResponse{
-		Message: *response,
-		Usage:   *usage,
-		CostUSD: costUSD,
+		Message:      *response,
+		Usage:        *usage,
+		CostUSD:      costUSD,
+		Model:        standardModelID,
+		FinishReason: finishReason,
 	}, nil
 }
 
 func (g *GeminiProvider) CompleteStream(ctx context.Context, input *types.StreamCompletionInput) (<-chan *types.StreamChunk, error) {
 	ctx, cancel := context.WithTimeout(ctx, g.timeout)
 
-	modelToUse := g.model
-	standardModelID := g.standardModelID
-	if input.Model != "" {
-
-		sdkModel, err := MapToGeminiModel(input.Model)
+	model, err := requestModel(ProviderGemini, g.standardModelID, input.Model)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid gemini model: %w", err)
+	}
+	standardModelID := model.ID
+
+	system, conversation := splitSystemPrompt(input.Messages)
+	geminiMessages, currentMessage := g.convertMessages(conversation)
+
+	config := g.buildConfig(model, system, input.Params)
+	g.applyTools(config, input.Tools, input.ToolChoice)
+	g.applyResponseFormat(config, input.ResponseFormat)
+
+	// Gemini streams are lazy; the first response is read here so a rejected key can be swapped
+	stream, err := withAPIKey(g.keys, ProviderGemini, providererrors.TranslateGeminiError, func(key string) (*geminiStream, error) {
+		chat, err := g.clients[key].Chats.Create(
+			ctx,
+			model.UpstreamName,
+			config,
+			geminiMessages,
+		)
 		if err != nil {
-			defer cancel()
-			return nil, fmt.Errorf("invalid gemini model: %w", err)
+			return nil, err
 		}
-		modelToUse = sdkModel
-		standardModelID = input.Model
-	}
-
-	geminiMessages, currentMessage := g.convertMessages(input.Messages)
-
-	chat, err := g.client.Chats.Create(
-		ctx,
-		modelToUse,
-		nil,
-		geminiMessages,
-	)
+		return startGeminiStream(chat.SendMessageStream(ctx, currentMessage...))
+	})
 
 	if err != nil {
 		defer cancel()
-		return nil, err
+		return nil, providererrors.TranslateGeminiError(err)
 	}
 
-	stream := chat.SendMessageStream(ctx, genai.Part{Text: currentMessage})
-
 	chunks := make(chan *types.StreamChunk)
+	state := newStreamState(ctx, chunks, standardModelID, input.Messages)
 
 	go func() {
 		defer cancel()
 		defer close(chunks)
 
 		var finalUsage types.Usage
-		for chunk, err := range stream {
+		var finishReason genai.FinishReason
+		toolIndex := 0
+	events:
+		for chunk, err := range stream.all() {
+			if err != nil && ctx.Err() != nil {
+				break
+			}
+
 			if err != nil {
 				logger.Error("Streaming error occurred", zap.Error(err))
 				providerErr := providererrors.TranslateGeminiError(err)
@@ -208,58 +257,326 @@ func (g *GeminiProvider) CompleteStream(ctx context.Context, input *types.Stream
 				finalUsage.TotalTokens = finalUsage.PromptTokens + finalUsage.CompletionTokens
 			}
 
+			if len(chunk.Candidates) > 0 && chunk.Candidates[0].FinishReason != "" {
+				finishReason = chunk.Candidates[0].FinishReason
+			}
+
 			if len(chunk.Candidates) == 0 ||
+				chunk.Candidates[0].Content == nil ||
 				len(chunk.Candidates[0].Content.Parts) == 0 {
 				continue
 			}
 
-			part := chunk.Candidates[0].Content.Parts[0]
+			for _, part := range chunk.Candidates[0].Content.Parts {
+				if part.FunctionCall != nil {
+					// Gemini sends each function call whole, so it is forwarded as a single fragment
+					call := geminiToolCall(part.FunctionCall)
+					if !state.sendToolCalls([]types.ToolCallDelta{{
+						Index: toolIndex,
+						ID:    call.ID,
+						Type:  call.Type,
+						Function: types.FunctionCallDelta{
+							Name:      call.Function.Name,
+							Arguments: call.Function.Arguments,
+						},
+					}}) {
+						break events
+					}
+					toolIndex++
+					continue
+				}
 
-			chunks <- &types.StreamChunk{
-				Content: part.Text,
-				Done:    false,
+				if part.Thought || part.Text == "" {
+					continue
+				}
+				if !state.sendContent(part.Text) {
+					break events
+				}
 			}
 		}
 
+		if ctx.Err() != nil {
+			logger.Warn("Stream cancelled before completion", zap.Error(ctx.Err()))
+			state.finishCancelled(finalUsage, providererrors.TranslateGeminiError(ctx.Err()))
+			return
+		}
+
+		reason := geminiFinishReason(finishReason)
+		if toolIndex > 0 && reason == types.FinishReasonStop {
+			reason = types.FinishReasonToolCalls
+		}
+
 		cost, _ := CalculateCost(standardModelID, finalUsage.PromptTokens, finalUsage.CompletionTokens)
 		chunks <- &types.StreamChunk{
-			Done:    true,
-			Usage:   finalUsage,
-			CostUSD: cost,
+			Done:         true,
+			Usage:        finalUsage,
+			CostUSD:      cost,
+			FinishReason: reason,
 		}
 	}()
 
 	return chunks, nil
 }
 
-func (g *GeminiProvider) convertMessages(messages []types.Message) ([]*genai.Content, string) {
-	var geminiMessages []*genai.Content
-	var lastMessage string
+// buildConfig maps the request onto Gemini's generation config.
+// The merged system prompt becomes the SystemInstruction. Gemini supports every
+// sampling setting the router accepts, with the same ranges.
+// Embed embeds the input with one of the catalog's Gemini embedding models. The API
+// doesn't report usage for embeddings, so prompt tokens are estimated.
+func (g *GeminiProvider) Embed(ctx context.Context, input *types.EmbeddingInput) (*types.EmbeddingResponse, error) {
+	start := time.Now()
+	providerName := g.GetProviderName()
+
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	model, err := ResolveModel(ProviderGemini, input.Model)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gemini embedding model: %w", err)
+	}
+
+	contents := make([]*genai.Content, len(input.Input))
+	inputTokens := 0
+	for i, text := range input.Input {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+		inputTokens += estimateTokens(text)
+	}
+
+	config := &genai.EmbedContentConfig{}
+	if input.Dimensions != nil {
+		dimensions := int32(*input.Dimensions)
+		config.OutputDimensionality = &dimensions
+	}
+
+	res, err := withAPIKey(g.keys, providerName, providererrors.TranslateGeminiError, func(key string) (*genai.EmbedContentResponse, error) {
+		return g.clients[key].Models.EmbedContent(ctx, model.UpstreamName, contents, config)
+	})
+
+	metrics.ProviderRequestDuration.WithLabelValues(providerName).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.ProviderRequestsTotal.WithLabelValues(providerName, "error").Inc()
+		return nil, providererrors.TranslateGeminiError(err)
+	}
+	metrics.ProviderRequestsTotal.WithLabelValues(providerName, "success").Inc()
+
+	embeddings := make([][]float32, len(input.Input))
+	for i, embedding := range res.Embeddings {
+		if i < len(embeddings) && embedding != nil {
+			embeddings[i] = embedding.Values
+		}
+	}
+
+	metrics.ProviderTokensUsed.WithLabelValues(providerName, "input").Add(float64(inputTokens))
+
+	cost, _ := CalculateCost(model.ID, inputTokens, 0)
+	metrics.ProviderCostTotal.WithLabelValues(providerName).Add(cost)
+
+	return &types.EmbeddingResponse{
+		Embeddings: embeddings,
+		Usage:      types.Usage{PromptTokens: inputTokens, TotalTokens: inputTokens},
+		CostUSD:    cost,
+		Model:      model.ID,
+	}, nil
+}
+
+func (g *GeminiProvider) buildConfig(model ModelInfo, system string, params types.SamplingParams) *genai.GenerateContentConfig {
+	config := &genai.GenerateContentConfig{
+		MaxOutputTokens:  int32(resolveMaxTokens(params, model.MaxTokensOr(g.maxTokens))),
+		Temperature:      float32Ptr(params.Temperature),
+		TopP:             float32Ptr(params.TopP),
+		FrequencyPenalty: float32Ptr(params.FrequencyPenalty),
+		PresencePenalty:  float32Ptr(params.PresencePenalty),
+	}
 
-	for i, message := range messages {
-		var toAppend *genai.Content
+	if system != "" {
+		config.SystemInstruction = genai.NewContentFromText(system, genai.RoleUser)
+	}
+
+	if len(model.Params) > 0 {
+		config.HTTPOptions = &genai.HTTPOptions{ExtraBody: model.Params}
+	}
 
-		if i == len(messages)-1 {
-			lastMessage = message.Content
-			break
+	return config
+}
+
+// applyTools adds the request's tools as Gemini function declarations. Both "required" and a
+// named function use Gemini's ANY mode; the latter restricts it to that one function.
+func (g *GeminiProvider) applyTools(config *genai.GenerateContentConfig, tools []types.Tool, choice *types.ToolChoice) {
+	if len(tools) > 0 {
+		declarations := make([]*genai.FunctionDeclaration, len(tools))
+		for i, tool := range tools {
+			declarations[i] = &genai.FunctionDeclaration{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+			}
+			if tool.Function.Parameters != nil {
+				declarations[i].ParametersJsonSchema = tool.Function.Parameters
+			}
 		}
+		config.Tools = []*genai.Tool{{FunctionDeclarations: declarations}}
+	}
 
-		if message.Role == "user" || message.Role == "system" {
-			toAppend = genai.NewContentFromText(message.Content, genai.RoleUser)
-		} else {
-			toAppend = genai.NewContentFromText(message.Content, genai.RoleModel)
+	if choice == nil {
+		return
+	}
+
+	callingConfig := &genai.FunctionCallingConfig{Mode: genai.FunctionCallingConfigModeAuto}
+	switch choice.Mode {
+	case types.ToolChoiceNone:
+		callingConfig.Mode = genai.FunctionCallingConfigModeNone
+	case types.ToolChoiceRequired:
+		callingConfig.Mode = genai.FunctionCallingConfigModeAny
+	case types.ToolChoiceFunction:
+		callingConfig.Mode = genai.FunctionCallingConfigModeAny
+		callingConfig.AllowedFunctionNames = []string{choice.Function}
+	}
+	config.ToolConfig = &genai.ToolConfig{FunctionCallingConfig: callingConfig}
+}
+
+// applyResponseFormat asks Gemini for a JSON response. A json_schema format is passed as the
+// response's JSON schema, which Gemini accepts as-is in place of its own ResponseSchema type.
+func (g *GeminiProvider) applyResponseFormat(config *genai.GenerateContentConfig, format *types.ResponseFormat) {
+	if !format.IsJSON() {
+		return
+	}
+
+	config.ResponseMIMEType = "application/json"
+	if format.Type == types.ResponseFormatJSONSchema && format.JSONSchema.Schema != nil {
+		config.ResponseJsonSchema = format.JSONSchema.Schema
+	}
+}
+
+// convertMessages returns the chat history and the parts of the final turn, which is sent as the new message.
+// System messages must already have been removed with splitSystemPrompt.
+// Tool calls become function call parts and tool results become function response parts; Gemini
+// identifies results by function name, which is looked up from the call they answer.
+func (g *GeminiProvider) convertMessages(messages []types.Message) ([]*genai.Content, []genai.Part) {
+	var contents []*genai.Content
+	toolNames := make(map[string]string)
+	lastWasToolResult := false
+
+	for _, message := range messages {
+		switch message.Role {
+		case "user":
+			if len(message.Parts) > 0 {
+				contents = append(contents, genai.NewContentFromParts(geminiContentParts(message.Parts), genai.RoleUser))
+			} else {
+				contents = append(contents, genai.NewContentFromText(message.Content, genai.RoleUser))
+			}
+
+		case "tool":
+			part := &genai.Part{FunctionResponse: &genai.FunctionResponse{
+				ID:       message.ToolCallID,
+				Name:     toolNames[message.ToolCallID],
+				Response: map[string]any{"output": message.Content},
+			}}
+			if lastWasToolResult {
+				last := contents[len(contents)-1]
+				last.Parts = append(last.Parts, part)
+			} else {
+				contents = append(contents, genai.NewContentFromParts([]*genai.Part{part}, genai.RoleUser))
+			}
+
+		default:
+			var parts []*genai.Part
+			if message.Content != "" {
+				parts = append(parts, genai.NewPartFromText(message.Content))
+			}
+			for _, call := range message.ToolCalls {
+				toolNames[call.ID] = call.Function.Name
+				parts = append(parts, &genai.Part{FunctionCall: &genai.FunctionCall{
+					ID:   call.ID,
+					Name: call.Function.Name,
+					Args: parseToolArguments(call.Function.Arguments),
+				}})
+			}
+			contents = append(contents, genai.NewContentFromParts(parts, genai.RoleModel))
 		}
 
-		geminiMessages = append(geminiMessages, toAppend)
+		lastWasToolResult = message.Role == "tool"
+	}
+
+	if len(contents) == 0 {
+		return nil, nil
+	}
+
+	last := contents[len(contents)-1]
+	current := make([]genai.Part, len(last.Parts))
+	for i, part := range last.Parts {
+		current[i] = *part
+	}
+
+	return contents[:len(contents)-1], current
+}
+
+// geminiContentParts maps content parts onto Gemini parts. Inline media becomes InlineData and
+// image URLs become FileData, with the media type guessed from the URL.
+func geminiContentParts(parts []types.ContentPart) []*genai.Part {
+	converted := make([]*genai.Part, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case types.ContentPartImageURL:
+			if dataURL, err := types.ParseDataURL(part.ImageURL.URL); err == nil {
+				converted = append(converted, geminiInlinePart(dataURL.Data, dataURL.MediaType))
+			} else {
+				converted = append(converted, genai.NewPartFromURI(part.ImageURL.URL, imageMediaType(part.ImageURL.URL)))
+			}
+
+		case types.ContentPartInputAudio:
+			converted = append(converted, geminiInlinePart(part.InputAudio.Data, "audio/"+part.InputAudio.Format))
+
+		case types.ContentPartFile:
+			dataURL, _ := types.ParseDataURL(part.File.FileData)
+			converted = append(converted, geminiInlinePart(dataURL.Data, dataURL.MediaType))
+
+		default:
+			converted = append(converted, genai.NewPartFromText(part.Text))
+		}
+	}
+	return converted
+}
+
+// geminiInlinePart decodes base64 media, which was validated when the request was bound.
+func geminiInlinePart(data string, mediaType string) *genai.Part {
+	decoded, _ := base64.StdEncoding.DecodeString(data)
+	return genai.NewPartFromBytes(decoded, mediaType)
+}
+
+func (g *GeminiProvider) convertToRouterMessage(content *genai.Content) *types.Message {
+	message := &types.Message{Role: "assistant"}
+	if content == nil {
+		return message
+	}
+
+	var text strings.Builder
+	for _, part := range content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			message.ToolCalls = append(message.ToolCalls, geminiToolCall(part.FunctionCall))
+		case !part.Thought:
+			text.WriteString(part.Text)
+		}
 	}
+	message.Content = text.String()
 
-	return geminiMessages, lastMessage
+	return message
 }
 
-func (g *GeminiProvider) convertToRouterMessage(text string) *types.Message {
-	return &types.Message{
-		Content: text,
-		Role:    "assistant",
+// geminiToolCall converts a function call part. The Gemini API does not always assign call IDs,
+// so one is generated when missing; clients echo it back in the tool result.
+func geminiToolCall(call *genai.FunctionCall) types.ToolCall {
+	id := call.ID
+	if id == "" {
+		id = newToolCallID()
+	}
+
+	return types.ToolCall{
+		ID:   id,
+		Type: "function",
+		Function: types.FunctionCall{
+			Name:      call.Name,
+			Arguments: encodeToolArguments(call.Args),
+		},
 	}
 }
 
@@ -272,7 +589,7 @@ func (g *GeminiProvider) CountTokens(ctx context.Context, messages []types.Messa
 	totalTokens := 0
 	for _, msg := range messages {
 		tokens := encoding.Encode(msg.Content, nil, nil)
-		totalTokens += len(tokens)
+		totalTokens += len(tokens) + mediaTokens(msg)
 
 		// Add overhead for role and message formatting
 		// Anthropic format: ~4 tokens per message for structure
@@ -286,19 +603,34 @@ func (g *GeminiProvider) GetProviderName() string {
 	return ProviderGemini
 }
 
-func NewGeminiProvider(config GeminiConfig) (*GeminiProvider, error) {
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  config.APIKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+// KeyUsage returns the usage and health of the provider's API keys.
+func (g *GeminiProvider) KeyUsage() []KeyStats {
+	return g.keys.Stats()
+}
 
+func NewGeminiProvider(config GeminiConfig) (*GeminiProvider, error) {
+	keys, err := resolveKeyPool(config.Keys, config.APIKey)
 	if err != nil {
 		return nil, err
 	}
+	if keys == nil {
+		return nil, fmt.Errorf("API key cannot be empty")
+	}
 
-	model, err := MapToGeminiModel(config.Model)
-	if err != nil {
+	ctx := context.Background()
+	clients := make(map[string]*genai.Client)
+	for _, key := range keys.keys {
+		client, err := genai.NewClient(ctx, &genai.ClientConfig{
+			APIKey:  key.value,
+			Backend: genai.BackendGeminiAPI,
+		})
+		if err != nil {
+			return nil, err
+		}
+		clients[key.value] = client
+	}
+
+	if _, err := ResolveModel(ProviderGemini, config.Model); err != nil {
 		return nil, fmt.Errorf("invalid Gemini model: %w", err)
 	}
 
@@ -308,9 +640,9 @@ func NewGeminiProvider(config GeminiConfig) (*GeminiProvider, error) {
 	}
 
 	return &GeminiProvider{
-		client:          client,
+		clients:         clients,
+		keys:            keys,
 		maxTokens:       config.MaxTokens,
-		model:           model,
 		standardModelID: config.Model,
 		timeout:         timeout,
 	}, nil
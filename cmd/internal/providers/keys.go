@@ -0,0 +1,244 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	providererrors "llm-router/cmd/internal/provider_errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Key selection policies for providers configured with several API keys
+const (
+	KeySelectionRoundRobin = "round-robin" // Spread requests evenly across keys
+	KeySelectionLeastUsed  = "least-used"  // Use the key that has served the fewest requests
+	KeySelectionFailover   = "failover"    // Use keys in order, moving on only when one is quarantined
+)
+
+const defaultKeyQuarantine = 60 * time.Second
+
+// KeyStats is the usage and health of one API key, as shown in /admin/status.
+type KeyStats struct {
+	Key              string     `json:"key"` // Masked
+	Requests         int64      `json:"requests"`
+	Failures         int64      `json:"failures"`
+	Quarantined      bool       `json:"quarantined"`
+	QuarantinedUntil *time.Time `json:"quarantined_until,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+}
+
+type apiKey struct {
+	value            string
+	requests         int64
+	failures         int64
+	quarantinedUntil time.Time
+	lastError        string
+}
+
+// KeyPool hands out a provider's API keys. Keys that fail authentication or hit rate and
+// quota limits are quarantined, and requests move on to the next key, so a single exhausted
+// key doesn't count against the provider's circuit breaker.
+type KeyPool struct {
+	mu         sync.Mutex
+	keys       []*apiKey
+	selection  string
+	quarantine time.Duration
+	next       int
+}
+
+func NewKeyPool(keys []string, selection string, quarantine time.Duration) (*KeyPool, error) {
+	if selection == "" {
+		selection = KeySelectionRoundRobin
+	}
+	switch selection {
+	case KeySelectionRoundRobin, KeySelectionLeastUsed, KeySelectionFailover:
+	default:
+		return nil, fmt.Errorf("unknown key selection %q (expected %s, %s or %s)",
+			selection, KeySelectionRoundRobin, KeySelectionLeastUsed, KeySelectionFailover)
+	}
+
+	if quarantine <= 0 {
+		quarantine = defaultKeyQuarantine
+	}
+
+	pool := &KeyPool{selection: selection, quarantine: quarantine}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		pool.keys = append(pool.keys, &apiKey{value: key})
+	}
+
+	if len(pool.keys) == 0 {
+		return nil, fmt.Errorf("at least one API key is required")
+	}
+
+	return pool, nil
+}
+
+// acquire returns the key to use for the next request.
+func (p *KeyPool) acquire() (*apiKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var chosen *apiKey
+
+	switch p.selection {
+	case KeySelectionLeastUsed:
+		for _, key := range p.keys {
+			if key.available(now) && (chosen == nil || key.requests < chosen.requests) {
+				chosen = key
+			}
+		}
+
+	case KeySelectionFailover:
+		for _, key := range p.keys {
+			if key.available(now) {
+				chosen = key
+				break
+			}
+		}
+
+	default:
+		for i := range p.keys {
+			key := p.keys[(p.next+i)%len(p.keys)]
+			if key.available(now) {
+				chosen = key
+				p.next = (p.next + i + 1) % len(p.keys)
+				break
+			}
+		}
+	}
+
+	if chosen == nil {
+		return nil, errors.New("all API keys are quarantined")
+	}
+
+	chosen.requests++
+	return chosen, nil
+}
+
+// report records the outcome of a request made with key, quarantining the key when the
+// provider rejected it. It returns whether the key was quarantined.
+func (p *KeyPool) report(key *apiKey, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key.failures++
+	key.lastError = err.Error()
+
+	var providerErr *providererrors.ProviderError
+	if !errors.As(err, &providerErr) {
+		return false
+	}
+
+	switch providerErr.Type {
+	case providererrors.ErrorTypeAuthentication, providererrors.ErrorTypeRateLimit, providererrors.ErrorTypeQuotaExceeded:
+	default:
+		return false
+	}
+
+	// With nothing to switch to, a lone key is left to the circuit breaker
+	if len(p.keys) == 1 {
+		return false
+	}
+
+	quarantine := p.quarantine
+	if retryAfter := time.Duration(providerErr.RetryAfter) * time.Second; retryAfter > quarantine {
+		quarantine = retryAfter
+	}
+	key.quarantinedUntil = time.Now().Add(quarantine)
+
+	return true
+}
+
+// Stats returns the usage and health of each key, in configured order.
+func (p *KeyPool) Stats() []KeyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]KeyStats, len(p.keys))
+	for i, key := range p.keys {
+		stats[i] = KeyStats{
+			Key:       maskKey(key.value),
+			Requests:  key.requests,
+			Failures:  key.failures,
+			LastError: key.lastError,
+		}
+		if !key.available(now) {
+			until := key.quarantinedUntil
+			stats[i].Quarantined = true
+			stats[i].QuarantinedUntil = &until
+		}
+	}
+	return stats
+}
+
+// resolveKeyPool returns the configured pool, or a pool of the single API key. Without either, nil.
+func resolveKeyPool(pool *KeyPool, apiKey string) (*KeyPool, error) {
+	if pool != nil || apiKey == "" {
+		return pool, nil
+	}
+	return NewKeyPool([]string{apiKey}, KeySelectionRoundRobin, 0)
+}
+
+func (k *apiKey) available(now time.Time) bool {
+	return !now.Before(k.quarantinedUntil)
+}
+
+// maskKey keeps just enough of a key to tell it apart from the others.
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:3] + "****" + key[len(key)-4:]
+}
+
+// withAPIKey runs call with a key from the pool. When the provider rejects the key, it is
+// quarantined and the call is repeated with the next key; other errors are returned as they are.
+// translate classifies the call's errors. A nil pool runs call once without a key.
+func withAPIKey[T any](pool *KeyPool, providerName string, translate func(error) error, call func(key string) (T, error)) (T, error) {
+	if pool == nil {
+		return call("")
+	}
+
+	var result T
+	var lastErr error
+
+	for {
+		key, err := pool.acquire()
+		if err != nil {
+			if lastErr != nil {
+				return result, lastErr
+			}
+			return result, &providererrors.ProviderError{
+				Type:          providererrors.ErrorTypeUnavailable,
+				ProviderName:  providerName,
+				Message:       err.Error(),
+				OriginalError: err,
+				Retryable:     false,
+			}
+		}
+
+		result, lastErr = call(key.value)
+		if !pool.report(key, translate(lastErr)) {
+			return result, lastErr
+		}
+
+		logger.Warn("API key quarantined, trying the next key",
+			zap.String("provider", providerName),
+			zap.String("key", maskKey(key.value)),
+			zap.Error(lastErr),
+		)
+	}
+}
@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	providererrors "llm-router/cmd/internal/provider_errors"
+	"llm-router/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestKeyPool_Selection(t *testing.T) {
+	keys := []string{"key-aaaaaaaa", "key-bbbbbbbb", "key-cccccccc"}
+
+	tests := []struct {
+		name      string
+		selection string
+		want      []string
+	}{
+		{name: "round-robin", selection: KeySelectionRoundRobin, want: []string{"key-aaaaaaaa", "key-bbbbbbbb", "key-cccccccc", "key-aaaaaaaa"}},
+		{name: "failover", selection: KeySelectionFailover, want: []string{"key-aaaaaaaa", "key-aaaaaaaa", "key-aaaaaaaa", "key-aaaaaaaa"}},
+		{name: "least-used", selection: KeySelectionLeastUsed, want: []string{"key-aaaaaaaa", "key-bbbbbbbb", "key-cccccccc", "key-aaaaaaaa"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := NewKeyPool(keys, tt.selection, time.Minute)
+			if err != nil {
+				t.Fatalf("NewKeyPool() error = %v", err)
+			}
+
+			for i, want := range tt.want {
+				key, err := pool.acquire()
+				if err != nil {
+					t.Fatalf("acquire() error = %v", err)
+				}
+				if key.value != want {
+					t.Errorf("request %d used %s, want %s", i+1, key.value, want)
+				}
+			}
+		})
+	}
+
+	if _, err := NewKeyPool(keys, "random", 0); err == nil {
+		t.Error("expected an error for an unknown selection policy")
+	}
+}
+
+func TestKeyPool_Quarantine(t *testing.T) {
+	pool, err := NewKeyPool([]string{"key-aaaaaaaa", "key-bbbbbbbb"}, KeySelectionFailover, time.Minute)
+	if err != nil {
+		t.Fatalf("NewKeyPool() error = %v", err)
+	}
+
+	first, _ := pool.acquire()
+	if pool.report(first, providererrors.NewServerError("openai", 500, errors.New("boom"))) {
+		t.Error("a server error must not quarantine the key")
+	}
+	if !pool.report(first, providererrors.NewRateLimitError("openai", 429, 0, errors.New("slow down"))) {
+		t.Fatal("a rate limit error should quarantine the key")
+	}
+
+	next, _ := pool.acquire()
+	if next.value != "key-bbbbbbbb" {
+		t.Errorf("acquire() = %s, want the next key while the first is quarantined", next.value)
+	}
+
+	pool.report(next, providererrors.NewAuthenticationError("openai", errors.New("bad key")))
+	if _, err := pool.acquire(); err == nil {
+		t.Error("expected an error once every key is quarantined")
+	}
+
+	stats := pool.Stats()
+	if len(stats) != 2 || !stats[0].Quarantined || stats[0].Failures != 2 || stats[0].Requests != 1 {
+		t.Errorf("Stats()[0] = %+v, want 1 request, 2 failures and quarantined", stats[0])
+	}
+	if stats[0].Key == "key-aaaaaaaa" {
+		t.Error("Stats() must not expose the full key")
+	}
+}
+
+func TestOpenAIProvider_RotatesRejectedKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer sk-exhausted" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error": {"message": "quota exceeded", "type": "insufficient_quota"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"created": 1,
+			"model": "gpt-4o-mini",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hello"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 5, "completion_tokens": 1, "total_tokens": 6}
+		}`)
+	}))
+	defer server.Close()
+
+	keys, err := NewKeyPool([]string{"sk-exhausted", "sk-healthy"}, KeySelectionFailover, time.Minute)
+	if err != nil {
+		t.Fatalf("NewKeyPool() error = %v", err)
+	}
+
+	provider, err := NewOpenAIProvider(OpenAIConfig{
+		Keys:      keys,
+		BaseURL:   server.URL,
+		MaxTokens: 256,
+		Model:     "openai/gpt-4o-mini",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAIProvider() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		response, err := provider.Complete(context.Background(), &types.CompletionInput{
+			Messages: []types.Message{{Role: "user", Content: "Hi"}},
+		})
+		if err != nil {
+			t.Fatalf("Complete() error = %v, want the healthy key to answer", err)
+		}
+		if response.Message.Content != "Hello" {
+			t.Errorf("content = %q, want Hello", response.Message.Content)
+		}
+	}
+
+	stats := provider.KeyUsage()
+	if !stats[0].Quarantined || stats[0].Requests != 1 {
+		t.Errorf("exhausted key = %+v, want quarantined after its only request", stats[0])
+	}
+	if stats[1].Quarantined || stats[1].Requests != 2 {
+		t.Errorf("healthy key = %+v, want both requests", stats[1])
+	}
+}
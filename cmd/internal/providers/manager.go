@@ -74,6 +74,40 @@ func (pm *ProviderManager) GetProvider(name string) (types.Provider, error) {
 	return nil, fmt.Errorf("provider %s not found", name)
 }
 
+// KeyUsage returns the usage and health of each provider's API keys, by provider name.
+func (pm *ProviderManager) KeyUsage() map[string][]KeyStats {
+	usage := make(map[string][]KeyStats)
+
+	for _, provider := range pm.GetProviders() {
+		pooled, ok := unwrapProvider(provider).(interface{ KeyUsage() []KeyStats })
+		if !ok {
+			continue
+		}
+		if stats := pooled.KeyUsage(); len(stats) > 0 {
+			usage[provider.GetProviderName()] = stats
+		}
+	}
+
+	return usage
+}
+
+// unwrapProvider returns the provider beneath any wrappers, such as latency monitoring.
+func unwrapProvider(provider types.Provider) types.Provider {
+	for {
+		wrapper, ok := provider.(interface{ Unwrap() types.Provider })
+		if !ok {
+			return provider
+		}
+		provider = wrapper.Unwrap()
+	}
+}
+
+// AsEmbeddingProvider returns the provider beneath any wrappers if it can embed text.
+func AsEmbeddingProvider(provider types.Provider) (types.EmbeddingProvider, bool) {
+	embedder, ok := unwrapProvider(provider).(types.EmbeddingProvider)
+	return embedder, ok
+}
+
 func (pm *ProviderManager) GetProviderCount() int {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
@@ -1,14 +1,13 @@
 package providers
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 
 	"llm-router/types"
-
-	"github.com/anthropics/anthropic-sdk-go"
-	"github.com/openai/openai-go/v3"
 )
 
 const (
@@ -40,6 +39,12 @@ const (
 	ModelGeminiFlash25Lite = "gemini/gemini-2.5-flash-lite"
 )
 
+const (
+	ModelOpenAIEmbedding3Small = "openai/text-embedding-3-small"
+	ModelOpenAIEmbedding3Large = "openai/text-embedding-3-large"
+	ModelGeminiEmbedding       = "gemini/gemini-embedding-001"
+)
+
 type ModelTier string
 
 const (
@@ -58,6 +63,11 @@ type ModelInfo struct {
 	ContextWindow   int
 	Tier            ModelTier
 	Capabilities    []string
+	UpstreamName    string         // Model name in the provider's API
+	MaxTokens       int64          // Default max output tokens; 0 uses the provider default
+	Params          map[string]any // Extra request body fields
+	Type            string         // types.ModelTypeChat or types.ModelTypeEmbedding
+	Dimensions      int            // Output vector size of an embedding model; 0 if unknown
 }
 
 var (
@@ -76,11 +86,35 @@ func InitializeModelRegistry(defaults []types.ModelConfig, overrides []types.Mod
 	}
 
 	for _, cfg := range overrides {
+		// Overriding a built-in model's pricing shouldn't require repeating its upstream name or type
+		if existing, ok := modelRegistry[cfg.ID]; ok {
+			if cfg.UpstreamName == "" {
+				cfg.UpstreamName = existing.UpstreamName
+			}
+			if cfg.Type == "" {
+				cfg.Type = existing.Type
+			}
+			if cfg.Dimensions == 0 {
+				cfg.Dimensions = existing.Dimensions
+			}
+		}
 		addToRegistry(cfg)
 	}
 }
 
 func addToRegistry(cfg types.ModelConfig) {
+	upstreamName := cfg.UpstreamName
+	if upstreamName == "" {
+		if _, name, err := ParseModelID(cfg.ID); err == nil {
+			upstreamName = name
+		}
+	}
+
+	modelType := cfg.Type
+	if modelType == "" {
+		modelType = types.ModelTypeChat
+	}
+
 	info := ModelInfo{
 		ID:              cfg.ID,
 		Provider:        cfg.Provider,
@@ -90,6 +124,11 @@ func addToRegistry(cfg types.ModelConfig) {
 		ContextWindow:   cfg.ContextWindow,
 		Tier:            ModelTier(cfg.Tier),
 		Capabilities:    cfg.Capabilities,
+		UpstreamName:    upstreamName,
+		MaxTokens:       cfg.MaxTokens,
+		Params:          cfg.Params,
+		Type:            modelType,
+		Dimensions:      cfg.Dimensions,
 	}
 	modelRegistry[cfg.ID] = info
 }
@@ -113,53 +152,36 @@ func GetModelInfo(modelID string) (ModelInfo, error) {
 	return info, nil
 }
 
-func MapToOpenAIModel(modelID string) (string, error) {
-	switch modelID {
-	case ModelOpenAIGPT5:
-		return openai.ChatModelGPT5_2025_08_07, nil
-	case ModelOpenAIGPT51:
-		return openai.ChatModelGPT5_1ChatLatest, nil
-	case ModelOpenAIGPT4o:
-		return openai.ChatModelChatgpt4oLatest, nil
-	case ModelOpenAIGPT4oMini:
-		return openai.ChatModelGPT4oMini, nil
-	case ModelOpenAIGPT35Turbo:
-		return openai.ChatModelGPT3_5Turbo, nil
-	default:
-		return "", fmt.Errorf("unknown OpenAI model: %s", modelID)
+// ResolveModel looks up a model for a provider about to call it. Catalog entries carry the
+// upstream model name, so models added in config can be called without code changes.
+func ResolveModel(providerName string, modelID string) (ModelInfo, error) {
+	info, err := GetModelInfo(modelID)
+	if err != nil {
+		return ModelInfo{}, err
+	}
+	if info.Provider != providerName {
+		return ModelInfo{}, fmt.Errorf("model %s belongs to provider %s, not %s", modelID, info.Provider, providerName)
 	}
+	if info.UpstreamName == "" {
+		return ModelInfo{}, fmt.Errorf("model %s has no upstream name", modelID)
+	}
+	return info, nil
 }
 
-func MapToAnthropicModel(modelID string) (anthropic.Model, error) {
-	switch modelID {
-	case ModelAnthropicOpus45:
-		return anthropic.ModelClaudeOpus4_5_20251101, nil
-	case ModelAnthropicSonnet4:
-		return anthropic.ModelClaude4Sonnet20250514, nil
-	case ModelAnthropicHaiku45:
-		return anthropic.ModelClaudeHaiku4_5_20251001, nil
-	case ModelAnthropicHaiku3:
-		return anthropic.ModelClaude_3_Haiku_20240307, nil
-	default:
-		return "", fmt.Errorf("unknown Anthropic model: %s", modelID)
+// requestModel resolves the model a request names, or the provider's default model when it names none.
+func requestModel(providerName string, defaultModelID string, requested string) (ModelInfo, error) {
+	if requested != "" {
+		return ResolveModel(providerName, requested)
 	}
+	return ResolveModel(providerName, defaultModelID)
 }
 
-func MapToGeminiModel(modelID string) (string, error) {
-	switch modelID {
-	case ModelGeminiPro3:
-		return "gemini-3-pro-preview", nil
-	case ModelGeminiFlash3:
-		return "gemini-3-flash-preview", nil
-	case ModelGeminiPro25:
-		return "gemini-2.5-pro", nil
-	case ModelGeminiFlash25:
-		return "gemini-2.5-flash", nil
-	case ModelGeminiFlash25Lite:
-		return "gemini-2.5-flash-lite", nil
-	default:
-		return "", fmt.Errorf("unknown Gemini model: %s", modelID)
+// MaxTokensOr returns the model's default max output tokens, or fallback when it has none.
+func (m ModelInfo) MaxTokensOr(fallback int64) int64 {
+	if m.MaxTokens > 0 {
+		return m.MaxTokens
 	}
+	return fallback
 }
 
 func CalculateCost(modelID string, inputTokens, outputTokens int) (float64, error) {
@@ -174,13 +196,92 @@ func CalculateCost(modelID string, inputTokens, outputTokens int) (float64, erro
 	return inputCost + outputCost, nil
 }
 
+// IsEmbedding reports whether the model serves embeddings rather than chat completions.
+// Embedding models are left out of every chat model listing.
+func (m ModelInfo) IsEmbedding() bool {
+	return m.Type == types.ModelTypeEmbedding
+}
+
+// ListEmbeddingModels returns the provider's embedding models.
+func ListEmbeddingModels(providerName string) []ModelInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var models []ModelInfo
+	for _, model := range modelRegistry {
+		if model.Provider == providerName && model.IsEmbedding() {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// FilterAllowedModels returns the models whose IDs are in allowed. An empty allowed list keeps every model.
+func FilterAllowedModels(models []ModelInfo, allowed []string) []ModelInfo {
+	if len(allowed) == 0 {
+		return models
+	}
+
+	var filtered []ModelInfo
+	for _, model := range models {
+		if slices.Contains(allowed, model.ID) {
+			filtered = append(filtered, model)
+		}
+	}
+	return filtered
+}
+
+// ListModels returns every model in the registry, chat and embedding alike.
+func ListModels() []ModelInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	models := make([]ModelInfo, 0, len(modelRegistry))
+	for _, model := range modelRegistry {
+		models = append(models, model)
+	}
+	return models
+}
+
+// FilterModelsByContext returns the models whose context window fits the request, with its prompt
+// counted by provider's tokenizer. Models with an unknown context window are kept.
+func FilterModelsByContext(ctx context.Context, models []ModelInfo, provider types.Provider, tokens *types.RequestTokens) []ModelInfo {
+	if tokens == nil {
+		return models
+	}
+
+	var filtered []ModelInfo
+	for _, model := range models {
+		if tokens.Fits(ctx, provider, model.ContextWindow) {
+			filtered = append(filtered, model)
+		}
+	}
+	return filtered
+}
+
+// FilterModelsByDimensions returns the embedding models that can produce vectors of the given size.
+// Models without a known size are kept; a zero size keeps every model.
+func FilterModelsByDimensions(models []ModelInfo, dimensions int) []ModelInfo {
+	if dimensions <= 0 {
+		return models
+	}
+
+	var filtered []ModelInfo
+	for _, model := range models {
+		if model.Dimensions == 0 || model.Dimensions >= dimensions {
+			filtered = append(filtered, model)
+		}
+	}
+	return filtered
+}
+
 func ListModelsByProvider(providerName string) []ModelInfo {
 	registryMu.RLock()
 	defer registryMu.RUnlock()
 
 	var models []ModelInfo
 	for _, model := range modelRegistry {
-		if model.Provider == providerName {
+		if model.Provider == providerName && !model.IsEmbedding() {
 			models = append(models, model)
 		}
 	}
@@ -206,7 +307,7 @@ func ListModelsByTier(tier ModelTier) []ModelInfo {
 
 	var models []ModelInfo
 	for _, model := range modelRegistry {
-		if model.Tier == tier {
+		if model.Tier == tier && !model.IsEmbedding() {
 			models = append(models, model)
 		}
 	}
@@ -219,7 +320,7 @@ func ListModelsByProviderAndTier(providerName string, tier ModelTier) []ModelInf
 
 	var models []ModelInfo
 	for _, model := range modelRegistry {
-		if model.Provider == providerName && model.Tier == tier {
+		if model.Provider == providerName && model.Tier == tier && !model.IsEmbedding() {
 			models = append(models, model)
 		}
 	}
@@ -245,12 +346,47 @@ func FindCheapestModel(models []ModelInfo) (ModelInfo, error) {
 	return cheapest, nil
 }
 
+// HasCapabilities reports whether the model offers every one of the required capabilities.
+func (m ModelInfo) HasCapabilities(required []string) bool {
+	for _, capability := range required {
+		found := false
+		for _, cap := range m.Capabilities {
+			if strings.EqualFold(cap, capability) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterModelsByCapabilities returns the models that offer every required capability.
+func FilterModelsByCapabilities(models []ModelInfo, required []string) []ModelInfo {
+	if len(required) == 0 {
+		return models
+	}
+
+	var filtered []ModelInfo
+	for _, model := range models {
+		if model.HasCapabilities(required) {
+			filtered = append(filtered, model)
+		}
+	}
+	return filtered
+}
+
 func ListProvidersByCapability(capability string) []string {
 	registryMu.RLock()
 	defer registryMu.RUnlock()
 
 	providerSet := make(map[string]struct{})
 	for _, model := range modelRegistry {
+		if model.IsEmbedding() {
+			continue
+		}
 		for _, cap := range model.Capabilities {
 			if strings.EqualFold(cap, capability) {
 				providerSet[model.Provider] = struct{}{}
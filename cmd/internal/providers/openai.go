@@ -11,23 +11,32 @@ import (
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/packages/ssestream"
+	"github.com/openai/openai-go/v3/shared"
 	"github.com/pkoukk/tiktoken-go"
 	"go.uber.org/zap"
 )
 
 type OpenAIConfig struct {
 	APIKey    string
+	Keys      *KeyPool // Optional; several keys to use instead of APIKey
+	BaseURL   string   // Optional, e.g. an enterprise gateway in front of api.openai.com
 	MaxTokens int64
 	Model     string
 	Timeout   time.Duration
 }
 
 type OpenAIProvider struct {
+	name            string
 	maxTokens       int64
 	client          openai.Client
-	model           string // SDK model string
-	standardModelID string // Standardized model ID for cost calculation
+	standardModelID string // Default model's catalog ID
 	timeout         time.Duration
+	// modelOptions returns extra client options for calls to a model, e.g. its Azure deployment URL
+	modelOptions func(model ModelInfo) []option.RequestOption
+	keys         *KeyPool
+	// keyOption authenticates a request with one of the pool's keys
+	keyOption func(key string) option.RequestOption
 }
 
 func (o *OpenAIProvider) Complete(ctx context.Context, input *types.CompletionInput) (*types.CompletionResponse, error) {
@@ -37,23 +46,20 @@ func (o *OpenAIProvider) Complete(ctx context.Context, input *types.CompletionIn
 	ctx, cancel := context.WithTimeout(ctx, o.timeout)
 	defer cancel()
 
-	modelToUse := o.model
-	standardModelID := o.standardModelID
-	if input.Model != "" {
-		sdkModel, err := MapToOpenAIModel(input.Model)
-		if err != nil {
-			return nil, fmt.Errorf("invalid openai model: %w", err)
-		}
-		modelToUse = sdkModel
-		standardModelID = input.Model
+	model, err := requestModel(o.name, o.standardModelID, input.Model)
+	if err != nil {
+		return nil, fmt.Errorf("invalid openai model: %w", err)
 	}
+	standardModelID := model.ID
 
 	openAIMessages := o.convertMessages(input.Messages)
 
-	chatCompletion, err := o.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages:            openAIMessages,
-		Model:               openai.ChatModel(modelToUse),
-		MaxCompletionTokens: openai.Opt(o.maxTokens),
+	params := o.buildParams(model, openAIMessages, input.Params)
+	o.applyTools(&params, input.Tools, input.ToolChoice)
+	o.applyResponseFormat(&params, input.ResponseFormat)
+
+	chatCompletion, err := withAPIKey(o.keys, providerName, o.translateError, func(key string) (*openai.ChatCompletion, error) {
+		return o.client.Chat.Completions.New(ctx, params, o.requestOptions(model, key)...)
 	})
 
 	duration := time.Since(start).Seconds()
@@ -61,11 +67,12 @@ func (o *OpenAIProvider) Complete(ctx context.Context, input *types.CompletionIn
 
 	if err != nil {
 		status = "error"
-		translatedErr := providererrors.TranslateOpenAIError(err)
+		translatedErr := o.translateError(err)
 
 		var providerErr *providererrors.ProviderError
 		if errors.As(translatedErr, &providerErr) {
 			logger.Error("OpenAI request failed",
+				zap.String("provider", providerName),
 				zap.String("error_type", providerErr.Type.String()),
 				zap.Int("status_code", providerErr.StatusCode),
 				zap.Bool("retryable", providerErr.Retryable),
@@ -114,42 +121,48 @@ func (o *OpenAIProvider) Complete(ctx context.Context, input *types.CompletionIn
 			CompletionTokens: outputTokens,
 			TotalTokens:      inputTokens + outputTokens,
 		},
-		CostUSD: cost,
+		CostUSD:      cost,
+		Model:        standardModelID,
+		FinishReason: openAIFinishReason(chatCompletion.Choices[0].FinishReason),
 	}, nil
 }
 
 func (o *OpenAIProvider) CompleteStream(ctx context.Context, input *types.StreamCompletionInput) (<-chan *types.StreamChunk, error) {
 	ctx, cancel := context.WithTimeout(ctx, o.timeout)
 
-	modelToUse := o.model
-	standardModelID := o.standardModelID
-	if input.Model != "" {
-		sdkModel, err := MapToOpenAIModel(input.Model)
-		if err != nil {
-			defer cancel()
-			return nil, fmt.Errorf("invalid openai model: %w", err)
-		}
-		modelToUse = sdkModel
-		standardModelID = input.Model
+	model, err := requestModel(o.name, o.standardModelID, input.Model)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid openai model: %w", err)
 	}
-
-	fmt.Printf("model to use %v \n", modelToUse)
+	standardModelID := model.ID
 
 	openAIMessages := o.convertMessages(input.Messages)
 
-	stream := o.client.Chat.Completions.NewStreaming(ctx, openai.ChatCompletionNewParams{
-		Messages:            openAIMessages,
-		Model:               modelToUse,
-		MaxCompletionTokens: openai.Opt(o.maxTokens),
+	params := o.buildParams(model, openAIMessages, input.Params)
+	o.applyTools(&params, input.Tools, input.ToolChoice)
+	o.applyResponseFormat(&params, input.ResponseFormat)
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+	// The request is sent before the first event is read, so a rejected key shows up here
+	stream, err := withAPIKey(o.keys, o.name, o.translateError, func(key string) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+		stream := o.client.Chat.Completions.NewStreaming(ctx, params, o.requestOptions(model, key)...)
+		return stream, stream.Err()
 	})
+	if err != nil {
+		cancel()
+		return nil, o.translateError(err)
+	}
 
 	acc := openai.ChatCompletionAccumulator{}
 	chunks := make(chan *types.StreamChunk)
+	state := newStreamState(ctx, chunks, standardModelID, input.Messages)
 
 	go func() {
 		defer cancel()
 		defer close(chunks)
 
+		finishReason := ""
 		for stream.Next() {
 			chunk := stream.Current()
 
@@ -157,37 +170,65 @@ func (o *OpenAIProvider) CompleteStream(ctx context.Context, input *types.Stream
 
 			if _, ok := acc.JustFinishedContent(); ok {
 				logger.Debug("Content streaming finished")
-				chunks <- &types.StreamChunk{
-					Content: "",
-					Done:    true,
-				}
+			}
+
+			if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
+				finishReason = chunk.Choices[0].FinishReason
 			}
 
 			if refusal, ok := acc.JustFinishedRefusal(); ok {
 				logger.Warn("Content refused by OpenAI", zap.String("refusal", refusal))
 			}
 
-			// when I integrate tools calls
 			if tool, ok := acc.JustFinishedToolCall(); ok {
 				logger.Debug("Tool call finished",
 					zap.Int("index", tool.Index),
 					zap.String("name", tool.Name),
-					zap.String("arguments", tool.Arguments),
 				)
+			}
 
+			if len(chunk.Choices) > 0 && len(chunk.Choices[0].Delta.ToolCalls) > 0 {
+				deltas := make([]types.ToolCallDelta, len(chunk.Choices[0].Delta.ToolCalls))
+				for i, call := range chunk.Choices[0].Delta.ToolCalls {
+					deltas[i] = types.ToolCallDelta{
+						Index: int(call.Index),
+						ID:    call.ID,
+						Type:  call.Type,
+						Function: types.FunctionCallDelta{
+							Name:      call.Function.Name,
+							Arguments: call.Function.Arguments,
+						},
+					}
+				}
+				if !state.sendToolCalls(deltas) {
+					break
+				}
 			}
 
 			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
-				chunks <- &types.StreamChunk{
-					Content: chunk.Choices[0].Delta.Content,
-					Done:    false,
+				if !state.sendContent(chunk.Choices[0].Delta.Content) {
+					break
 				}
 			}
 		}
 
+		inputTokens := int(acc.Usage.PromptTokens)
+		outputTokens := int(acc.Usage.CompletionTokens)
+		usage := types.Usage{
+			PromptTokens:     inputTokens,
+			CompletionTokens: outputTokens,
+			TotalTokens:      inputTokens + outputTokens,
+		}
+
+		if ctx.Err() != nil {
+			logger.Warn("Stream cancelled before completion", zap.Error(ctx.Err()))
+			state.finishCancelled(usage, o.translateError(ctx.Err()))
+			return
+		}
+
 		if err := stream.Err(); err != nil {
 			logger.Error("Streaming error occurred", zap.Error(err))
-			providerErr := providererrors.TranslateOpenAIError(err)
+			providerErr := o.translateError(err)
 
 			chunks <- &types.StreamChunk{
 				Content: "",
@@ -198,36 +239,198 @@ func (o *OpenAIProvider) CompleteStream(ctx context.Context, input *types.Stream
 			return
 		}
 
-		// later for token usages stuffs
-		if acc.Usage.TotalTokens > 0 {
-			inputTokens := int(acc.Usage.PromptTokens)
-			outputTokens := int(acc.Usage.CompletionTokens)
-			cost, _ := CalculateCost(standardModelID, inputTokens, outputTokens)
-
-			chunks <- &types.StreamChunk{
-				Done: true,
-				Usage: types.Usage{
-					PromptTokens:     inputTokens,
-					CompletionTokens: outputTokens,
-					TotalTokens:      inputTokens + outputTokens,
-				},
-				CostUSD: cost,
-			}
+		// Usage arrives in a trailing chunk (stream_options.include_usage), so the final chunk is sent once the stream ends
+		cost, _ := CalculateCost(standardModelID, inputTokens, outputTokens)
+		chunks <- &types.StreamChunk{
+			Done:         true,
+			Usage:        usage,
+			CostUSD:      cost,
+			FinishReason: openAIFinishReason(finishReason),
 		}
 	}()
 
 	return chunks, nil
 }
 
+// Embed embeds the input with one of the catalog's OpenAI embedding models.
+func (o *OpenAIProvider) Embed(ctx context.Context, input *types.EmbeddingInput) (*types.EmbeddingResponse, error) {
+	start := time.Now()
+	providerName := o.GetProviderName()
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	model, err := ResolveModel(o.name, input.Model)
+	if err != nil {
+		return nil, fmt.Errorf("invalid openai embedding model: %w", err)
+	}
+
+	params := openai.EmbeddingNewParams{
+		Input:          openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: input.Input},
+		Model:          openai.EmbeddingModel(model.UpstreamName),
+		EncodingFormat: openai.EmbeddingNewParamsEncodingFormatFloat,
+	}
+	if input.Dimensions != nil {
+		params.Dimensions = openai.Int(int64(*input.Dimensions))
+	}
+	if len(model.Params) > 0 {
+		params.SetExtraFields(model.Params)
+	}
+
+	result, err := withAPIKey(o.keys, providerName, o.translateError, func(key string) (*openai.CreateEmbeddingResponse, error) {
+		return o.client.Embeddings.New(ctx, params, o.requestOptions(model, key)...)
+	})
+
+	metrics.ProviderRequestDuration.WithLabelValues(providerName).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.ProviderRequestsTotal.WithLabelValues(providerName, "error").Inc()
+		return nil, o.translateError(err)
+	}
+	metrics.ProviderRequestsTotal.WithLabelValues(providerName, "success").Inc()
+
+	embeddings := make([][]float32, len(input.Input))
+	for _, data := range result.Data {
+		if data.Index < 0 || int(data.Index) >= len(embeddings) {
+			continue
+		}
+		vector := make([]float32, len(data.Embedding))
+		for i, value := range data.Embedding {
+			vector[i] = float32(value)
+		}
+		embeddings[data.Index] = vector
+	}
+
+	inputTokens := int(result.Usage.PromptTokens)
+	metrics.ProviderTokensUsed.WithLabelValues(providerName, "input").Add(float64(inputTokens))
+
+	cost, _ := CalculateCost(model.ID, inputTokens, 0)
+	metrics.ProviderCostTotal.WithLabelValues(providerName).Add(cost)
+
+	return &types.EmbeddingResponse{
+		Embeddings: embeddings,
+		Usage:      types.Usage{PromptTokens: inputTokens, TotalTokens: inputTokens},
+		CostUSD:    cost,
+		Model:      model.ID,
+	}, nil
+}
+
+// buildParams maps the request onto OpenAI's parameters. OpenAI supports every sampling setting
+// the router accepts, with the same ranges, so they are passed through unchanged.
+func (o *OpenAIProvider) buildParams(model ModelInfo, messages []openai.ChatCompletionMessageParamUnion, params types.SamplingParams) openai.ChatCompletionNewParams {
+	request := openai.ChatCompletionNewParams{
+		Messages:            messages,
+		Model:               openai.ChatModel(model.UpstreamName),
+		MaxCompletionTokens: openai.Opt(resolveMaxTokens(params, model.MaxTokensOr(o.maxTokens))),
+	}
+
+	if len(model.Params) > 0 {
+		request.SetExtraFields(model.Params)
+	}
+
+	if params.Temperature != nil {
+		request.Temperature = openai.Opt(*params.Temperature)
+	}
+	if params.TopP != nil {
+		request.TopP = openai.Opt(*params.TopP)
+	}
+	if params.FrequencyPenalty != nil {
+		request.FrequencyPenalty = openai.Opt(*params.FrequencyPenalty)
+	}
+	if params.PresencePenalty != nil {
+		request.PresencePenalty = openai.Opt(*params.PresencePenalty)
+	}
+
+	return request
+}
+
+// applyTools adds the request's tools and tool choice. The router's tool shapes are OpenAI's own.
+func (o *OpenAIProvider) applyTools(request *openai.ChatCompletionNewParams, tools []types.Tool, choice *types.ToolChoice) {
+	for _, tool := range tools {
+		definition := shared.FunctionDefinitionParam{
+			Name:       tool.Function.Name,
+			Parameters: shared.FunctionParameters(tool.Function.Parameters),
+		}
+		if tool.Function.Description != "" {
+			definition.Description = openai.String(tool.Function.Description)
+		}
+		request.Tools = append(request.Tools, openai.ChatCompletionFunctionTool(definition))
+	}
+
+	if choice == nil {
+		return
+	}
+
+	if choice.Mode == types.ToolChoiceFunction {
+		request.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{
+			OfFunctionToolChoice: &openai.ChatCompletionNamedToolChoiceParam{
+				Function: openai.ChatCompletionNamedToolChoiceFunctionParam{Name: choice.Function},
+			},
+		}
+		return
+	}
+
+	request.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String(choice.Mode)}
+}
+
+// applyResponseFormat requests structured output, which OpenAI supports natively.
+func (o *OpenAIProvider) applyResponseFormat(request *openai.ChatCompletionNewParams, format *types.ResponseFormat) {
+	if format == nil {
+		return
+	}
+
+	switch format.Type {
+	case types.ResponseFormatJSONObject:
+		request.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{OfJSONObject: &shared.ResponseFormatJSONObjectParam{}}
+
+	case types.ResponseFormatJSONSchema:
+		schema := shared.ResponseFormatJSONSchemaJSONSchemaParam{
+			Name:   format.JSONSchema.Name,
+			Schema: format.JSONSchema.Schema,
+		}
+		if format.JSONSchema.Description != "" {
+			schema.Description = openai.String(format.JSONSchema.Description)
+		}
+		if format.JSONSchema.Strict != nil {
+			schema.Strict = openai.Bool(*format.JSONSchema.Strict)
+		}
+		request.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{JSONSchema: schema}}
+	}
+}
+
 func (o *OpenAIProvider) convertMessages(messages []types.Message) []openai.ChatCompletionMessageParamUnion {
 	var openAIMessages []openai.ChatCompletionMessageParamUnion
 
 	for _, message := range messages {
 		var toAppend openai.ChatCompletionMessageParamUnion
-		if message.Role == "user" {
-			toAppend = openai.UserMessage(message.Content)
-		} else {
+		switch message.Role {
+		case "user":
+			if len(message.Parts) > 0 {
+				toAppend = openai.UserMessage(openAIContentParts(message.Parts))
+			} else {
+				toAppend = openai.UserMessage(message.Content)
+			}
+		case "system":
+			// OpenAI accepts system messages anywhere in the conversation, so they are kept in place
+			toAppend = openai.SystemMessage(message.Content)
+		case "tool":
+			toAppend = openai.ToolMessage(message.Content, message.ToolCallID)
+		default:
 			toAppend = openai.AssistantMessage(message.Content)
+			if message.Content == "" && len(message.ToolCalls) > 0 {
+				// Tool-call-only turns are sent without content rather than with an empty string
+				toAppend.OfAssistant.Content = openai.ChatCompletionAssistantMessageParamContentUnion{}
+			}
+			for _, call := range message.ToolCalls {
+				toAppend.OfAssistant.ToolCalls = append(toAppend.OfAssistant.ToolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+					OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+						ID: call.ID,
+						Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+							Name:      call.Function.Name,
+							Arguments: call.Function.Arguments,
+						},
+					},
+				})
+			}
 		}
 
 		openAIMessages = append(openAIMessages, toAppend)
@@ -237,11 +440,56 @@ func (o *OpenAIProvider) convertMessages(messages []types.Message) []openai.Chat
 	return openAIMessages
 }
 
+// openAIContentParts maps content parts onto OpenAI's, which they mirror.
+func openAIContentParts(parts []types.ContentPart) []openai.ChatCompletionContentPartUnionParam {
+	converted := make([]openai.ChatCompletionContentPartUnionParam, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case types.ContentPartImageURL:
+			converted = append(converted, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL:    part.ImageURL.URL,
+				Detail: part.ImageURL.Detail,
+			}))
+		case types.ContentPartInputAudio:
+			converted = append(converted, openai.InputAudioContentPart(openai.ChatCompletionContentPartInputAudioInputAudioParam{
+				Data:   part.InputAudio.Data,
+				Format: part.InputAudio.Format,
+			}))
+		case types.ContentPartFile:
+			file := openai.ChatCompletionContentPartFileFileParam{FileData: openai.String(part.File.FileData)}
+			if part.File.Filename != "" {
+				file.Filename = openai.String(part.File.Filename)
+			}
+			converted = append(converted, openai.FileContentPart(file))
+		default:
+			converted = append(converted, openai.TextContentPart(part.Text))
+		}
+	}
+	return converted
+}
+
 func (o *OpenAIProvider) convertToRouterMessage(openAIMessage *openai.ChatCompletion) types.Message {
+	message := openAIMessage.Choices[0].Message
+
+	var toolCalls []types.ToolCall
+	for _, call := range message.ToolCalls {
+		if call.Type != "function" {
+			continue
+		}
+		toolCalls = append(toolCalls, types.ToolCall{
+			ID:   call.ID,
+			Type: "function",
+			Function: types.FunctionCall{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			},
+		})
+	}
 
 	return types.Message{
-		Role:    string(openAIMessage.Choices[0].Message.Role),
-		Content: openAIMessage.Choices[0].Message.Content,
+		Role:      string(message.Role),
+		Content:   message.Content,
+		ToolCalls: toolCalls,
 	}
 }
 
@@ -256,7 +504,7 @@ func (o *OpenAIProvider) CountTokens(ctx context.Context, messages []types.Messa
 	for _, msg := range messages {
 
 		tokens := encoding.Encode(msg.Content, nil, nil)
-		totalTokens += len(tokens)
+		totalTokens += len(tokens) + mediaTokens(msg)
 
 		// Add overhead for role and message formatting
 		// OpenAI format: ~4 tokens per message for structure
@@ -267,18 +515,62 @@ func (o *OpenAIProvider) CountTokens(ctx context.Context, messages []types.Messa
 }
 
 func (o *OpenAIProvider) GetProviderName() string {
-	return ProviderOpenAI
+	return o.name
+}
+
+// translateError converts an SDK error, naming this provider in it (OpenAI-compatible servers
+// share this implementation under their own names).
+func (o *OpenAIProvider) translateError(err error) error {
+	translated := providererrors.TranslateOpenAIError(err)
+
+	var providerErr *providererrors.ProviderError
+	if errors.As(translated, &providerErr) {
+		providerErr.ProviderName = o.name
+	}
+	return translated
+}
+
+// requestOptions returns the client options specific to a call to the given model with the given key.
+func (o *OpenAIProvider) requestOptions(model ModelInfo, key string) []option.RequestOption {
+	var opts []option.RequestOption
+	if o.modelOptions != nil {
+		opts = append(opts, o.modelOptions(model)...)
+	}
+	if key != "" {
+		opts = append(opts, o.keyOption(key))
+	}
+	return opts
+}
+
+// KeyUsage returns the usage and health of the provider's API keys.
+func (o *OpenAIProvider) KeyUsage() []KeyStats {
+	if o.keys == nil {
+		return nil
+	}
+	return o.keys.Stats()
 }
 
 func NewOpenAIProvider(config OpenAIConfig) (*OpenAIProvider, error) {
-	client := openai.NewClient(
-		option.WithAPIKey(config.APIKey),
-	)
+	var opts []option.RequestOption
+	if config.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(config.BaseURL))
+	}
 
-	// Map standardized model ID to OpenAI SDK model
-	model, err := MapToOpenAIModel(config.Model)
+	provider, err := newOpenAIProvider(ProviderOpenAI, config, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("invalid OpenAI model: %w", err)
+		return nil, err
+	}
+	if provider.keys == nil {
+		return nil, fmt.Errorf("API key cannot be empty")
+	}
+	return provider, nil
+}
+
+// newOpenAIProvider creates a provider speaking the OpenAI chat completions API under the given name.
+// The client options select the server and how to authenticate with it.
+func newOpenAIProvider(name string, config OpenAIConfig, opts ...option.RequestOption) (*OpenAIProvider, error) {
+	if _, err := ResolveModel(name, config.Model); err != nil {
+		return nil, fmt.Errorf("invalid %s model: %w", name, err)
 	}
 
 	timeout := config.Timeout
@@ -286,11 +578,18 @@ func NewOpenAIProvider(config OpenAIConfig) (*OpenAIProvider, error) {
 		timeout = 30 * time.Second
 	}
 
+	keys, err := resolveKeyPool(config.Keys, config.APIKey)
+	if err != nil {
+		return nil, err
+	}
+
 	return &OpenAIProvider{
-		client:          client,
-		model:           model,
+		name:            name,
+		client:          openai.NewClient(opts...),
 		standardModelID: config.Model,
 		maxTokens:       int64(config.MaxTokens),
 		timeout:         timeout,
+		keys:            keys,
+		keyOption:       option.WithAPIKey,
 	}, nil
 }
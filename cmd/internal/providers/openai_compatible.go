@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// ProviderTypeOpenAICompatible is the provider type for servers that speak the OpenAI chat
+// completions API, such as Ollama, vLLM and llama.cpp.
+const ProviderTypeOpenAICompatible = "openai-compatible"
+
+type OpenAICompatibleConfig struct {
+	Name      string   // Instance name, e.g. "ollama-gpu1"; prefixes the instance's catalog models
+	BaseURL   string   // e.g. "http://localhost:11434/v1"
+	APIKey    string   // Optional
+	Keys      *KeyPool // Optional; several keys to use instead of APIKey
+	Headers   map[string]string
+	MaxTokens int64
+	Model     string
+	Timeout   time.Duration
+}
+
+// OpenAICompatibleProvider calls a self-hosted or third-party server through the OpenAI wire format.
+// Any number of instances can be configured, each under its own name, and their models are
+// called through the catalog like any other provider's.
+type OpenAICompatibleProvider struct {
+	*OpenAIProvider
+}
+
+func NewOpenAICompatibleProvider(config OpenAICompatibleConfig) (*OpenAICompatibleProvider, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("name is required for %s providers", ProviderTypeOpenAICompatible)
+	}
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("baseURL is required for provider %s", config.Name)
+	}
+
+	opts := []option.RequestOption{
+		option.WithBaseURL(config.BaseURL),
+		// The SDK picks these up from OPENAI_* environment variables; they belong to OpenAI, not this
+		// server. Configured keys are added back per request.
+		option.WithHeaderDel("Authorization"),
+		option.WithHeaderDel("OpenAI-Organization"),
+		option.WithHeaderDel("OpenAI-Project"),
+	}
+	for name, value := range config.Headers {
+		opts = append(opts, option.WithHeader(name, value))
+	}
+
+	provider, err := newOpenAIProvider(config.Name, OpenAIConfig{
+		APIKey:    config.APIKey,
+		Keys:      config.Keys,
+		MaxTokens: config.MaxTokens,
+		Model:     config.Model,
+		Timeout:   config.Timeout,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAICompatibleProvider{OpenAIProvider: provider}, nil
+}
@@ -0,0 +1,192 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"llm-router/types"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeOpenAIServer stands in for an OpenAI-compatible server, recording the last request it received.
+type fakeOpenAIServer struct {
+	*httptest.Server
+	lastURL     *url.URL
+	lastHeaders http.Header
+	lastBody    map[string]any
+}
+
+func newFakeOpenAIServer(t *testing.T) *fakeOpenAIServer {
+	t.Helper()
+	fake := &fakeOpenAIServer{}
+
+	fake.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+			http.NotFound(w, r)
+			return
+		}
+
+		fake.lastURL = r.URL
+		fake.lastHeaders = r.Header.Clone()
+		fake.lastBody = map[string]any{}
+		if err := json.NewDecoder(r.Body).Decode(&fake.lastBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if stream, _ := fake.lastBody["stream"].(bool); stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, event := range []string{
+				`{"id":"1","object":"chat.completion.chunk","created":1,"model":"llama3:8b","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"}}]}`,
+				`{"id":"1","object":"chat.completion.chunk","created":1,"model":"llama3:8b","choices":[{"index":0,"delta":{"content":" there"},"finish_reason":"stop"}]}`,
+				`{"id":"1","object":"chat.completion.chunk","created":1,"model":"llama3:8b","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`,
+				`[DONE]`,
+			} {
+				fmt.Fprintf(w, "data: %s\n\n", event)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"created": 1,
+			"model": "llama3:8b",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hello there"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 5, "completion_tokens": 2, "total_tokens": 7}
+		}`)
+	}))
+	t.Cleanup(fake.Close)
+
+	return fake
+}
+
+func TestOpenAICompatibleProvider_Complete(t *testing.T) {
+	// Must not be sent to a server that isn't OpenAI
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+
+	server := newFakeOpenAIServer(t)
+
+	provider, err := NewOpenAICompatibleProvider(OpenAICompatibleConfig{
+		Name:      "ollama-gpu1",
+		BaseURL:   server.URL + "/v1",
+		Headers:   map[string]string{"X-Team": "research"},
+		MaxTokens: 256,
+		Model:     "ollama-gpu1/llama3",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleProvider() error = %v", err)
+	}
+
+	response, err := provider.Complete(context.Background(), &types.CompletionInput{
+		Messages: []types.Message{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	if response.Message.Content != "Hello there" || response.FinishReason != types.FinishReasonStop {
+		t.Errorf("response = %+v, want the server's message", response)
+	}
+	if response.Model != "ollama-gpu1/llama3" || response.Usage.TotalTokens != 7 {
+		t.Errorf("Model = %s, TotalTokens = %d, want ollama-gpu1/llama3 and 7", response.Model, response.Usage.TotalTokens)
+	}
+	if response.CostUSD != 0 {
+		t.Errorf("CostUSD = %v, want 0 for a zero-cost catalog entry", response.CostUSD)
+	}
+
+	if server.lastURL.Path != "/v1/chat/completions" {
+		t.Errorf("path = %s, want /v1/chat/completions", server.lastURL.Path)
+	}
+	if got := server.lastBody["model"]; got != "llama3:8b" {
+		t.Errorf("model sent = %v, want the catalog's upstream name", got)
+	}
+	if got := server.lastHeaders.Get("X-Team"); got != "research" {
+		t.Errorf("X-Team header = %q, want the configured header", got)
+	}
+	if got := server.lastHeaders.Get("Authorization"); got != "" {
+		t.Errorf("Authorization header = %q, want none without an API key", got)
+	}
+	if provider.GetProviderName() != "ollama-gpu1" {
+		t.Errorf("GetProviderName() = %s, want the instance name", provider.GetProviderName())
+	}
+}
+
+func TestOpenAICompatibleProvider_CompleteStream(t *testing.T) {
+	server := newFakeOpenAIServer(t)
+
+	provider, err := NewOpenAICompatibleProvider(OpenAICompatibleConfig{
+		Name:    "ollama-gpu1",
+		BaseURL: server.URL + "/v1",
+		APIKey:  "local-key",
+		Model:   "ollama-gpu1/llama3",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleProvider() error = %v", err)
+	}
+
+	chunks, err := provider.CompleteStream(context.Background(), &types.StreamCompletionInput{
+		Messages: []types.Message{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CompleteStream() error = %v", err)
+	}
+
+	var content string
+	var final *types.StreamChunk
+	for chunk := range chunks {
+		content += chunk.Content
+		if chunk.Done {
+			final = chunk
+		}
+	}
+
+	if content != "Hello there" {
+		t.Errorf("content = %q, want %q", content, "Hello there")
+	}
+	if final == nil || final.Error != nil || final.Usage.TotalTokens != 7 {
+		t.Errorf("final chunk = %+v, want usage from the server", final)
+	}
+	if got := server.lastHeaders.Get("Authorization"); got != "Bearer local-key" {
+		t.Errorf("Authorization header = %q, want the configured key", got)
+	}
+}
+
+func TestProviderFactory_OpenAICompatible(t *testing.T) {
+	factory := NewProviderFactory()
+
+	provider, err := factory.CreateProvider(types.ProviderConfigWithExtras{
+		Name:    "ollama-gpu1",
+		Type:    ProviderTypeOpenAICompatible,
+		BaseURL: "http://localhost:11434/v1",
+		Enabled: true,
+		Defaults: &types.ProviderExtra{
+			Model:     "ollama-gpu1/llama3",
+			MaxTokens: 1024,
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateProvider() error = %v", err)
+	}
+	if provider.GetProviderName() != "ollama-gpu1" {
+		t.Errorf("GetProviderName() = %s, want ollama-gpu1", provider.GetProviderName())
+	}
+
+	_, err = factory.CreateProvider(types.ProviderConfigWithExtras{
+		Name:    "vllm-prod",
+		Type:    ProviderTypeOpenAICompatible,
+		Enabled: true,
+		Defaults: &types.ProviderExtra{
+			Model:     "ollama-gpu1/llama3",
+			MaxTokens: 1024,
+		},
+	})
+	if err == nil {
+		t.Error("expected an error for a model belonging to another instance")
+	}
+}
@@ -0,0 +1,40 @@
+package providers
+
+import (
+	"fmt"
+	providererrors "llm-router/cmd/internal/provider_errors"
+	"llm-router/types"
+)
+
+// resolveMaxTokens prefers the client's max_tokens over the provider default.
+func resolveMaxTokens(params types.SamplingParams, defaultMaxTokens int64) int64 {
+	if params.MaxTokens != nil && *params.MaxTokens > 0 {
+		return int64(*params.MaxTokens)
+	}
+	return defaultMaxTokens
+}
+
+// clamp keeps value within the range a provider accepts.
+func clamp(value float64, min float64, max float64) float64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// unsupportedParam rejects a setting the provider has no equivalent for.
+// Validation errors are not retried, so the request moves on to the next provider in the chain.
+func unsupportedParam(provider string, name string) error {
+	return providererrors.NewValidationError(provider, fmt.Sprintf("%s is not supported by %s", name, provider), nil)
+}
+
+func float32Ptr(value *float64) *float32 {
+	if value == nil {
+		return nil
+	}
+	v := float32(*value)
+	return &v
+}
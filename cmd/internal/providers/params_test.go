@@ -0,0 +1,212 @@
+package providers
+
+import (
+	"errors"
+	providererrors "llm-router/cmd/internal/provider_errors"
+	"llm-router/types"
+	"testing"
+)
+
+func float64Ptr(v float64) *float64 { return &v }
+func intPtr(v int) *int             { return &v }
+
+func TestOpenAIProvider_BuildParams(t *testing.T) {
+	provider := &OpenAIProvider{maxTokens: 1024}
+
+	tests := []struct {
+		name          string
+		params        types.SamplingParams
+		wantMaxTokens int64
+		wantTemp      *float64
+		wantTopP      *float64
+		wantFreq      *float64
+		wantPresence  *float64
+	}{
+		{
+			name:          "defaults",
+			wantMaxTokens: 1024,
+		},
+		{
+			name: "all params passed through",
+			params: types.SamplingParams{
+				Temperature:      float64Ptr(1.5),
+				MaxTokens:        intPtr(256),
+				TopP:             float64Ptr(0.9),
+				FrequencyPenalty: float64Ptr(-1),
+				PresencePenalty:  float64Ptr(0.5),
+			},
+			wantMaxTokens: 256,
+			wantTemp:      float64Ptr(1.5),
+			wantTopP:      float64Ptr(0.9),
+			wantFreq:      float64Ptr(-1),
+			wantPresence:  float64Ptr(0.5),
+		},
+		{
+			name:          "zero temperature is kept",
+			params:        types.SamplingParams{Temperature: float64Ptr(0)},
+			wantMaxTokens: 1024,
+			wantTemp:      float64Ptr(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := provider.buildParams(ModelInfo{UpstreamName: "gpt-4o-mini"}, nil, tt.params)
+
+			if got := request.MaxCompletionTokens.Value; got != tt.wantMaxTokens {
+				t.Errorf("MaxCompletionTokens = %d, want %d", got, tt.wantMaxTokens)
+			}
+			checkOpt(t, "Temperature", request.Temperature.Valid(), request.Temperature.Value, tt.wantTemp)
+			checkOpt(t, "TopP", request.TopP.Valid(), request.TopP.Value, tt.wantTopP)
+			checkOpt(t, "FrequencyPenalty", request.FrequencyPenalty.Valid(), request.FrequencyPenalty.Value, tt.wantFreq)
+			checkOpt(t, "PresencePenalty", request.PresencePenalty.Valid(), request.PresencePenalty.Value, tt.wantPresence)
+		})
+	}
+}
+
+func TestAnthropicProvider_BuildParams(t *testing.T) {
+	provider := &AnthropicProvider{maxTokens: 1024}
+
+	tests := []struct {
+		name          string
+		params        types.SamplingParams
+		wantErr       bool
+		wantMaxTokens int64
+		wantTemp      *float64
+		wantTopP      *float64
+	}{
+		{
+			name:          "defaults",
+			wantMaxTokens: 1024,
+		},
+		{
+			name: "supported params passed through",
+			params: types.SamplingParams{
+				Temperature: float64Ptr(0.3),
+				MaxTokens:   intPtr(200),
+				TopP:        float64Ptr(0.8),
+			},
+			wantMaxTokens: 200,
+			wantTemp:      float64Ptr(0.3),
+			wantTopP:      float64Ptr(0.8),
+		},
+		{
+			name:          "temperature above 1 is clamped",
+			params:        types.SamplingParams{Temperature: float64Ptr(1.8)},
+			wantMaxTokens: 1024,
+			wantTemp:      float64Ptr(1),
+		},
+		{
+			name:          "zero penalties are accepted",
+			params:        types.SamplingParams{FrequencyPenalty: float64Ptr(0), PresencePenalty: float64Ptr(0)},
+			wantMaxTokens: 1024,
+		},
+		{
+			name:    "frequency penalty is rejected",
+			params:  types.SamplingParams{FrequencyPenalty: float64Ptr(0.5)},
+			wantErr: true,
+		},
+		{
+			name:    "presence penalty is rejected",
+			params:  types.SamplingParams{PresencePenalty: float64Ptr(-0.5)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := provider.buildParams(ModelInfo{UpstreamName: "claude-3-haiku-20240307"}, nil, tt.params)
+
+			if tt.wantErr {
+				var providerErr *providererrors.ProviderError
+				if !errors.As(err, &providerErr) || providerErr.Type != providererrors.ErrorTypeValidation || providerErr.Retryable {
+					t.Fatalf("buildParams() error = %v, want a non-retryable validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildParams() error = %v", err)
+			}
+
+			if request.MaxTokens != tt.wantMaxTokens {
+				t.Errorf("MaxTokens = %d, want %d", request.MaxTokens, tt.wantMaxTokens)
+			}
+			checkOpt(t, "Temperature", request.Temperature.Valid(), request.Temperature.Value, tt.wantTemp)
+			checkOpt(t, "TopP", request.TopP.Valid(), request.TopP.Value, tt.wantTopP)
+		})
+	}
+}
+
+func TestGeminiProvider_BuildConfig(t *testing.T) {
+	provider := &GeminiProvider{maxTokens: 1024}
+
+	tests := []struct {
+		name          string
+		params        types.SamplingParams
+		wantMaxTokens int32
+		wantTemp      *float64
+		wantTopP      *float64
+		wantFreq      *float64
+		wantPresence  *float64
+	}{
+		{
+			name:          "defaults",
+			wantMaxTokens: 1024,
+		},
+		{
+			name: "all params passed through",
+			params: types.SamplingParams{
+				Temperature:      float64Ptr(0.5),
+				MaxTokens:        intPtr(64),
+				TopP:             float64Ptr(0.25),
+				FrequencyPenalty: float64Ptr(1),
+				PresencePenalty:  float64Ptr(-2),
+			},
+			wantMaxTokens: 64,
+			wantTemp:      float64Ptr(0.5),
+			wantTopP:      float64Ptr(0.25),
+			wantFreq:      float64Ptr(1),
+			wantPresence:  float64Ptr(-2),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := provider.buildConfig(ModelInfo{}, "", tt.params)
+
+			if config.MaxOutputTokens != tt.wantMaxTokens {
+				t.Errorf("MaxOutputTokens = %d, want %d", config.MaxOutputTokens, tt.wantMaxTokens)
+			}
+			checkFloat32(t, "Temperature", config.Temperature, tt.wantTemp)
+			checkFloat32(t, "TopP", config.TopP, tt.wantTopP)
+			checkFloat32(t, "FrequencyPenalty", config.FrequencyPenalty, tt.wantFreq)
+			checkFloat32(t, "PresencePenalty", config.PresencePenalty, tt.wantPresence)
+		})
+	}
+}
+
+func checkOpt(t *testing.T, name string, valid bool, value float64, want *float64) {
+	t.Helper()
+	if want == nil {
+		if valid {
+			t.Errorf("%s = %v, want unset", name, value)
+		}
+		return
+	}
+	if !valid || value != *want {
+		t.Errorf("%s = %v (set: %v), want %v", name, value, valid, *want)
+	}
+}
+
+func checkFloat32(t *testing.T, name string, got *float32, want *float64) {
+	t.Helper()
+	if want == nil {
+		if got != nil {
+			t.Errorf("%s = %v, want unset", name, *got)
+		}
+		return
+	}
+	if got == nil || *got != float32(*want) {
+		t.Errorf("%s = %v, want %v", name, got, *want)
+	}
+}
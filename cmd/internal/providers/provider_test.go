@@ -5,13 +5,18 @@ import (
 	"testing"
 )
 
+var testCatalog = []types.ModelConfig{
+	{ID: "openai/gpt-4o-mini", Provider: "openai", Name: "GPT-4o Mini"},
+	{ID: "gemini/gemini-2.5-flash", Provider: "gemini", Name: "Gemini Flash"},
+	{ID: "anthropic/claude-haiku-3", Provider: "anthropic", Name: "Claude Haiku"},
+	{ID: "test-model", Provider: "unknown-provider", Name: "Test Model"},
+	{ID: "ollama-gpu1/llama3", Provider: "ollama-gpu1", Name: "Llama 3", UpstreamName: "llama3:8b", Capabilities: []string{"tools"}},
+	{ID: "azure-eastus/gpt-4o", Provider: "azure-eastus", Name: "GPT-4o (Azure)", InputCostPer1M: 2.5, OutputCostPer1M: 10},
+	{ID: "azure-eastus/gpt-4o-mini", Provider: "azure-eastus", Name: "GPT-4o Mini (Azure)"},
+}
+
 func init() {
-	InitializeModelRegistry([]types.ModelConfig{
-		{ID: "openai/gpt-4o-mini", Provider: "openai", Name: "GPT-4o Mini"},
-		{ID: "gemini/gemini-2.5-flash", Provider: "gemini", Name: "Gemini Flash"},
-		{ID: "anthropic/claude-haiku-3", Provider: "anthropic", Name: "Claude Haiku"},
-		{ID: "test-model", Provider: "unknown-provider", Name: "Test Model"},
-	}, nil)
+	InitializeModelRegistry(testCatalog, nil)
 }
 
 var providerConfigs = []types.ProviderConfigWithExtras{
@@ -95,6 +100,62 @@ func TestProviderFactoryWithInvalidAPIKey(t *testing.T) {
 	}
 }
 
+func TestResolveModel(t *testing.T) {
+	InitializeModelRegistry(GetDefaultCatalog(), []types.ModelConfig{
+		// Pricing override of a built-in model, without an upstream name
+		{ID: "openai/gpt-4o", Provider: "openai", Name: "GPT-4o", InputCostPer1M: 2},
+		// A model added in config only
+		{ID: "openai/gpt-next", Provider: "openai", Name: "GPT Next", MaxTokens: 4096, Params: map[string]any{"reasoning_effort": "low"}},
+		{ID: "anthropic/claude-next", Provider: "anthropic", Name: "Claude Next", UpstreamName: "claude-next-20260101"},
+	})
+	t.Cleanup(func() {
+		InitializeModelRegistry(testCatalog, nil)
+	})
+
+	tests := []struct {
+		name          string
+		provider      string
+		modelID       string
+		wantUpstream  string
+		wantMaxTokens int64
+		wantErr       bool
+	}{
+		{name: "built-in model", provider: "anthropic", modelID: "anthropic/claude-haiku-3", wantUpstream: "claude-3-haiku-20240307", wantMaxTokens: 1024},
+		{name: "override keeps the built-in upstream name", provider: "openai", modelID: "openai/gpt-4o", wantUpstream: "chatgpt-4o-latest", wantMaxTokens: 1024},
+		{name: "config model defaults to its ID", provider: "openai", modelID: "openai/gpt-next", wantUpstream: "gpt-next", wantMaxTokens: 4096},
+		{name: "config model with an upstream name", provider: "anthropic", modelID: "anthropic/claude-next", wantUpstream: "claude-next-20260101", wantMaxTokens: 1024},
+		{name: "unknown model", provider: "openai", modelID: "openai/gpt-unknown", wantErr: true},
+		{name: "model of another provider", provider: "gemini", modelID: "openai/gpt-next", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ResolveModel(tt.provider, tt.modelID)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ResolveModel(%s, %s) expected an error", tt.provider, tt.modelID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveModel(%s, %s) error = %v", tt.provider, tt.modelID, err)
+			}
+			if info.UpstreamName != tt.wantUpstream {
+				t.Errorf("UpstreamName = %s, want %s", info.UpstreamName, tt.wantUpstream)
+			}
+			if got := info.MaxTokensOr(1024); got != tt.wantMaxTokens {
+				t.Errorf("MaxTokensOr(1024) = %d, want %d", got, tt.wantMaxTokens)
+			}
+		})
+	}
+
+	info, _ := ResolveModel("openai", "openai/gpt-next")
+	params := (&OpenAIProvider{maxTokens: 1024}).buildParams(info, nil, types.SamplingParams{})
+	if got := params.ExtraFields()["reasoning_effort"]; got != "low" {
+		t.Errorf("reasoning_effort = %v, want the catalog param to be sent", got)
+	}
+}
+
 // Test model selection functions
 // func TestSelectOpenAIModel(t *testing.T) {
 // 	tests := []struct {
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"llm-router/types"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+	"google.golang.org/genai"
+)
+
+var personFormat = &types.ResponseFormat{
+	Type: types.ResponseFormatJSONSchema,
+	JSONSchema: &types.JSONSchema{
+		Name: "person",
+		Schema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"name": map[string]any{"type": "string"}},
+			"required":   []any{"name"},
+		},
+	},
+}
+
+func TestOpenAI_ResponseFormatMapping(t *testing.T) {
+	provider := &OpenAIProvider{}
+
+	var params openai.ChatCompletionNewParams
+	provider.applyResponseFormat(&params, personFormat)
+	if schema := params.ResponseFormat.OfJSONSchema; schema == nil || schema.JSONSchema.Name != "person" {
+		t.Errorf("ResponseFormat = %+v, want the person json_schema", params.ResponseFormat)
+	}
+
+	params = openai.ChatCompletionNewParams{}
+	provider.applyResponseFormat(&params, &types.ResponseFormat{Type: types.ResponseFormatJSONObject})
+	if params.ResponseFormat.OfJSONObject == nil {
+		t.Errorf("ResponseFormat = %+v, want json_object", params.ResponseFormat)
+	}
+}
+
+func TestGemini_ResponseFormatMapping(t *testing.T) {
+	provider := &GeminiProvider{}
+
+	config := &genai.GenerateContentConfig{}
+	provider.applyResponseFormat(config, personFormat)
+	if config.ResponseMIMEType != "application/json" || config.ResponseJsonSchema == nil {
+		t.Errorf("config = %+v, want a JSON response with the person schema", config)
+	}
+
+	config = &genai.GenerateContentConfig{}
+	provider.applyResponseFormat(config, &types.ResponseFormat{Type: types.ResponseFormatText})
+	if config.ResponseMIMEType != "" {
+		t.Errorf("ResponseMIMEType = %q, want unset for text", config.ResponseMIMEType)
+	}
+}
+
+func TestAnthropic_ResponseFormatMapping(t *testing.T) {
+	provider := &AnthropicProvider{}
+
+	var params anthropic.MessageNewParams
+	if err := provider.applyResponseFormat(&params, personFormat); err != nil {
+		t.Fatalf("applyResponseFormat() error = %v", err)
+	}
+	if len(params.Tools) != 1 || params.Tools[0].OfTool.Name != "person" {
+		t.Fatalf("Tools = %+v, want the person tool", params.Tools)
+	}
+	if params.ToolChoice.OfTool == nil || params.ToolChoice.OfTool.Name != "person" {
+		t.Errorf("ToolChoice = %+v, want the person tool forced", params.ToolChoice)
+	}
+
+	params = anthropic.MessageNewParams{}
+	provider.applyTools(&params, []types.Tool{weatherTool}, nil)
+	if err := provider.applyResponseFormat(&params, personFormat); err == nil {
+		t.Error("expected response_format with tools to be rejected")
+	}
+
+	message := provider.convertToRouterMessage(&anthropic.Message{
+		Role:       "assistant",
+		StopReason: anthropic.StopReasonToolUse,
+		Content: []anthropic.ContentBlockUnion{
+			{Type: "tool_use", ID: "toolu_1", Name: "person", Input: []byte(`{"name":"Ada"}`)},
+		},
+	}, true)
+	if message.Content != `{"name":"Ada"}` || len(message.ToolCalls) != 0 {
+		t.Errorf("message = %+v, want the tool input as content", message)
+	}
+	if got := anthropicResponseFinishReason(anthropic.StopReasonToolUse, true); got != types.FinishReasonStop {
+		t.Errorf("finish reason = %q, want %q", got, types.FinishReasonStop)
+	}
+}
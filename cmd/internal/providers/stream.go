@@ -0,0 +1,97 @@
+package providers
+
+import (
+	"context"
+	"llm-router/types"
+	"strings"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// streamState tracks what a provider stream has produced so far, so usage can still be
+// reported when the stream's context is cancelled part way through.
+type streamState struct {
+	ctx      context.Context
+	chunks   chan<- *types.StreamChunk
+	modelID  string
+	messages []types.Message
+	output   strings.Builder
+}
+
+func newStreamState(ctx context.Context, chunks chan<- *types.StreamChunk, modelID string, messages []types.Message) *streamState {
+	return &streamState{
+		ctx:      ctx,
+		chunks:   chunks,
+		modelID:  modelID,
+		messages: messages,
+	}
+}
+
+// sendContent forwards a piece of content. It returns false once the context is done,
+// in which case the provider should stop reading from upstream and call finishCancelled.
+func (s *streamState) sendContent(text string) bool {
+	s.output.WriteString(text)
+
+	select {
+	case s.chunks <- &types.StreamChunk{Content: text}:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// sendToolCalls forwards tool call fragments, with the same cancellation behaviour as sendContent.
+func (s *streamState) sendToolCalls(deltas []types.ToolCallDelta) bool {
+	for _, delta := range deltas {
+		s.output.WriteString(delta.Function.Name)
+		s.output.WriteString(delta.Function.Arguments)
+	}
+
+	select {
+	case s.chunks <- &types.StreamChunk{ToolCalls: deltas}:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// finishCancelled sends the final chunk of a stream whose context was cancelled or timed out.
+// Upstream still bills for what it generated, so the chunk carries usage and cost: whatever the
+// provider reported before it was cut off, with the rest estimated from the prompt and the
+// content produced so far.
+func (s *streamState) finishCancelled(reported types.Usage, err error) {
+	usage := reported
+	if usage.PromptTokens == 0 {
+		for _, msg := range s.messages {
+			usage.PromptTokens += estimateTokens(msg.Content) + mediaTokens(msg) + 4
+		}
+	}
+	if usage.CompletionTokens == 0 {
+		usage.CompletionTokens = estimateTokens(s.output.String())
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	cost, _ := CalculateCost(s.modelID, usage.PromptTokens, usage.CompletionTokens)
+
+	// Callers drain the channel until it is closed, so this send cannot block forever
+	s.chunks <- &types.StreamChunk{
+		Done:    true,
+		Error:   err,
+		Usage:   usage,
+		CostUSD: cost,
+	}
+}
+
+func estimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	encoding, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		// Roughly four characters per token for English text
+		return len(text) / 4
+	}
+
+	return len(encoding.Encode(text, nil, nil))
+}
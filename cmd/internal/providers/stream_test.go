@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"llm-router/types"
+	"testing"
+)
+
+func TestStreamStateSendContent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	chunks := make(chan *types.StreamChunk, 1)
+	state := newStreamState(ctx, chunks, "openai/gpt-4o-mini", nil)
+
+	if !state.sendContent("Hello") {
+		t.Fatal("Expected content to be sent while the context is live")
+	}
+	if chunk := <-chunks; chunk.Content != "Hello" {
+		t.Errorf("Expected chunk content %q, got %q", "Hello", chunk.Content)
+	}
+
+	cancel()
+	// Nobody reads the channel, so the send can only complete through the cancelled context
+	chunks <- &types.StreamChunk{}
+	if state.sendContent(" world") {
+		t.Error("Expected sendContent to report the cancelled context")
+	}
+}
+
+func TestStreamStateFinishCancelled(t *testing.T) {
+	messages := []types.Message{{Role: "user", Content: "Tell me a story"}}
+	cancelErr := errors.New("request canceled")
+
+	tests := []struct {
+		name           string
+		reported       types.Usage
+		wantPrompt     int
+		wantCompletion int
+	}{
+		{
+			name:           "estimates missing usage from prompt and output",
+			reported:       types.Usage{},
+			wantPrompt:     estimateTokens("Tell me a story") + 4,
+			wantCompletion: estimateTokens("Once upon a time"),
+		},
+		{
+			name:           "keeps usage reported by the provider",
+			reported:       types.Usage{PromptTokens: 12, CompletionTokens: 3},
+			wantPrompt:     12,
+			wantCompletion: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := make(chan *types.StreamChunk, 2)
+			state := newStreamState(context.Background(), chunks, "openai/gpt-4o-mini", messages)
+			state.sendContent("Once upon a time")
+			<-chunks
+
+			state.finishCancelled(tt.reported, cancelErr)
+			chunk := <-chunks
+
+			if !chunk.Done || !errors.Is(chunk.Error, cancelErr) {
+				t.Fatalf("Expected a final chunk carrying the cancellation error, got %+v", chunk)
+			}
+			if chunk.Usage.PromptTokens != tt.wantPrompt {
+				t.Errorf("PromptTokens = %d, want %d", chunk.Usage.PromptTokens, tt.wantPrompt)
+			}
+			if chunk.Usage.CompletionTokens != tt.wantCompletion {
+				t.Errorf("CompletionTokens = %d, want %d", chunk.Usage.CompletionTokens, tt.wantCompletion)
+			}
+			if chunk.Usage.TotalTokens != tt.wantPrompt+tt.wantCompletion {
+				t.Errorf("TotalTokens = %d, want %d", chunk.Usage.TotalTokens, tt.wantPrompt+tt.wantCompletion)
+			}
+		})
+	}
+}
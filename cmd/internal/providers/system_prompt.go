@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"llm-router/types"
+	"strings"
+)
+
+const systemPromptSeparator = "\n\n"
+
+// splitSystemPrompt separates system messages from the conversation for providers
+// that take the system prompt as a single top-level field (Anthropic, Gemini).
+//
+// Merge policy: every system message, wherever it appears in the conversation, is
+// merged into one prompt in the order it was sent, separated by a blank line.
+// Mid-conversation system messages therefore lose their position. If the request
+// has nothing but system messages, the merged prompt is sent as a user message instead,
+// since both providers need at least one conversation turn.
+func splitSystemPrompt(messages []types.Message) (string, []types.Message) {
+	var system []string
+	conversation := make([]types.Message, 0, len(messages))
+
+	for _, message := range messages {
+		if message.Role != "system" {
+			conversation = append(conversation, message)
+			continue
+		}
+		if content := strings.TrimSpace(message.Content); content != "" {
+			system = append(system, content)
+		}
+	}
+
+	prompt := strings.Join(system, systemPromptSeparator)
+
+	if len(conversation) == 0 && prompt != "" {
+		return "", []types.Message{{Role: "user", Content: prompt}}
+	}
+
+	return prompt, conversation
+}
@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"llm-router/types"
+	"reflect"
+	"testing"
+)
+
+func TestSplitSystemPrompt(t *testing.T) {
+	tests := []struct {
+		name             string
+		messages         []types.Message
+		wantSystem       string
+		wantConversation []types.Message
+	}{
+		{
+			name:             "no system messages",
+			messages:         []types.Message{{Role: "user", Content: "hi"}},
+			wantSystem:       "",
+			wantConversation: []types.Message{{Role: "user", Content: "hi"}},
+		},
+		{
+			name: "leading system message",
+			messages: []types.Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Content: "hi"},
+			},
+			wantSystem:       "Be brief.",
+			wantConversation: []types.Message{{Role: "user", Content: "hi"}},
+		},
+		{
+			name: "multiple and mid-conversation system messages are merged in order",
+			messages: []types.Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Content: "hi"},
+				{Role: "assistant", Content: "hello"},
+				{Role: "system", Content: " Answer in French. "},
+				{Role: "user", Content: "how are you?"},
+			},
+			wantSystem: "Be brief.\n\nAnswer in French.",
+			wantConversation: []types.Message{
+				{Role: "user", Content: "hi"},
+				{Role: "assistant", Content: "hello"},
+				{Role: "user", Content: "how are you?"},
+			},
+		},
+		{
+			name:             "only system messages become a user turn",
+			messages:         []types.Message{{Role: "system", Content: "Say hello."}},
+			wantSystem:       "",
+			wantConversation: []types.Message{{Role: "user", Content: "Say hello."}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			system, conversation := splitSystemPrompt(tt.messages)
+
+			if system != tt.wantSystem {
+				t.Errorf("system = %q, want %q", system, tt.wantSystem)
+			}
+			if !reflect.DeepEqual(conversation, tt.wantConversation) {
+				t.Errorf("conversation = %+v, want %+v", conversation, tt.wantConversation)
+			}
+		})
+	}
+}
+
+func TestProviders_SystemPromptMapping(t *testing.T) {
+	messages := []types.Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "hi"},
+	}
+
+	openAIMessages := (&OpenAIProvider{}).convertMessages(messages)
+	if len(openAIMessages) != 2 || openAIMessages[0].OfSystem == nil || openAIMessages[1].OfUser == nil {
+		t.Errorf("openai: expected a system message followed by a user message, got %+v", openAIMessages)
+	}
+
+	anthropicParams, err := (&AnthropicProvider{maxTokens: 1024}).buildParams(ModelInfo{UpstreamName: "claude-3-haiku-20240307"}, messages, types.SamplingParams{})
+	if err != nil {
+		t.Fatalf("anthropic: buildParams() error = %v", err)
+	}
+	if len(anthropicParams.System) != 1 || anthropicParams.System[0].Text != "Be brief." {
+		t.Errorf("anthropic: System = %+v, want the system prompt", anthropicParams.System)
+	}
+	if len(anthropicParams.Messages) != 1 {
+		t.Errorf("anthropic: expected only the user message, got %d messages", len(anthropicParams.Messages))
+	}
+
+	geminiConfig := (&GeminiProvider{}).buildConfig(ModelInfo{}, "Be brief.", types.SamplingParams{})
+	if geminiConfig.SystemInstruction == nil || geminiConfig.SystemInstruction.Parts[0].Text != "Be brief." {
+		t.Errorf("gemini: SystemInstruction = %+v, want the system prompt", geminiConfig.SystemInstruction)
+	}
+	if (&GeminiProvider{}).buildConfig(ModelInfo{}, "", types.SamplingParams{}).SystemInstruction != nil {
+		t.Error("gemini: SystemInstruction should be unset without a system prompt")
+	}
+}
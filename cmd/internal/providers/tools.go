@@ -0,0 +1,38 @@
+package providers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// parseToolArguments decodes a tool call's JSON-encoded arguments for providers that take them
+// as an object. Models occasionally produce invalid JSON; such arguments are sent as an empty object.
+func parseToolArguments(arguments string) map[string]any {
+	args := map[string]any{}
+	if arguments == "" {
+		return args
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return map[string]any{}
+	}
+	return args
+}
+
+// encodeToolArguments is the reverse of parseToolArguments, for providers that return arguments as an object.
+func encodeToolArguments(args map[string]any) string {
+	if args == nil {
+		return "{}"
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+func newToolCallID() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return "call_" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,160 @@
+package providers
+
+import (
+	"llm-router/types"
+	"reflect"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+	"google.golang.org/genai"
+)
+
+var weatherTool = types.Tool{
+	Type: "function",
+	Function: types.FunctionDefinition{
+		Name:        "get_weather",
+		Description: "Get the weather for a city",
+		Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"city": map[string]any{"type": "string"}},
+			"required":   []any{"city"},
+		},
+	},
+}
+
+// toolConversation is a completed tool round trip: the model called a tool and the client answered it.
+var toolConversation = []types.Message{
+	{Role: "user", Content: "Weather in Paris and Rome?"},
+	{Role: "assistant", ToolCalls: []types.ToolCall{
+		{ID: "call_1", Type: "function", Function: types.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+		{ID: "call_2", Type: "function", Function: types.FunctionCall{Name: "get_weather", Arguments: `{"city":"Rome"}`}},
+	}},
+	{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+	{Role: "tool", ToolCallID: "call_2", Content: "rainy"},
+}
+
+func TestParseToolArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		want      map[string]any
+	}{
+		{name: "object", arguments: `{"city":"Paris"}`, want: map[string]any{"city": "Paris"}},
+		{name: "empty", arguments: "", want: map[string]any{}},
+		{name: "invalid JSON", arguments: `{"city":`, want: map[string]any{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseToolArguments(tt.arguments); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseToolArguments(%q) = %v, want %v", tt.arguments, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAI_ToolMapping(t *testing.T) {
+	provider := &OpenAIProvider{}
+
+	var params openai.ChatCompletionNewParams
+	provider.applyTools(&params, []types.Tool{weatherTool}, &types.ToolChoice{Mode: types.ToolChoiceFunction, Function: "get_weather"})
+
+	if len(params.Tools) != 1 || params.Tools[0].GetFunction().Name != "get_weather" {
+		t.Errorf("Tools = %+v, want the get_weather function", params.Tools)
+	}
+	if params.ToolChoice.OfFunctionToolChoice == nil || params.ToolChoice.OfFunctionToolChoice.Function.Name != "get_weather" {
+		t.Errorf("ToolChoice = %+v, want a named function choice", params.ToolChoice)
+	}
+
+	messages := provider.convertMessages(toolConversation)
+	if len(messages) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(messages))
+	}
+	if assistant := messages[1].OfAssistant; assistant == nil || len(assistant.ToolCalls) != 2 {
+		t.Errorf("expected an assistant message with 2 tool calls, got %+v", messages[1])
+	}
+	if tool := messages[3].OfTool; tool == nil || tool.ToolCallID != "call_2" {
+		t.Errorf("expected a tool message answering call_2, got %+v", messages[3])
+	}
+}
+
+func TestAnthropic_ToolMapping(t *testing.T) {
+	provider := &AnthropicProvider{}
+
+	tests := []struct {
+		choice *types.ToolChoice
+		check  func(anthropic.ToolChoiceUnionParam) bool
+	}{
+		{&types.ToolChoice{Mode: types.ToolChoiceAuto}, func(c anthropic.ToolChoiceUnionParam) bool { return c.OfAuto != nil }},
+		{&types.ToolChoice{Mode: types.ToolChoiceNone}, func(c anthropic.ToolChoiceUnionParam) bool { return c.OfNone != nil }},
+		{&types.ToolChoice{Mode: types.ToolChoiceRequired}, func(c anthropic.ToolChoiceUnionParam) bool { return c.OfAny != nil }},
+		{&types.ToolChoice{Mode: types.ToolChoiceFunction, Function: "get_weather"}, func(c anthropic.ToolChoiceUnionParam) bool {
+			return c.OfTool != nil && c.OfTool.Name == "get_weather"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.choice.Mode, func(t *testing.T) {
+			var params anthropic.MessageNewParams
+			provider.applyTools(&params, []types.Tool{weatherTool}, tt.choice)
+
+			if !tt.check(params.ToolChoice) {
+				t.Errorf("ToolChoice = %+v, unexpected for mode %q", params.ToolChoice, tt.choice.Mode)
+			}
+			tool := params.Tools[0].OfTool
+			if tool == nil || tool.Name != "get_weather" || !reflect.DeepEqual(tool.InputSchema.Required, []string{"city"}) {
+				t.Errorf("Tools[0] = %+v, want get_weather requiring city", tool)
+			}
+		})
+	}
+
+	messages, err := provider.convertMessages(toolConversation)
+	if err != nil {
+		t.Fatalf("convertMessages() error = %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("expected tool results to share one user message, got %d messages", len(messages))
+	}
+	if len(messages[1].Content) != 2 || messages[1].Content[0].OfToolUse == nil {
+		t.Errorf("expected an assistant message with 2 tool_use blocks, got %+v", messages[1].Content)
+	}
+	results := messages[2].Content
+	if len(results) != 2 || results[0].OfToolResult == nil || results[1].OfToolResult.ToolUseID != "call_2" {
+		t.Errorf("expected 2 tool_result blocks, got %+v", results)
+	}
+}
+
+func TestGemini_ToolMapping(t *testing.T) {
+	provider := &GeminiProvider{}
+
+	config := &genai.GenerateContentConfig{}
+	provider.applyTools(config, []types.Tool{weatherTool}, &types.ToolChoice{Mode: types.ToolChoiceFunction, Function: "get_weather"})
+
+	if len(config.Tools) != 1 || len(config.Tools[0].FunctionDeclarations) != 1 || config.Tools[0].FunctionDeclarations[0].Name != "get_weather" {
+		t.Errorf("Tools = %+v, want the get_weather declaration", config.Tools)
+	}
+	calling := config.ToolConfig.FunctionCallingConfig
+	if calling.Mode != genai.FunctionCallingConfigModeAny || !reflect.DeepEqual(calling.AllowedFunctionNames, []string{"get_weather"}) {
+		t.Errorf("FunctionCallingConfig = %+v, want ANY restricted to get_weather", calling)
+	}
+
+	history, current := provider.convertMessages(toolConversation)
+	if len(history) != 2 {
+		t.Fatalf("expected 2 history contents, got %d", len(history))
+	}
+	if len(current) != 2 {
+		t.Fatalf("expected both tool results in the sent message, got %d parts", len(current))
+	}
+	response := current[1].FunctionResponse
+	if response == nil || response.ID != "call_2" || response.Name != "get_weather" || response.Response["output"] != "rainy" {
+		t.Errorf("FunctionResponse = %+v, want get_weather's result for call_2", response)
+	}
+
+	message := provider.convertToRouterMessage(&genai.Content{Parts: []*genai.Part{
+		{FunctionCall: &genai.FunctionCall{Name: "get_weather", Args: map[string]any{"city": "Paris"}}},
+	}})
+	if len(message.ToolCalls) != 1 || message.ToolCalls[0].ID == "" || message.ToolCalls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("ToolCalls = %+v, want one get_weather call with a generated ID", message.ToolCalls)
+	}
+}
@@ -1,7 +1,9 @@
 package resilience
 
 import (
+	"errors"
 	"llm-router/cmd/internal/metrics"
+	providererrors "llm-router/cmd/internal/provider_errors"
 	"llm-router/types"
 	"sync"
 	"time"
@@ -62,6 +64,19 @@ func (c *Circuit) Execute(err error) {
 
 }
 
+// RecordResult reports an attempt's outcome to the provider's circuit breaker. Errors caused by
+// the request rather than the provider, i.e. parameters the provider rejects and responses that
+// don't match the request's response_format, say nothing about the provider's health and aren't
+// counted, so a few bad requests can't open the circuit for everyone.
+func RecordResult(circuitBreaker types.CircuitBreaker, err error) {
+	var providerErr *providererrors.ProviderError
+	if errors.As(err, &providerErr) &&
+		(providerErr.Type == providererrors.ErrorTypeValidation || providerErr.Type == providererrors.ErrorTypeInvalidResponse) {
+		return
+	}
+	circuitBreaker.Execute(err)
+}
+
 func (c *Circuit) resetState() {
 	c.FailureCount = 0
 	metrics.CircuitBreakerState.WithLabelValues(c.Provider).Set(0)
@@ -0,0 +1,31 @@
+package resilience
+
+import (
+	"errors"
+	providererrors "llm-router/cmd/internal/provider_errors"
+	"testing"
+)
+
+func TestRecordResult_RequestErrors(t *testing.T) {
+	circuit := NewCircuitBreakers([]string{"anthropic"}, map[string]int{"failureThreshold": 3})["anthropic"]
+
+	requestErrors := []error{
+		providererrors.NewValidationError("anthropic", "frequency_penalty is not supported by anthropic", nil),
+		providererrors.NewInvalidResponseError("anthropic", "response does not match response_format", nil),
+	}
+	for i := 0; i < 10; i++ {
+		for _, err := range requestErrors {
+			RecordResult(circuit, err)
+		}
+	}
+	if state := circuit.GetState(); state != "CLOSED" {
+		t.Fatalf("circuit is %s after repeated request errors, want CLOSED", state)
+	}
+
+	for i := 0; i < 3; i++ {
+		RecordResult(circuit, providererrors.NewServerError("anthropic", 503, errors.New("overloaded")))
+	}
+	if state := circuit.GetState(); state != "OPEN" {
+		t.Errorf("circuit is %s after repeated server errors, want OPEN", state)
+	}
+}
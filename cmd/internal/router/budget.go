@@ -3,7 +3,9 @@ package router
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -13,6 +15,8 @@ type BudgetManager interface {
 	TrackUsage(provider string, cost float64)
 	IsWithinBudget(provider string) bool
 	GetUsage(provider string) float64
+	// GetRemaining returns what is left of the provider's budget, and false when it has no budget
+	GetRemaining(provider string) (float64, bool)
 	ResetUsage(provider string)
 }
 
@@ -68,6 +72,17 @@ func (bm *InMemoryBudgetManager) GetUsage(provider string) float64 {
 	return bm.usage[provider]
 }
 
+func (bm *InMemoryBudgetManager) GetRemaining(provider string) (float64, bool) {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+
+	limit, exists := bm.limits[provider]
+	if !exists {
+		return 0, false
+	}
+	return limit - bm.usage[provider], true
+}
+
 func (bm *InMemoryBudgetManager) ResetUsage(provider string) {
 	bm.mu.Lock()
 	defer bm.mu.Unlock()
@@ -75,28 +90,54 @@ func (bm *InMemoryBudgetManager) ResetUsage(provider string) {
 }
 
 type RedisBudgetManager struct {
-	client *redis.Client
-	limits map[string]float64
-	logger *zap.Logger
-	ctx    context.Context
+	client    *redis.Client
+	namespace string
+	limits    map[string]float64
+	logger    *zap.Logger
+	ctx       context.Context
 }
 
-func NewRedisBudgetManager(client *redis.Client, limits map[string]float64, logger *zap.Logger) BudgetManager {
+// ConsumerBudgetPrefix starts the keys tracking consumers' daily spend, consumer:<name>:<YYYY-MM-DD>.
+const ConsumerBudgetPrefix = "consumer:"
+
+// consumerBudgetRetention is how long a consumer's daily spend is kept in Redis after its last request.
+const consumerBudgetRetention = 48 * time.Hour
+
+// NewRedisBudgetManager tracks budgets in Redis. Keys are prefixed with namespace (if any),
+// so tenants sharing a Redis server never see each other's usage.
+func NewRedisBudgetManager(client *redis.Client, namespace string, limits map[string]float64, logger *zap.Logger) BudgetManager {
 	return &RedisBudgetManager{
-		client: client,
-		limits: limits,
-		logger: logger,
-		ctx:    context.Background(),
+		client:    client,
+		namespace: namespace,
+		limits:    limits,
+		logger:    logger,
+		ctx:       context.Background(),
 	}
 }
 
 func (bm *RedisBudgetManager) getRedisKey(provider string) string {
-	return fmt.Sprintf("budget:total:%s", provider)
+	return namespacedKey(bm.namespace, fmt.Sprintf("budget:total:%s", provider))
+}
+
+// namespacedKey prefixes a Redis key with a namespace, leaving it unchanged without one.
+func namespacedKey(namespace string, key string) string {
+	if namespace == "" {
+		return key
+	}
+	return namespace + ":" + key
 }
 
 func (bm *RedisBudgetManager) TrackUsage(provider string, cost float64) {
 	key := bm.getRedisKey(provider)
-	newUsage, err := bm.client.IncrByFloat(bm.ctx, key, cost).Result()
+
+	pipe := bm.client.TxPipeline()
+	incr := pipe.IncrByFloat(bm.ctx, key, cost)
+	// A consumer's key only counts one day's spend, so it can go once the day is over
+	if strings.HasPrefix(provider, ConsumerBudgetPrefix) {
+		pipe.Expire(bm.ctx, key, consumerBudgetRetention)
+	}
+	_, err := pipe.Exec(bm.ctx)
+	newUsage := incr.Val()
 	if err != nil {
 		bm.logger.Error("Failed to track usage in Redis", zap.Error(err), zap.String("provider", provider))
 		return
@@ -134,6 +175,14 @@ func (bm *RedisBudgetManager) GetUsage(provider string) float64 {
 	return val
 }
 
+func (bm *RedisBudgetManager) GetRemaining(provider string) (float64, bool) {
+	limit, exists := bm.limits[provider]
+	if !exists {
+		return 0, false
+	}
+	return limit - bm.GetUsage(provider), true
+}
+
 func (bm *RedisBudgetManager) ResetUsage(provider string) {
 	key := bm.getRedisKey(provider)
 	bm.client.Del(bm.ctx, key)
@@ -0,0 +1,111 @@
+package router_test
+
+import (
+	"context"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/cmd/internal/router/filters"
+	"llm-router/types"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// countingProvider reports a fixed prompt size so the cost router can compare model prices.
+type countingProvider struct {
+	MockProvider
+}
+
+func (p *countingProvider) CountTokens(ctx context.Context, messages []types.Message) (int, error) {
+	return 1000, nil
+}
+
+func setUpCapabilityCatalog(t *testing.T) (*countingProvider, *countingProvider) {
+	t.Helper()
+
+	providers.InitializeModelRegistry([]types.ModelConfig{
+		{ID: "plain-ai/cheap", Provider: "plain-ai", Name: "cheap", InputCostPer1M: 0.1, OutputCostPer1M: 0.1, Tier: "budget", Capabilities: []string{"chat"}},
+		{ID: "tool-ai/cheap", Provider: "tool-ai", Name: "cheap", InputCostPer1M: 0.2, OutputCostPer1M: 0.2, Tier: "budget", Capabilities: []string{"chat"}},
+		{ID: "tool-ai/capable", Provider: "tool-ai", Name: "capable", InputCostPer1M: 1, OutputCostPer1M: 1, Tier: "standard", Capabilities: []string{"chat", types.CapabilityTools}},
+	}, nil)
+	t.Cleanup(func() {
+		providers.InitializeModelRegistry(providers.GetDefaultCatalog(), nil)
+	})
+
+	return &countingProvider{MockProvider{name: "plain-ai"}}, &countingProvider{MockProvider{name: "tool-ai"}}
+}
+
+func TestCapabilityFilter(t *testing.T) {
+	plain, tool := setUpCapabilityCatalog(t)
+	filter := filters.NewCapabilityFilter(zap.NewNop())
+
+	out, err := filter.Filter(context.Background(), &types.FilterInput{
+		Candidates:   []types.Provider{plain, tool},
+		Capabilities: []string{types.CapabilityTools},
+	})
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	if len(out.Candidates) != 1 || out.Candidates[0] != tool {
+		t.Errorf("expected only tool-ai to remain, got %d candidates", len(out.Candidates))
+	}
+
+	out, err = filter.Filter(context.Background(), &types.FilterInput{
+		Candidates: []types.Provider{plain, tool},
+	})
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	if len(out.Candidates) != 2 {
+		t.Errorf("expected no filtering without required capabilities, got %d candidates", len(out.Candidates))
+	}
+
+	// Callers limited to certain models keep only the providers serving one of them
+	out, err = filter.Filter(context.Background(), &types.FilterInput{
+		Candidates: []types.Provider{plain, tool},
+		Models:     []string{"plain-ai/cheap"},
+	})
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	if len(out.Candidates) != 1 || out.Candidates[0] != plain {
+		t.Errorf("expected only plain-ai to remain, got %d candidates", len(out.Candidates))
+	}
+}
+
+func TestCostRouter_RequiredCapabilities(t *testing.T) {
+	plain, tool := setUpCapabilityCatalog(t)
+
+	manager := providers.NewProviderManager(providers.NewProviderFactory())
+	manager.SetProviders([]types.Provider{plain, tool})
+
+	costRouter, err := router.NewCostRouter(manager, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create cost router: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		capabilities []string
+		wantModel    string
+	}{
+		{name: "cheapest model overall", wantModel: "plain-ai/cheap"},
+		{name: "cheapest model with tools", capabilities: []string{types.CapabilityTools}, wantModel: "tool-ai/capable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := costRouter.SelectProvider(context.Background(), &types.SelectProviderInput{
+				Messages:     []types.Message{{Role: "user", Content: "hi"}},
+				Circuits:     map[string]types.CircuitBreaker{},
+				Capabilities: tt.capabilities,
+			})
+			if err != nil {
+				t.Fatalf("SelectProvider() error = %v", err)
+			}
+			if out.Model != tt.wantModel {
+				t.Errorf("Model = %s, want %s", out.Model, tt.wantModel)
+			}
+		})
+	}
+}
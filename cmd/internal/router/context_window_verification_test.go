@@ -0,0 +1,119 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/cmd/internal/router/filters"
+	"llm-router/types"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// lengthProvider counts one token per character, so tests control prompt sizes exactly.
+type lengthProvider struct {
+	MockProvider
+}
+
+func (p *lengthProvider) CountTokens(ctx context.Context, messages []types.Message) (int, error) {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += len(msg.Content)
+	}
+	return tokens, nil
+}
+
+func setUpContextCatalog(t *testing.T) (*lengthProvider, *lengthProvider) {
+	t.Helper()
+
+	providers.InitializeModelRegistry([]types.ModelConfig{
+		{ID: "small-ai/mini", Provider: "small-ai", Name: "mini", InputCostPer1M: 0.1, OutputCostPer1M: 0.1, ContextWindow: 16000, Tier: "budget", Capabilities: []string{"chat"}},
+		{ID: "large-ai/mini", Provider: "large-ai", Name: "mini", InputCostPer1M: 0.2, OutputCostPer1M: 0.2, ContextWindow: 16000, Tier: "budget", Capabilities: []string{"chat"}},
+		{ID: "large-ai/long", Provider: "large-ai", Name: "long", InputCostPer1M: 1, OutputCostPer1M: 1, ContextWindow: 200000, Tier: "budget", Capabilities: []string{"chat"}},
+	}, nil)
+	t.Cleanup(func() {
+		providers.InitializeModelRegistry(providers.GetDefaultCatalog(), nil)
+	})
+
+	return &lengthProvider{MockProvider{name: "small-ai"}}, &lengthProvider{MockProvider{name: "large-ai"}}
+}
+
+func prompt(tokens int) []types.Message {
+	return []types.Message{{Role: "user", Content: strings.Repeat("a", tokens)}}
+}
+
+func TestContextWindowFilter(t *testing.T) {
+	small, large := setUpContextCatalog(t)
+	filter := filters.NewContextWindowFilter(zap.NewNop())
+
+	tests := []struct {
+		name      string
+		tokens    int
+		maxTokens int
+		want      int
+	}{
+		{name: "fits everywhere", tokens: 1000, want: 2},
+		{name: "long prompt", tokens: 50000, want: 1},
+		{name: "prompt plus max_tokens", tokens: 10000, maxTokens: 8000, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := filter.Filter(context.Background(), &types.FilterInput{
+				Candidates: []types.Provider{small, large},
+				Tokens:     types.NewRequestTokens(prompt(tt.tokens), tt.maxTokens),
+			})
+			if err != nil {
+				t.Fatalf("Filter() error = %v", err)
+			}
+			if len(out.Candidates) != tt.want {
+				t.Errorf("Filter() kept %d candidates, want %d", len(out.Candidates), tt.want)
+			}
+		})
+	}
+
+	_, err := filter.Filter(context.Background(), &types.FilterInput{
+		Candidates: []types.Provider{small, large},
+		Tokens:     types.NewRequestTokens(prompt(300000), 0),
+	})
+	var tooLong *types.ContextWindowError
+	if !errors.As(err, &tooLong) || tooLong.ContextWindow != 200000 || tooLong.PromptTokens != 300000 {
+		t.Errorf("Filter() error = %v, want a ContextWindowError reporting a 200000 token window", err)
+	}
+}
+
+func TestContextWindow_ModelSelection(t *testing.T) {
+	small, large := setUpContextCatalog(t)
+
+	manager := providers.NewProviderManager(providers.NewProviderFactory())
+	manager.SetProviders([]types.Provider{small, large})
+
+	base, err := router.NewCostRouter(manager, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCostRouter() error = %v", err)
+	}
+	pipeline := router.NewPipelineRouter(base, manager, nil, nil, nil)
+	pipeline.AddFilter(filters.NewContextWindowFilter(zap.NewNop()))
+
+	// The cost router skips the cheaper models that can't fit the prompt
+	out, err := pipeline.SelectProvider(context.Background(), &types.SelectProviderInput{Messages: prompt(50000)})
+	if err != nil {
+		t.Fatalf("SelectProvider() error = %v", err)
+	}
+	if out.Model != "large-ai/long" {
+		t.Errorf("SelectProvider() model = %s, want large-ai/long", out.Model)
+	}
+
+	// A pinned model too small for the request is refused rather than sent upstream
+	_, err = pipeline.SelectProvider(context.Background(), &types.SelectProviderInput{
+		Messages: prompt(50000),
+		Model:    "large-ai/mini",
+	})
+	var tooLong *types.ContextWindowError
+	if !errors.As(err, &tooLong) {
+		t.Errorf("SelectProvider() error = %v, want a ContextWindowError", err)
+	}
+}
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"context"
+	"llm-router/cmd/internal/providers"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultOutputPercentile = 90
+	// minOutputSamples is how many responses of a model are needed before its output lengths are trusted
+	minOutputSamples = 20
+	// estimatorRefreshInterval is how often output lengths are re-read from the usage history
+	estimatorRefreshInterval = time.Minute
+)
+
+// CostEstimator predicts what a request will cost on a model. The prompt is counted; the output is
+// estimated from a percentile of the model's past output lengths, capped by the request's
+// max_tokens. Models with too few recorded responses fall back to max_tokens, and without it the
+// output is assumed to be as long as the prompt.
+// Output lengths are read from the usage history in the background, at most once per minute.
+type CostEstimator struct {
+	history    UsageHistoryManager
+	percentile float64
+
+	mu         sync.Mutex
+	histograms map[string]TokenHistogram
+	loadedAt   time.Time
+	loading    bool
+}
+
+// NewCostEstimator estimates costs using the given percentile (1-100, 0 for the default of 90)
+// of the output lengths in history, which may be nil.
+func NewCostEstimator(history UsageHistoryManager, percentile float64) *CostEstimator {
+	if percentile <= 0 {
+		percentile = defaultOutputPercentile
+	}
+	return &CostEstimator{history: history, percentile: percentile}
+}
+
+// OutputTokens estimates how many tokens the model will generate for a request.
+func (e *CostEstimator) OutputTokens(modelID string, promptTokens, maxTokens int) int {
+	if histogram, ok := e.histogram(modelID); ok && histogram.Samples() >= minOutputSamples {
+		tokens := histogram.Percentile(e.percentile)
+		if maxTokens > 0 {
+			tokens = min(tokens, maxTokens)
+		}
+		return tokens
+	}
+
+	if maxTokens > 0 {
+		return maxTokens
+	}
+	return promptTokens
+}
+
+// Estimate returns the expected cost in USD of a request on the model.
+func (e *CostEstimator) Estimate(modelID string, promptTokens, maxTokens int) (float64, error) {
+	return providers.CalculateCost(modelID, promptTokens, e.OutputTokens(modelID, promptTokens, maxTokens))
+}
+
+// Refresh re-reads output lengths from the usage history.
+func (e *CostEstimator) Refresh(ctx context.Context) error {
+	histograms, err := e.history.GetOutputTokenHistograms(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.loading = false
+	e.loadedAt = time.Now()
+	if err != nil {
+		return err
+	}
+	e.histograms = histograms
+	return nil
+}
+
+// histogram returns the model's output lengths, starting a refresh if they are stale.
+func (e *CostEstimator) histogram(modelID string) (TokenHistogram, bool) {
+	if e == nil || e.history == nil {
+		return nil, false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.loading && time.Since(e.loadedAt) > estimatorRefreshInterval {
+		e.loading = true
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := e.Refresh(ctx); err != nil {
+				logger.Warn("Failed to load output lengths for cost estimates", zap.Error(err))
+			}
+		}()
+	}
+
+	histogram, ok := e.histograms[modelID]
+	return histogram, ok
+}
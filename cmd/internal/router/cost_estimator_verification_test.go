@@ -0,0 +1,104 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/cmd/internal/router/filters"
+	"llm-router/types"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestCostEstimator_OutputTokens(t *testing.T) {
+	ctx := context.Background()
+	history := router.NewInMemoryUsageHistoryManager()
+
+	// 27 short answers and 3 long ones
+	for i := 0; i < 30; i++ {
+		outputTokens := 100
+		if i%10 == 0 {
+			outputTokens = 2000
+		}
+		history.RecordUsage(ctx, "chatty-ai", router.UsageTags{Model: "chatty-ai/model"}, 0, 50, outputTokens)
+	}
+	for i := 0; i < 5; i++ {
+		history.RecordUsage(ctx, "new-ai", router.UsageTags{Model: "new-ai/model"}, 0, 50, 100)
+	}
+
+	estimator := router.NewCostEstimator(history, 90)
+	if err := estimator.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		model     string
+		maxTokens int
+		want      int
+	}{
+		{name: "percentile of past outputs", model: "chatty-ai/model", want: 128},
+		{name: "capped by max_tokens", model: "chatty-ai/model", maxTokens: 50, want: 50},
+		{name: "too few samples uses max_tokens", model: "new-ai/model", maxTokens: 500, want: 500},
+		{name: "no history or max_tokens uses the prompt", model: "unknown-ai/model", want: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimator.OutputTokens(tt.model, 1000, tt.maxTokens); got != tt.want {
+				t.Errorf("OutputTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := router.NewCostEstimator(history, 95).OutputTokens("chatty-ai/model", 1000, 0); got != 1000 {
+		t.Errorf("OutputTokens() before the history is loaded = %d, want the prompt's 1000", got)
+	}
+}
+
+func TestPipelineRouter_CostCeiling(t *testing.T) {
+	small, large := setUpContextCatalog(t)
+
+	manager := providers.NewProviderManager(providers.NewProviderFactory())
+	manager.SetProviders([]types.Provider{small, large})
+
+	base, err := router.NewRoundRobinRouter(manager, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRoundRobinRouter() error = %v", err)
+	}
+	pipeline := router.NewPipelineRouter(base, manager, nil, nil, nil)
+	pipeline.AddFilter(filters.NewCapabilityFilter(zap.NewNop()))
+	pipeline.SetDefaultModels(map[string]string{"small-ai": "small-ai/mini", "large-ai": "large-ai/long"})
+
+	// 1000 prompt tokens and up to 1000 output tokens: $0.0002 on small-ai/mini, $0.002 on large-ai/long
+	selectWithin := func(maxCost float64, model string) (*types.SelectedProviderOutput, error) {
+		return pipeline.SelectProvider(context.Background(), &types.SelectProviderInput{
+			Messages: prompt(1000),
+			Tokens:   types.NewRequestTokens(prompt(1000), 1000),
+			Model:    model,
+			MaxCost:  maxCost,
+		})
+	}
+
+	for i := 0; i < 4; i++ {
+		out, err := selectWithin(0.001, "")
+		if err != nil {
+			t.Fatalf("SelectProvider() error = %v", err)
+		}
+		if out.Model == "large-ai/long" || out.EstimatedCost > 0.001 {
+			t.Errorf("SelectProvider() = %s at $%f, want a model under the $0.001 ceiling", out.Model, out.EstimatedCost)
+		}
+	}
+
+	var tooExpensive *types.CostCeilingError
+	if _, err := selectWithin(0.0001, ""); !errors.As(err, &tooExpensive) || tooExpensive.Cheapest != 0.0002 {
+		t.Errorf("SelectProvider() error = %v, want a CostCeilingError with the cheapest at $0.0002", err)
+	}
+
+	// A pinned model is refused rather than replaced
+	if _, err := selectWithin(0.001, "large-ai/long"); !errors.As(err, &tooExpensive) {
+		t.Errorf("SelectProvider() error = %v, want a CostCeilingError for the pinned model", err)
+	}
+}
@@ -17,6 +17,7 @@ type CostRouter struct {
 	budgetManager    BudgetManager
 	rateLimitManager RateLimitManager
 	usageHistory     UsageHistoryManager
+	estimator        *CostEstimator
 	mu               sync.RWMutex
 }
 
@@ -62,9 +63,14 @@ func NewCostRouter(providerManager *providers.ProviderManager, costOptions *type
 		budgetManager:    budget,
 		rateLimitManager: rateLimit,
 		usageHistory:     history,
+		estimator:        NewCostEstimator(history, costOptions.OutputPercentile),
 	}, nil
 }
 
+// SelectProvider picks the cheapest model for the request. Which tiers it may come from depends on
+// the tier strategy: same-tier only uses the requested (or default) tier, allow-downgrade moves
+// down one tier at a time, to no lower than the minimum tier, while the requested tier has no
+// available model, and cheapest ignores tiers entirely, including the minimum tier.
 func (c *CostRouter) SelectProvider(ctx context.Context, deps *types.SelectProviderInput) (*types.SelectedProviderOutput, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -74,7 +80,28 @@ func (c *CostRouter) SelectProvider(ctx context.Context, deps *types.SelectProvi
 		tierConstraint = c.costOptions.DefaultTier
 	}
 
-	cheapestModel, cheapestProvider, err := c.findCheapestModel(ctx, deps.Messages, deps.Circuits, tierConstraint)
+	tiers := []string{tierConstraint}
+	switch c.costOptions.TierStrategy {
+	case "cheapest":
+		tiers = []string{""}
+	case "allow-downgrade":
+		if tierConstraint != "" {
+			tiers = downgradeTiers(tierConstraint, c.costOptions.MinimumTier)
+		}
+	}
+
+	var cheapestModel providers.ModelInfo
+	var cheapestProvider types.Provider
+	var cost float64
+	var err error
+
+	for _, tier := range tiers {
+		cheapestModel, cheapestProvider, cost, err = c.findCheapestModel(ctx, deps, tier)
+		if err == nil {
+			break
+		}
+		logger.Debug("No model available in tier", zap.String("tier", tier), zap.Error(err))
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -84,23 +111,49 @@ func (c *CostRouter) SelectProvider(ctx context.Context, deps *types.SelectProvi
 		zap.String("model", cheapestModel.ID),
 		zap.String("tier", string(cheapestModel.Tier)),
 		zap.String("requested_tier", tierConstraint),
+		zap.String("tier_strategy", c.costOptions.TierStrategy),
 		zap.Float64("input_cost_per_1m", cheapestModel.InputCostPer1M),
 		zap.Float64("output_cost_per_1m", cheapestModel.OutputCostPer1M),
+		zap.Float64("estimated_cost", cost),
 	)
 
 	return &types.SelectedProviderOutput{
-		Provider: cheapestProvider,
-		Model:    cheapestModel.ID,
+		Provider:      cheapestProvider,
+		Model:         cheapestModel.ID,
+		EstimatedCost: cost,
+		Tier:          string(cheapestModel.Tier),
 	}, nil
 }
 
+// downgradeTiers returns tier followed by each lower tier, down to minimumTier (if any).
+func downgradeTiers(tier string, minimumTier string) []string {
+	tiers := []string{tier}
+	for _, lower := range []providers.ModelTier{providers.TierPremium, providers.TierStandard, providers.TierBudget} {
+		if tierLevels[lower] < tierLevels[providers.ModelTier(tier)] && tierLevels[lower] >= tierLevels[providers.ModelTier(minimumTier)] {
+			tiers = append(tiers, string(lower))
+		}
+	}
+	return tiers
+}
+
+// findCheapestModel returns the model with the lowest estimated cost for the request, and that
+// cost. Models estimated to cost more than their provider's remaining budget are skipped.
 func (c *CostRouter) findCheapestModel(
 	ctx context.Context,
-	messages []types.Message,
-	circuits map[string]types.CircuitBreaker,
+	deps *types.SelectProviderInput,
 	tierConstraint string,
-) (providers.ModelInfo, types.Provider, error) {
-	allProviders := c.providerManager.GetProviders()
+) (providers.ModelInfo, types.Provider, float64, error) {
+	circuits := deps.Circuits
+
+	requestTokens := deps.Tokens
+	if requestTokens == nil {
+		requestTokens = types.NewRequestTokens(deps.Messages, 0)
+	}
+
+	allProviders := deps.Candidates
+	if len(allProviders) == 0 {
+		allProviders = c.providerManager.GetProviders()
+	}
 
 	var cheapestModel providers.ModelInfo
 	var selectedProvider types.Provider
@@ -118,7 +171,7 @@ func (c *CostRouter) findCheapestModel(
 			}
 		}
 
-		tokens, err := provider.CountTokens(ctx, messages)
+		tokens, err := requestTokens.Prompt(ctx, provider)
 		if err != nil {
 			logger.Warn("Failed to count tokens for provider",
 				zap.String("provider", providerName),
@@ -141,20 +194,39 @@ func (c *CostRouter) findCheapestModel(
 		} else {
 
 			allModels := providers.ListModelsByProvider(providerName)
-			if c.costOptions.MinimumTier != "" {
+			// The cheapest strategy ignores tiers entirely, including the minimum tier
+			if c.costOptions.MinimumTier != "" && c.costOptions.TierStrategy != "cheapest" {
 				modelsToCheck = c.filterByMinimumTier(allModels, providers.ModelTier(c.costOptions.MinimumTier))
 			} else {
 				modelsToCheck = allModels
 			}
 		}
 
+		modelsToCheck = providers.FilterModelsByCapabilities(modelsToCheck, deps.Capabilities)
+		modelsToCheck = providers.FilterAllowedModels(modelsToCheck, deps.Models)
+		modelsToCheck = providers.FilterModelsByContext(ctx, modelsToCheck, provider, requestTokens)
+
+		remaining, hasBudget := 0.0, false
+		if c.budgetManager != nil && len(modelsToCheck) > 0 {
+			remaining, hasBudget = c.budgetManager.GetRemaining(providerName)
+		}
+
 		for _, model := range modelsToCheck {
 
-			cost, err := providers.CalculateCost(model.ID, tokens, tokens)
+			cost, err := c.estimator.Estimate(model.ID, tokens, requestTokens.MaxTokens())
 			if err != nil {
 				continue
 			}
 
+			if hasBudget && cost > remaining {
+				logger.Debug("Skipping model that would exceed its provider's remaining budget",
+					zap.String("model", model.ID),
+					zap.Float64("estimated_cost", cost),
+					zap.Float64("remaining_budget", remaining),
+				)
+				continue
+			}
+
 			if cost < cheapestPrice {
 				cheapestPrice = cost
 				cheapestModel = model
@@ -165,27 +237,28 @@ func (c *CostRouter) findCheapestModel(
 
 	if selectedProvider == nil {
 		if tierConstraint != "" {
-			return providers.ModelInfo{}, nil, fmt.Errorf("no available providers in tier: %s", tierConstraint)
+			return providers.ModelInfo{}, nil, 0, fmt.Errorf("no available providers in tier: %s", tierConstraint)
 		}
-		return providers.ModelInfo{}, nil, fmt.Errorf("no available providers")
+		return providers.ModelInfo{}, nil, 0, fmt.Errorf("no available providers")
 	}
 
-	return cheapestModel, selectedProvider, nil
+	return cheapestModel, selectedProvider, cheapestPrice, nil
 }
 
-func (c *CostRouter) filterByMinimumTier(models []providers.ModelInfo, minimumTier providers.ModelTier) []providers.ModelInfo {
-	tierOrder := map[providers.ModelTier]int{
-		providers.TierBudget:       1,
-		providers.TierStandard:     2,
-		providers.TierPremium:      3,
-		providers.TierUltraPremium: 4,
-	}
+// tierLevels ranks the tiers from the cheapest up; unknown tiers rank 0.
+var tierLevels = map[providers.ModelTier]int{
+	providers.TierBudget:       1,
+	providers.TierStandard:     2,
+	providers.TierPremium:      3,
+	providers.TierUltraPremium: 4,
+}
 
-	minTierLevel := tierOrder[minimumTier]
+func (c *CostRouter) filterByMinimumTier(models []providers.ModelInfo, minimumTier providers.ModelTier) []providers.ModelInfo {
+	minTierLevel := tierLevels[minimumTier]
 	var filtered []providers.ModelInfo
 
 	for _, model := range models {
-		if tierOrder[model.Tier] >= minTierLevel {
+		if tierLevels[model.Tier] >= minTierLevel {
 			filtered = append(filtered, model)
 		}
 	}
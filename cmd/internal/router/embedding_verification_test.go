@@ -0,0 +1,98 @@
+package router_test
+
+import (
+	"context"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/types"
+	"testing"
+)
+
+// embeddingProvider is a mock provider that can also embed text.
+type embeddingProvider struct {
+	MockProvider
+}
+
+func (p *embeddingProvider) Embed(ctx context.Context, input *types.EmbeddingInput) (*types.EmbeddingResponse, error) {
+	return &types.EmbeddingResponse{Embeddings: make([][]float32, len(input.Input)), Model: input.Model}, nil
+}
+
+func TestPipelineRouter_SelectEmbeddingProvider(t *testing.T) {
+	providers.InitializeModelRegistry([]types.ModelConfig{
+		{ID: "embed-a/chat", Provider: "embed-a", Name: "chat", InputCostPer1M: 0.01, OutputCostPer1M: 0.01, Tier: "budget"},
+		{ID: "embed-a/small", Provider: "embed-a", Name: "small", Type: types.ModelTypeEmbedding, InputCostPer1M: 0.02, Dimensions: 1536},
+		{ID: "embed-b/large", Provider: "embed-b", Name: "large", Type: types.ModelTypeEmbedding, InputCostPer1M: 0.13, Dimensions: 3072},
+		{ID: "chat-only/embed", Provider: "chat-only", Name: "embed", Type: types.ModelTypeEmbedding, InputCostPer1M: 0.001, Dimensions: 3072},
+	}, nil)
+	t.Cleanup(func() {
+		providers.InitializeModelRegistry(providers.GetDefaultCatalog(), nil)
+	})
+
+	embedA := &embeddingProvider{MockProvider{name: "embed-a"}}
+	embedB := &embeddingProvider{MockProvider{name: "embed-b"}}
+	// Has a catalog embedding model but can't embed, so it is never selected
+	chatOnly := &MockProvider{name: "chat-only"}
+
+	manager := providers.NewProviderManager(providers.NewProviderFactory())
+	manager.SetProviders([]types.Provider{embedA, embedB, chatOnly})
+
+	base, err := router.NewRoundRobinRouter(manager, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create round-robin router: %v", err)
+	}
+	tests := []struct {
+		name       string
+		model      string
+		dimensions int
+		exclude    string
+		wantModel  string
+		wantErr    bool
+	}{
+		{name: "cheapest embedding model", wantModel: "embed-a/small"},
+		{name: "dimensions rule out smaller models", dimensions: 2048, wantModel: "embed-b/large"},
+		{name: "pinned model", model: "embed-b/large", wantModel: "embed-b/large"},
+		{name: "filters still apply", exclude: "embed-a", wantModel: "embed-b/large"},
+		{name: "pinned provider filtered out", model: "embed-a/small", exclude: "embed-a", wantErr: true},
+		{name: "no model is large enough", dimensions: 4096, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := router.NewPipelineRouter(base, manager, nil, nil, nil)
+			if tt.exclude != "" {
+				pipeline.AddFilter(&excludeFilter{exclude: map[string]bool{tt.exclude: true}})
+			}
+
+			out, err := pipeline.SelectEmbeddingProvider(context.Background(), &types.SelectProviderInput{
+				Circuits:   map[string]types.CircuitBreaker{},
+				Model:      tt.model,
+				Dimensions: tt.dimensions,
+			})
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got model %s", out.Model)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectEmbeddingProvider() error = %v", err)
+			}
+			if out.Model != tt.wantModel {
+				t.Errorf("model = %s, want %s", out.Model, tt.wantModel)
+			}
+			for _, candidate := range out.Candidates {
+				if candidate.GetProviderName() == "chat-only" {
+					t.Error("provider without embedding support was a candidate")
+				}
+			}
+		})
+	}
+
+	// Embedding models are never offered to chat routing
+	for _, model := range providers.ListModelsByProvider("embed-a") {
+		if model.IsEmbedding() {
+			t.Errorf("ListModelsByProvider returned embedding model %s", model.ID)
+		}
+	}
+}
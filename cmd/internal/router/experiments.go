@@ -0,0 +1,102 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"llm-router/types"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Experiment assigns requests to its variants by hashing the experiment name with a sticky key,
+// so a key keeps its variant for as long as the experiment's traffic and variants don't change.
+type Experiment struct {
+	name      string
+	traffic   float64
+	stickyKey string
+	variants  []experimentVariant // Sorted by name so assignment doesn't depend on map order
+	weights   int
+}
+
+type experimentVariant struct {
+	name string
+	types.ExperimentVariant
+}
+
+// NewExperiments builds the enabled experiments, in config order.
+func NewExperiments(data []types.ExperimentData) []*Experiment {
+	var experiments []*Experiment
+
+	for _, d := range data {
+		if !d.Enabled {
+			continue
+		}
+
+		experiment := &Experiment{name: d.Name, traffic: d.Traffic, stickyKey: d.StickyKey}
+		for name, variant := range d.Variants {
+			if variant.Weight == 0 {
+				variant.Weight = 1
+			}
+			experiment.variants = append(experiment.variants, experimentVariant{name: name, ExperimentVariant: variant})
+			experiment.weights += variant.Weight
+		}
+		sort.Slice(experiment.variants, func(i, j int) bool {
+			return experiment.variants[i].name < experiment.variants[j].name
+		})
+
+		experiments = append(experiments, experiment)
+	}
+
+	return experiments
+}
+
+func (e *Experiment) Name() string {
+	return e.name
+}
+
+// Assign returns the variant the request belongs to, and false when it isn't in the experiment:
+// either its sticky key falls outside the experiment's share of traffic, or the request has no
+// value for it.
+func (e *Experiment) Assign(keys types.StickyKeys) (string, types.ExperimentVariant, bool) {
+	value := e.stickyValue(keys)
+	if value == "" {
+		return "", types.ExperimentVariant{}, false
+	}
+
+	sum := sha256.Sum256([]byte(e.name + ":" + value))
+	position := float64(binary.BigEndian.Uint64(sum[:8])) / math.MaxUint64
+	if position >= e.traffic {
+		return "", types.ExperimentVariant{}, false
+	}
+
+	// Spread the keys inside the experiment's share across the variants by weight
+	target := position / e.traffic * float64(e.weights)
+	cumulative := 0
+	for _, variant := range e.variants {
+		cumulative += variant.Weight
+		if target < float64(cumulative) {
+			return variant.name, variant.ExperimentVariant, true
+		}
+	}
+
+	last := e.variants[len(e.variants)-1]
+	return last.name, last.ExperimentVariant, true
+}
+
+func (e *Experiment) stickyValue(keys types.StickyKeys) string {
+	switch e.stickyKey {
+	case "", "apiKey":
+		return keys.APIKey
+	case "user":
+		return keys.User
+	}
+
+	header := strings.TrimPrefix(e.stickyKey, "header:")
+	for key, values := range keys.Headers {
+		if strings.EqualFold(key, header) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package router_test
+
+import (
+	"context"
+	"fmt"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/cmd/internal/router/filters"
+	"llm-router/types"
+	"math"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestExperiment_Assign(t *testing.T) {
+	experiments := router.NewExperiments([]types.ExperimentData{
+		{
+			Name:    "split",
+			Enabled: true,
+			Traffic: 0.5,
+			Variants: map[string]types.ExperimentVariant{
+				"control":   {Provider: "plain-ai"},
+				"treatment": {Provider: "tool-ai", Weight: 3},
+			},
+		},
+		{Name: "disabled", Traffic: 1, Variants: map[string]types.ExperimentVariant{"control": {Provider: "plain-ai"}}},
+	})
+	if len(experiments) != 1 {
+		t.Fatalf("NewExperiments() built %d experiments, want only the enabled one", len(experiments))
+	}
+	experiment := experiments[0]
+
+	counts := make(map[string]int)
+	const keys = 20000
+	for i := 0; i < keys; i++ {
+		sticky := types.StickyKeys{APIKey: fmt.Sprintf("key-%d", i)}
+		variant, _, ok := experiment.Assign(sticky)
+		if !ok {
+			variant = "none"
+		}
+		counts[variant]++
+
+		// The same key always gets the same variant
+		if again, _, againOK := experiment.Assign(sticky); again != variant && (ok || againOK) {
+			t.Fatalf("Assign() is not sticky for %s: %s then %s", sticky.APIKey, variant, again)
+		}
+	}
+
+	for variant, want := range map[string]float64{"none": 0.5, "control": 0.125, "treatment": 0.375} {
+		if got := float64(counts[variant]) / keys; math.Abs(got-want) > 0.02 {
+			t.Errorf("share of %s = %.3f, want about %.3f", variant, got, want)
+		}
+	}
+
+	if _, _, ok := experiment.Assign(types.StickyKeys{User: "alice"}); ok {
+		t.Error("Assign() assigned a request without an API key")
+	}
+}
+
+func TestPipelineRouter_Experiments(t *testing.T) {
+	plain, tool := setUpCapabilityCatalog(t)
+
+	manager := providers.NewProviderManager(providers.NewProviderFactory())
+	manager.SetProviders([]types.Provider{plain, tool})
+
+	base, err := router.NewRoundRobinRouter(manager, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRoundRobinRouter() error = %v", err)
+	}
+	pipeline := router.NewPipelineRouter(base, manager, nil, nil, nil)
+	pipeline.AddFilter(filters.NewCapabilityFilter(zap.NewNop()))
+	pipeline.SetExperiments(router.NewExperiments([]types.ExperimentData{{
+		Name:      "all-in",
+		Enabled:   true,
+		Traffic:   1,
+		StickyKey: "header:X-Session-Id",
+		Variants:  map[string]types.ExperimentVariant{"capable": {Model: "tool-ai/capable"}},
+	}}))
+
+	messages := []types.Message{{Role: "user", Content: "hello"}}
+	sticky := types.StickyKeys{Headers: map[string][]string{"X-Session-Id": {"session-1"}}}
+
+	out, err := pipeline.SelectProvider(context.Background(), &types.SelectProviderInput{Messages: messages, Sticky: sticky})
+	if err != nil {
+		t.Fatalf("SelectProvider() error = %v", err)
+	}
+	if out.Experiment != "all-in" || out.Variant != "capable" || out.Provider != tool || out.Model != "tool-ai/capable" {
+		t.Errorf("SelectProvider() = %s/%s on %s, want all-in/capable on tool-ai/capable", out.Experiment, out.Variant, out.Model)
+	}
+
+	// A variant the caller may not use leaves the request to normal routing, outside the experiment
+	out, err = pipeline.SelectProvider(context.Background(), &types.SelectProviderInput{
+		Messages: messages,
+		Sticky:   sticky,
+		Models:   []string{"plain-ai/cheap"},
+	})
+	if err != nil {
+		t.Fatalf("SelectProvider() error = %v", err)
+	}
+	if out.Experiment != "" || out.Provider != plain {
+		t.Errorf("SelectProvider() = experiment %q on %s, want no experiment on plain-ai", out.Experiment, out.Provider.GetProviderName())
+	}
+}
@@ -0,0 +1,63 @@
+package filters
+
+import (
+	"context"
+	"llm-router/cmd/internal/providers"
+	"llm-router/types"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// CapabilityFilter drops providers that have no model in the registry offering every
+// capability the request needs (e.g. "tools" for requests that define tools), or, for
+// callers limited to certain models, no model they may use. Requests limited to certain
+// providers (e.g. by a routing rule) also drop every other provider.
+type CapabilityFilter struct {
+	logger *zap.Logger
+}
+
+func NewCapabilityFilter(logger *zap.Logger) *CapabilityFilter {
+	return &CapabilityFilter{
+		logger: logger,
+	}
+}
+
+func (f *CapabilityFilter) Name() string {
+	return "capability"
+}
+
+func (f *CapabilityFilter) Filter(ctx context.Context, input *types.FilterInput) (*types.FilterOutput, error) {
+	if len(input.Capabilities) == 0 && len(input.Models) == 0 && len(input.Providers) == 0 {
+		return &types.FilterOutput{Candidates: input.Candidates}, nil
+	}
+
+	var filtered []types.Provider
+
+	for _, p := range input.Candidates {
+		name := p.GetProviderName()
+		if len(input.Providers) > 0 && !slices.Contains(input.Providers, name) {
+			f.logger.Debug("Provider is not allowed for the request, skipping", zap.String("provider", name))
+			continue
+		}
+		if len(input.Capabilities) == 0 && len(input.Models) == 0 {
+			filtered = append(filtered, p)
+			continue
+		}
+
+		models := providers.FilterModelsByCapabilities(providers.ListModelsByProvider(name), input.Capabilities)
+		models = providers.FilterAllowedModels(models, input.Models)
+		if len(models) > 0 {
+			filtered = append(filtered, p)
+		} else {
+			f.logger.Debug("Provider has no allowed model with the required capabilities, skipping",
+				zap.String("provider", name),
+				zap.Strings("capabilities", input.Capabilities),
+			)
+		}
+	}
+
+	return &types.FilterOutput{
+		Candidates: filtered,
+	}, nil
+}
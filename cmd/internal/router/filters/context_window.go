@@ -0,0 +1,74 @@
+package filters
+
+import (
+	"context"
+	"llm-router/cmd/internal/providers"
+	"llm-router/types"
+
+	"go.uber.org/zap"
+)
+
+// ContextWindowFilter drops providers with no model whose context window fits the request's
+// prompt, counted with the provider's tokenizer, plus its requested max_tokens. Only the models
+// the request could use (with its capabilities, and allowed for its caller) are considered.
+// Providers without catalog models are kept, since their context windows are unknown.
+// If every provider is dropped, it returns a *types.ContextWindowError.
+type ContextWindowFilter struct {
+	logger *zap.Logger
+}
+
+func NewContextWindowFilter(logger *zap.Logger) *ContextWindowFilter {
+	return &ContextWindowFilter{
+		logger: logger,
+	}
+}
+
+func (f *ContextWindowFilter) Name() string {
+	return "context-window"
+}
+
+func (f *ContextWindowFilter) Filter(ctx context.Context, input *types.FilterInput) (*types.FilterOutput, error) {
+	if input.Tokens == nil {
+		return &types.FilterOutput{Candidates: input.Candidates}, nil
+	}
+
+	var filtered []types.Provider
+	tooLong := &types.ContextWindowError{MaxTokens: input.Tokens.MaxTokens()}
+
+	for _, p := range input.Candidates {
+		name := p.GetProviderName()
+
+		models := providers.ListModelsByProvider(name)
+		if len(models) == 0 {
+			filtered = append(filtered, p)
+			continue
+		}
+		models = providers.FilterModelsByCapabilities(models, input.Capabilities)
+		models = providers.FilterAllowedModels(models, input.Models)
+
+		if len(providers.FilterModelsByContext(ctx, models, p, input.Tokens)) > 0 {
+			filtered = append(filtered, p)
+			continue
+		}
+
+		promptTokens, _ := input.Tokens.Prompt(ctx, p)
+		f.logger.Debug("Provider has no model with a large enough context window, skipping",
+			zap.String("provider", name),
+			zap.Int("prompt_tokens", promptTokens),
+			zap.Int("max_tokens", tooLong.MaxTokens),
+		)
+
+		tooLong.PromptTokens = max(tooLong.PromptTokens, promptTokens)
+		for _, model := range models {
+			tooLong.ContextWindow = max(tooLong.ContextWindow, model.ContextWindow)
+		}
+	}
+
+	if len(filtered) == 0 && len(input.Candidates) > 0 {
+		return nil, tooLong
+	}
+
+	return &types.FilterOutput{
+		Candidates: filtered,
+	}, nil
+}
@@ -2,192 +2,48 @@ package filters
 
 import (
 	"context"
-	"fmt"
+	"llm-router/cmd/internal/embeddings"
 	"llm-router/cmd/internal/providers"
 	"llm-router/types"
-	"math"
-	"os"
-	"path/filepath"
-	"sync"
 
-	"github.com/sugarme/tokenizer"
-	"github.com/sugarme/tokenizer/pretrained"
-	ort "github.com/yalue/onnxruntime_go"
 	"go.uber.org/zap"
 )
 
-// Constants for the MiniLM-L6-v2 model
-const (
-	MaxSeqLength = 128 // Transformer input limit
-	EmbeddingDim = 384 // MiniLM output vector size
-)
-
 // EmbeddingFilter implements the ProviderFilter interface using local vector similarity.
 // It converts user prompts into mathematical vectors (embeddings) and compares them
 // against pre-calculated "intent clusters" to determine the most relevant routing.
 type EmbeddingFilter struct {
 	policy           *types.SemanticPolicy
-	tokenizer        *tokenizer.Tokenizer
-	session          *ort.AdvancedSession
+	model            *embeddings.Model
 	intentEmbeddings map[string][]float32
-	mu               sync.Mutex
 	logger           *zap.Logger
-
-	// Pre-allocated buffers for inference (optimized for performance)
-	inputIds      []int64
-	attentionMask []int64
-	tokenTypeIds  []int64
-	outputData    []float32
 }
 
 // NewEmbeddingFilter creates and initializes a new EmbeddingFilter.
-// It handles ONNX Runtime environment setup and model loading.
+// The underlying MiniLM model is shared with any other component that embeds text.
 func NewEmbeddingFilter(policy *types.SemanticPolicy, logger *zap.Logger) (*EmbeddingFilter, error) {
-	// 1. Initialize ONNX runtime environment
-	if !ort.IsInitialized() {
-		libPath := resolveLibPath(policy.SharedLibPath, logger)
-		if libPath != "" {
-			ort.SetSharedLibraryPath(libPath)
-		}
-
-		err := ort.InitializeEnvironment()
-		if err != nil {
-			return nil, fmt.Errorf("failed to initialize onnxruntime: %w", err)
-		}
+	model, err := embeddings.Shared(policy.ModelPath, policy.SharedLibPath, logger)
+	if err != nil {
+		return nil, err
 	}
 
 	f := &EmbeddingFilter{
 		policy:           policy,
+		model:            model,
 		intentEmbeddings: make(map[string][]float32),
-		inputIds:         make([]int64, MaxSeqLength),
-		attentionMask:    make([]int64, MaxSeqLength),
-		tokenTypeIds:     make([]int64, MaxSeqLength),
-		outputData:       make([]float32, MaxSeqLength*EmbeddingDim),
 		logger:           logger,
 	}
 
-	err := f.loadModel(policy.ModelPath)
-	if err != nil {
-		return nil, err
-	}
+	// Pre-calculate Intent Centroids (Averaged Few-Shot Vectors)
+	f.initializeIntentClusters()
 
 	return f, nil
 }
 
-// resolveLibPath finds the best ONNX shared library path based on priority:
-// 1. Environment variable (ONNXRUNTIME_LIB_PATH)
-// 2. Configuration file (shared_lib_path)
-// 3. Known system locations (e.g. Homebrew)
-func resolveLibPath(configPath string, logger *zap.Logger) string {
-	// 1. Try Env Var
-	if env := os.Getenv("ONNXRUNTIME_LIB_PATH"); env != "" {
-		if logger != nil {
-			logger.Debug("Using ONNX library from environment variable", zap.String("path", env))
-		}
-		return env
-	}
-
-	// 2. Try Config
-	if configPath != "" {
-		if _, err := os.Stat(configPath); err == nil {
-			if logger != nil {
-				logger.Debug("Using ONNX library from config", zap.String("path", configPath))
-			}
-			return configPath
-		}
-	}
-
-	// 3. Known System Paths (macOS/Linux)
-	defaults := []string{
-		"/usr/local/lib/libonnxruntime.dylib",
-		"/opt/homebrew/lib/libonnxruntime.dylib",
-		"/usr/lib/libonnxruntime.so",
-	}
-	for _, p := range defaults {
-		if _, err := os.Stat(p); err == nil {
-			if logger != nil {
-				logger.Debug("Using ONNX library from default path", zap.String("path", p))
-			}
-			return p
-		}
-	}
-
-	return ""
-}
-
 func (f *EmbeddingFilter) Name() string {
 	return "embedding"
 }
 
-// loadModel prepares the tokenizer and ONNX inference session.
-func (f *EmbeddingFilter) loadModel(path string) error {
-	// Resolve relative model path
-	finalPath := path
-	if _, err := os.Stat(finalPath); os.IsNotExist(err) && !filepath.IsAbs(finalPath) {
-		tryPaths := []string{
-			filepath.Join("..", "..", path),
-			filepath.Join("..", path),
-		}
-		for _, tp := range tryPaths {
-			if _, err := os.Stat(tp); err == nil {
-				finalPath = tp
-				break
-			}
-		}
-	}
-
-	// workingDir, err := os.Getwd()
-
-	// 1. Load Tokenizer (custom tokenizer.json if present)
-	tokenizerPath := filepath.Join(filepath.Dir(finalPath), "tokenizer.json")
-	if _, err := os.Stat(tokenizerPath); err == nil {
-		f.logger.Debug("Loading tokenizer from file", zap.String("path", tokenizerPath), zap.String("finalPath", finalPath))
-
-		fileInfo, err := os.Stat(tokenizerPath)
-		if err != nil {
-			f.logger.Error("Cannot stat tokenizer file", zap.Error(err))
-		}
-		f.logger.Debug("Tokenizer file info",
-			zap.Int64("size", fileInfo.Size()),
-			zap.String("mode", fileInfo.Mode().String()))
-
-		tk := tokenizer.NewTokenizerFromFile(tokenizerPath)
-		if tk != nil {
-			f.tokenizer = tk
-		} else {
-			f.tokenizer = pretrained.BertBaseUncased()
-			f.logger.Debug("failed to load tokenizer from file", zap.String("path", tokenizerPath))
-		}
-	} else {
-		return fmt.Errorf("tokenizer.json not found at %s. This file is required for semantic routing", tokenizerPath)
-	}
-
-	// 2. Bind Tensors to Buffers
-	shape := ort.NewShape(1, MaxSeqLength)
-	inputIdsTensor, _ := ort.NewTensor(shape, f.inputIds)
-	maskTensor, _ := ort.NewTensor(shape, f.attentionMask)
-	typeIdsTensor, _ := ort.NewTensor(shape, f.tokenTypeIds)
-	outputShape := ort.NewShape(1, MaxSeqLength, EmbeddingDim)
-	outputTensor, _ := ort.NewTensor(outputShape, f.outputData)
-
-	// 3. Create Session
-	session, err := ort.NewAdvancedSession(finalPath,
-		[]string{"input_ids", "attention_mask", "token_type_ids"},
-		[]string{"last_hidden_state"},
-		[]ort.Value{inputIdsTensor, maskTensor, typeIdsTensor},
-		[]ort.Value{outputTensor},
-		nil)
-	if err != nil {
-		return fmt.Errorf("failed to create onnx session: %w", err)
-	}
-	f.session = session
-
-	// 4. Pre-calculate Intent Centroids (Averaged Few-Shot Vectors)
-	f.initializeIntentClusters()
-
-	return nil
-}
-
 func (f *EmbeddingFilter) initializeIntentClusters() {
 	// Merge user groups with system defaults if enabled
 	allGroups := f.policy.Groups
@@ -218,21 +74,21 @@ func (f *EmbeddingFilter) initializeIntentClusters() {
 		texts = append(texts, group.Examples...)
 
 		if len(texts) > 0 {
-			centroid := make([]float32, EmbeddingDim)
+			centroid := make([]float32, embeddings.EmbeddingDim)
 			validSampleCount := 0
 			for _, t := range texts {
-				emb, err := f.calculateEmbedding(context.Background(), t)
+				emb, err := f.model.Embed(context.Background(), t)
 				if err != nil {
 					continue
 				}
-				for d := 0; d < EmbeddingDim; d++ {
+				for d := 0; d < embeddings.EmbeddingDim; d++ {
 					centroid[d] += emb[d]
 				}
 				validSampleCount++
 			}
 			// Average to get the cluster center
 			if validSampleCount > 0 {
-				for d := 0; d < EmbeddingDim; d++ {
+				for d := 0; d < embeddings.EmbeddingDim; d++ {
 					centroid[d] /= float32(validSampleCount)
 				}
 				f.intentEmbeddings[group.Name] = centroid
@@ -242,104 +98,34 @@ func (f *EmbeddingFilter) initializeIntentClusters() {
 	}
 }
 
-// calculateEmbedding processes a string through the neural network.
-func (f *EmbeddingFilter) calculateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-
-	// 1. Tokenization: Break text into sub-words (WordPiece)
-	en, err := f.tokenizer.EncodeSingle(text, true)
-	if err != nil {
-		return nil, err
-	}
-
-	ids := en.GetIds()
-	mask := en.GetAttentionMask()
-	typeIds := en.GetTypeIds()
-
-	// Ensure BERT special tokens are present ([CLS] at 0, [SEP] at end)
-	if len(ids) > 0 && ids[0] != 101 {
-		newIds := []int{101}
-		newIds = append(newIds, ids...)
-		if newIds[len(newIds)-1] != 102 {
-			newIds = append(newIds, 102)
-		}
-		ids = newIds
-		// Resync mask
-		mask = make([]int, len(ids))
-		for i := range mask {
-			mask[i] = 1
-		}
-		typeIds = make([]int, len(ids))
-	}
-
-	// 2. Data Preparation: Fill ONNX buffers with padding
-	for i := 0; i < MaxSeqLength; i++ {
-		if i < len(ids) {
-			f.inputIds[i] = int64(ids[i])
-			f.attentionMask[i] = int64(mask[i])
-			f.tokenTypeIds[i] = int64(typeIds[i])
-		} else {
-			f.inputIds[i] = 0 // [PAD] id
-			f.attentionMask[i] = 0
-			f.tokenTypeIds[i] = 0
-		}
+// Filter compares the prompt's vector against each intent's centroid vector.
+func (f *EmbeddingFilter) Filter(ctx context.Context, input *types.FilterInput) (*types.FilterOutput, error) {
+	candidates := input.Candidates
+	if len(input.Messages) == 0 {
+		return &types.FilterOutput{Candidates: candidates}, nil
 	}
 
-	// 3. Inference: Run the Transformer model
-	err = f.session.Run()
+	bestGroup, err := f.Classify(ctx, input.Messages)
 	if err != nil {
-		return nil, err
-	}
-
-	// 4. Mean Pooling: Average the vectors of all tokens in the sentence
-	// This reduces a matrix (Words x Dimensions) into a single vector (Dimensions).
-	embedding := make([]float32, EmbeddingDim)
-	var validTokens float32
-
-	for i := 0; i < MaxSeqLength; i++ {
-		if i < len(ids) {
-			validTokens++
-			for d := 0; d < EmbeddingDim; d++ {
-				embedding[d] += f.outputData[i*EmbeddingDim+d]
-			}
-		}
-	}
-
-	if validTokens > 0 {
-		for d := 0; d < EmbeddingDim; d++ {
-			embedding[d] /= validTokens
-		}
-	}
-
-	// Calculate magnitude for debug
-	var mag float64
-	for _, v := range embedding {
-		mag += float64(v) * float64(v)
+		return &types.FilterOutput{Candidates: candidates}, err
 	}
-	magnitude := math.Sqrt(mag)
-
-	f.logger.Debug("Point embedding calculated",
-		zap.String("text", text),
-		zap.Int("tokens", len(ids)),
-		zap.Float64("magnitude", magnitude),
-	)
 
-	return embedding, nil
+	// Transform Decision into Candidate Pool
+	filtered, err := f.resolveCandidates(bestGroup, candidates)
+	return &types.FilterOutput{Candidates: filtered}, err
 }
 
-// Filter compares the prompt's vector against each intent's centroid vector.
-func (f *EmbeddingFilter) Filter(ctx context.Context, input *types.FilterInput) (*types.FilterOutput, error) {
-	candidates := input.Candidates
-	if len(input.Messages) == 0 {
-		return &types.FilterOutput{Candidates: candidates}, nil
+// Classify returns the intent group closest to the final message, or the default group when none is close enough.
+func (f *EmbeddingFilter) Classify(ctx context.Context, messages []types.Message) (string, error) {
+	if len(messages) == 0 {
+		return f.policy.DefaultGroup, nil
 	}
 
 	// 1. Vectorize the prompt
-	lastMsg := input.Messages[len(input.Messages)-1].Content
-	promptEmb, err := f.calculateEmbedding(ctx, lastMsg)
+	lastMsg := messages[len(messages)-1].Content
+	promptEmb, err := f.model.Embed(ctx, lastMsg)
 	if err != nil {
-		return &types.FilterOutput{Candidates: candidates}, err
+		return "", err
 	}
 
 	// 2. Find closest intent cluster (Cosine Similarity)
@@ -347,7 +133,7 @@ func (f *EmbeddingFilter) Filter(ctx context.Context, input *types.FilterInput)
 	maxSim := -1.0
 
 	for name, intentEmb := range f.intentEmbeddings {
-		sim := cosineSimilarity(promptEmb, intentEmb)
+		sim := embeddings.CosineSimilarity(promptEmb, intentEmb)
 		f.logger.Debug("Semantic Group Score",
 			zap.String("group", name),
 			zap.Float64("score", sim),
@@ -370,17 +156,14 @@ func (f *EmbeddingFilter) Filter(ctx context.Context, input *types.FilterInput)
 			zap.Float64("threshold", threshold),
 			zap.String("default_group", f.policy.DefaultGroup),
 		)
-		bestGroup = f.policy.DefaultGroup
-	} else {
-		f.logger.Info("Semantic match found",
-			zap.String("intent", bestGroup),
-			zap.Float64("score", maxSim),
-		)
+		return f.policy.DefaultGroup, nil
 	}
 
-	// 4. Transform Decision into Candidate Pool
-	filtered, err := f.resolveCandidates(bestGroup, candidates)
-	return &types.FilterOutput{Candidates: filtered}, err
+	f.logger.Info("Semantic match found",
+		zap.String("intent", bestGroup),
+		zap.Float64("score", maxSim),
+	)
+	return bestGroup, nil
 }
 
 func (f *EmbeddingFilter) resolveCandidates(groupName string, candidates []types.Provider) ([]types.Provider, error) {
@@ -435,18 +218,3 @@ func (f *EmbeddingFilter) resolveCandidates(groupName string, candidates []types
 
 	return filtered, nil
 }
-
-// cosineSimilarity measures how "aligned" two vectors are.
-// Scores range from -1.0 (opposite) to 1.0 (identical).
-func cosineSimilarity(a, b []float32) float64 {
-	var dot, normA, normB float64
-	for i := range a {
-		dot += float64(a[i]) * float64(b[i])
-		normA += float64(a[i]) * float64(a[i])
-		normB += float64(b[i]) * float64(b[i])
-	}
-	if normA == 0 || normB == 0 {
-		return 0
-	}
-	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
-}
@@ -30,33 +30,7 @@ func (f *KeywordFilter) Filter(ctx context.Context, input *types.FilterInput) (*
 		return &types.FilterOutput{Candidates: candidates}, nil
 	}
 
-	var promptBuilder strings.Builder
-	for _, msg := range input.Messages {
-		promptBuilder.WriteString(msg.Content)
-		promptBuilder.WriteString(" ")
-	}
-	prompt := strings.ToLower(promptBuilder.String())
-
-	matchedGroup := f.policy.DefaultGroup
-
-	for _, group := range f.policy.Groups {
-		for _, keyword := range group.IntentKeywords {
-			if strings.Contains(prompt, strings.ToLower(keyword)) {
-				matchedGroup = group.Name
-				f.logger.Debug("Keyword match found", zap.String("keyword", keyword), zap.String("group", group.Name))
-				break
-			}
-		}
-		if matchedGroup != f.policy.DefaultGroup {
-			break
-		}
-	}
-
-	if matchedGroup == f.policy.DefaultGroup {
-		f.logger.Info("No keyword match found, using default group", zap.String("default_group", matchedGroup))
-	} else {
-		f.logger.Info("Semantic match found (Keyword)", zap.String("intent", matchedGroup))
-	}
+	matchedGroup, _ := f.Classify(ctx, input.Messages)
 
 	var allowList []string
 	foundGroup := false
@@ -94,3 +68,36 @@ func (f *KeywordFilter) Filter(ctx context.Context, input *types.FilterInput) (*
 
 	return &types.FilterOutput{Candidates: filtered}, nil
 }
+
+// Classify returns the first group with a keyword found in the conversation, or the default group.
+func (f *KeywordFilter) Classify(ctx context.Context, messages []types.Message) (string, error) {
+	var promptBuilder strings.Builder
+	for _, msg := range messages {
+		promptBuilder.WriteString(msg.Content)
+		promptBuilder.WriteString(" ")
+	}
+	prompt := strings.ToLower(promptBuilder.String())
+
+	matchedGroup := f.policy.DefaultGroup
+
+	for _, group := range f.policy.Groups {
+		for _, keyword := range group.IntentKeywords {
+			if strings.Contains(prompt, strings.ToLower(keyword)) {
+				matchedGroup = group.Name
+				f.logger.Debug("Keyword match found", zap.String("keyword", keyword), zap.String("group", group.Name))
+				break
+			}
+		}
+		if matchedGroup != f.policy.DefaultGroup {
+			break
+		}
+	}
+
+	if matchedGroup == f.policy.DefaultGroup {
+		f.logger.Info("No keyword match found, using default group", zap.String("default_group", matchedGroup))
+	} else {
+		f.logger.Info("Semantic match found (Keyword)", zap.String("intent", matchedGroup))
+	}
+
+	return matchedGroup, nil
+}
@@ -19,6 +19,11 @@ func NewLatencyMonitoringProvider(provider types.Provider, tracker *LatencyTrack
 	}
 }
 
+// Unwrap returns the monitored provider.
+func (p *LatencyMonitoringProvider) Unwrap() types.Provider {
+	return p.Provider
+}
+
 func (p *LatencyMonitoringProvider) Complete(ctx context.Context, input *types.CompletionInput) (*types.CompletionResponse, error) {
 	start := time.Now()
 	resp, err := p.Provider.Complete(ctx, input)
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"llm-router/cmd/internal/providers"
 	"llm-router/types"
+	"slices"
+
+	"go.uber.org/zap"
 )
 
 type ProviderFilter interface {
@@ -15,10 +18,15 @@ type ProviderFilter interface {
 type PipelineRouter struct {
 	baseRouter       Router
 	filters          []ProviderFilter
+	policyFilters    []ProviderFilter
 	providerManager  *providers.ProviderManager
 	budgetManager    BudgetManager
 	rateLimitManager RateLimitManager
 	usageHistory     UsageHistoryManager
+	defaultModels    map[string]string // provider name -> configured default model ID
+	rules            *RuleEngine
+	experiments      []*Experiment
+	estimator        *CostEstimator
 }
 
 func NewPipelineRouter(baseRouter Router, manager *providers.ProviderManager, budget BudgetManager, rateLimit RateLimitManager, history UsageHistoryManager) *PipelineRouter {
@@ -48,7 +56,75 @@ func (r *PipelineRouter) AddFilter(filter ProviderFilter) {
 	r.filters = append(r.filters, filter)
 }
 
+// AddPolicyFilter adds a filter that expresses a routing preference (e.g. semantic intent groups)
+// rather than whether a provider can serve the request. Policy filters run after the others and
+// are skipped for requests pinned to a model.
+func (r *PipelineRouter) AddPolicyFilter(filter ProviderFilter) {
+	r.policyFilters = append(r.policyFilters, filter)
+}
+
+// SetDefaultModels records each provider's configured default model, used to check capabilities
+// when the base router selects a provider without choosing a model.
+func (r *PipelineRouter) SetDefaultModels(defaultModels map[string]string) {
+	r.defaultModels = defaultModels
+}
+
+// SetRules sets the routing rules MatchRule evaluates.
+func (r *PipelineRouter) SetRules(rules *RuleEngine) {
+	r.rules = rules
+}
+
+// SetExperiments sets the experiments requests may be assigned to. A request joins the first
+// experiment that takes it.
+func (r *PipelineRouter) SetExperiments(experiments []*Experiment) {
+	r.experiments = experiments
+}
+
+// SetCostEstimator sets the estimator used to price requests. Without one, output lengths are
+// assumed to be the request's max_tokens, or the prompt's length.
+func (r *PipelineRouter) SetCostEstimator(estimator *CostEstimator) {
+	r.estimator = estimator
+}
+
+// MatchRule returns the first routing rule matching the request, or nil.
+func (r *PipelineRouter) MatchRule(ctx context.Context, input *types.RuleInput) *types.RoutingRule {
+	return r.rules.Match(ctx, input)
+}
+
+// SelectProvider picks a provider, and usually a model, for the request. The selection's cost is
+// estimated, and if it exceeds the request's cost ceiling the cheapest model under the ceiling is
+// used instead, or a *types.CostCeilingError is returned. The selected model's tier is reported.
 func (r *PipelineRouter) SelectProvider(ctx context.Context, input *types.SelectProviderInput) (*types.SelectedProviderOutput, error) {
+	output, err := r.selectProvider(ctx, input)
+	if err != nil {
+		return output, err
+	}
+
+	output, err = r.applyCostCeiling(ctx, input, output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Tier == "" {
+		output.Tier = r.modelTier(output)
+	}
+	return output, nil
+}
+
+// modelTier returns the catalog tier of the selected model, or of its provider's default model.
+func (r *PipelineRouter) modelTier(output *types.SelectedProviderOutput) string {
+	modelID := output.Model
+	if modelID == "" {
+		modelID = r.defaultModels[output.Provider.GetProviderName()]
+	}
+	info, err := providers.GetModelInfo(modelID)
+	if err != nil {
+		return ""
+	}
+	return string(info.Tier)
+}
+
+func (r *PipelineRouter) selectProvider(ctx context.Context, input *types.SelectProviderInput) (*types.SelectedProviderOutput, error) {
 
 	allProviders := r.providerManager.GetProviders()
 	if len(allProviders) == 0 {
@@ -67,12 +143,282 @@ func (r *PipelineRouter) SelectProvider(ctx context.Context, input *types.Select
 		return nil, fmt.Errorf("no healthy providers available")
 	}
 
-	var err error
+	if input.Tokens == nil {
+		input.Tokens = types.NewRequestTokens(input.Messages, 0)
+	}
+
+	candidates, err := r.applyFilters(ctx, r.filters, candidates, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Model != "" {
+		return r.selectPinned(ctx, input.Model, candidates, input.Tokens)
+	}
+
+	// Experiments run before the policy filters, so a variant's provider isn't excluded by them
+	if output := r.selectExperiment(ctx, input, candidates); output != nil {
+		return output, nil
+	}
+
+	candidates, err = r.applyFilters(ctx, r.policyFilters, candidates, input)
+	if err != nil {
+		return nil, err
+	}
+
+	input.Candidates = candidates
+	output, err := r.baseRouter.SelectProvider(ctx, input)
+	if err != nil {
+		return output, err
+	}
+
+	output.Candidates = candidates
+	if output.Model == "" {
+		output.Model = r.resolveModel(ctx, output.Provider, input)
+	}
+	return output, nil
+}
+
+// EstimateCost returns the estimated cost in USD of the request on the model, or on the
+// provider's default model if modelID is empty. It returns false for models without pricing.
+func (r *PipelineRouter) EstimateCost(ctx context.Context, provider types.Provider, modelID string, tokens *types.RequestTokens) (float64, bool) {
+	if modelID == "" {
+		modelID = r.defaultModels[provider.GetProviderName()]
+	}
+	if modelID == "" || tokens == nil {
+		return 0, false
+	}
+
+	promptTokens, err := tokens.Prompt(ctx, provider)
+	if err != nil {
+		return 0, false
+	}
+	cost, err := r.estimator.Estimate(modelID, promptTokens, tokens.MaxTokens())
+	if err != nil {
+		return 0, false
+	}
+	return cost, true
+}
+
+// applyCostCeiling records the selection's estimated cost and enforces the request's cost ceiling.
+// A selection over the ceiling is replaced by the cheapest model among the candidates that can
+// serve the request under it; a pinned model is never replaced. Selections that can't be priced
+// are let through.
+func (r *PipelineRouter) applyCostCeiling(ctx context.Context, input *types.SelectProviderInput, output *types.SelectedProviderOutput) (*types.SelectedProviderOutput, error) {
+	cost, ok := r.EstimateCost(ctx, output.Provider, output.Model, input.Tokens)
+	if !ok {
+		return output, nil
+	}
+	if output.EstimatedCost == 0 {
+		output.EstimatedCost = cost
+	}
+	if input.MaxCost <= 0 || output.EstimatedCost <= input.MaxCost {
+		return output, nil
+	}
+
+	ceilingErr := &types.CostCeilingError{MaxCost: input.MaxCost, Cheapest: output.EstimatedCost}
+	if input.Model != "" {
+		return nil, ceilingErr
+	}
+
+	var cheapest *types.SelectedProviderOutput
+	for _, p := range output.Candidates {
+		models := providers.FilterModelsByCapabilities(providers.ListModelsByProvider(p.GetProviderName()), input.Capabilities)
+		models = providers.FilterAllowedModels(models, input.Models)
+		models = providers.FilterModelsByContext(ctx, models, p, input.Tokens)
+
+		for _, model := range models {
+			cost, ok := r.EstimateCost(ctx, p, model.ID, input.Tokens)
+			if !ok || (cheapest != nil && cost >= cheapest.EstimatedCost) {
+				continue
+			}
+			cheapest = &types.SelectedProviderOutput{
+				Provider:      p,
+				Model:         model.ID,
+				Candidates:    output.Candidates,
+				EstimatedCost: cost,
+			}
+		}
+	}
+
+	if cheapest == nil || cheapest.EstimatedCost > input.MaxCost {
+		if cheapest != nil {
+			ceilingErr.Cheapest = cheapest.EstimatedCost
+		}
+		return nil, ceilingErr
+	}
+
+	logger.Debug("Selected model exceeds the request's cost ceiling, using a cheaper one",
+		zap.String("selected", output.Model),
+		zap.Float64("estimated_cost", output.EstimatedCost),
+		zap.String("model", cheapest.Model),
+		zap.Float64("cheapest_cost", cheapest.EstimatedCost),
+		zap.Float64("max_cost", input.MaxCost),
+	)
+	return cheapest, nil
+}
+
+func (r *PipelineRouter) applyFilters(ctx context.Context, filters []ProviderFilter, candidates []types.Provider, input *types.SelectProviderInput) ([]types.Provider, error) {
+	for _, filter := range filters {
+		filterOutput, err := filter.Filter(ctx, &types.FilterInput{
+			Candidates:   candidates,
+			Messages:     input.Messages,
+			Tier:         input.Tier,
+			Capabilities: input.Capabilities,
+			Models:       input.Models,
+			Providers:    input.Providers,
+			Tokens:       input.Tokens,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("filter %s failed: %w", filter.Name(), err)
+		}
+		candidates = filterOutput.Candidates
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("filter %s filtered out all providers", filter.Name())
+		}
+	}
+	return candidates, nil
+}
+
+// selectExperiment routes the request as the variant of the first experiment it is assigned to.
+// If that variant's provider didn't survive the filters, or its model can't serve the request,
+// the request is routed normally and isn't counted in the experiment.
+func (r *PipelineRouter) selectExperiment(ctx context.Context, input *types.SelectProviderInput, candidates []types.Provider) *types.SelectedProviderOutput {
+	for _, experiment := range r.experiments {
+		name, variant, ok := experiment.Assign(input.Sticky)
+		if !ok {
+			continue
+		}
+
+		var info providers.ModelInfo
+		providerName := variant.Provider
+		if variant.Model != "" {
+			var err error
+			info, err = providers.GetModelInfo(variant.Model)
+			if err != nil || info.IsEmbedding() || !info.HasCapabilities(input.Capabilities) ||
+				(len(input.Models) > 0 && !slices.Contains(input.Models, info.ID)) {
+				logger.Debug("Experiment variant's model can't serve the request", zap.String("experiment", experiment.Name()), zap.String("variant", name))
+				return nil
+			}
+			providerName = info.Provider
+		}
+
+		for _, p := range candidates {
+			if p.GetProviderName() != providerName {
+				continue
+			}
+
+			model := variant.Model
+			if model == "" {
+				model = r.resolveModel(ctx, p, input)
+			} else if !input.Tokens.Fits(ctx, p, info.ContextWindow) {
+				logger.Debug("Experiment variant's model can't fit the request", zap.String("experiment", experiment.Name()), zap.String("variant", name))
+				return nil
+			}
+			return &types.SelectedProviderOutput{
+				Provider:   p,
+				Model:      model,
+				Candidates: candidates,
+				Experiment: experiment.Name(),
+				Variant:    name,
+			}
+		}
+
+		logger.Debug("Experiment variant's provider is unavailable", zap.String("experiment", experiment.Name()), zap.String("variant", name))
+		return nil
+	}
+
+	return nil
+}
+
+// selectPinned returns the pinned model's provider if it survived the filters, or a
+// *types.ContextWindowError if the model can't fit the request.
+func (r *PipelineRouter) selectPinned(ctx context.Context, modelID string, candidates []types.Provider, tokens *types.RequestTokens) (*types.SelectedProviderOutput, error) {
+	providerName, _, err := providers.ParseModelID(modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range candidates {
+		if p.GetProviderName() != providerName {
+			continue
+		}
+
+		if info, err := providers.GetModelInfo(modelID); err == nil && !tokens.Fits(ctx, p, info.ContextWindow) {
+			promptTokens, _ := tokens.Prompt(ctx, p)
+			return nil, &types.ContextWindowError{PromptTokens: promptTokens, MaxTokens: tokens.MaxTokens(), ContextWindow: info.ContextWindow}
+		}
+
+		return &types.SelectedProviderOutput{
+			Provider:   p,
+			Model:      modelID,
+			Candidates: candidates,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("provider %s for pinned model %s is unavailable", providerName, modelID)
+}
+
+// resolveModel picks a model for a provider selected without one: the provider's default model if
+// it can serve the request, otherwise its cheapest model that can. A model can serve the request if
+// it has the capabilities it needs, is allowed for its caller and its context window fits it.
+// An empty result leaves the provider on its default model. With allowed models the default is
+// named explicitly, so the fallback chain is built from allowed models too.
+func (r *PipelineRouter) resolveModel(ctx context.Context, provider types.Provider, input *types.SelectProviderInput) string {
+	providerName := provider.GetProviderName()
+
+	info, err := providers.GetModelInfo(r.defaultModels[providerName])
+	if err != nil {
+		// The default model isn't configured or isn't in the catalog, so it can't be checked
+		if len(input.Capabilities) == 0 && len(input.Models) == 0 {
+			return ""
+		}
+	} else if info.HasCapabilities(input.Capabilities) && input.Tokens.Fits(ctx, provider, info.ContextWindow) {
+		if len(input.Models) == 0 {
+			return ""
+		}
+		if slices.Contains(input.Models, info.ID) {
+			return info.ID
+		}
+	}
+
+	models := providers.FilterModelsByCapabilities(providers.ListModelsByProvider(providerName), input.Capabilities)
+	models = providers.FilterAllowedModels(models, input.Models)
+	models = providers.FilterModelsByContext(ctx, models, provider, input.Tokens)
+	cheapest, err := providers.FindCheapestModel(models)
+	if err != nil {
+		return ""
+	}
+	return cheapest.ID
+}
+
+// SelectEmbeddingProvider picks a provider and embedding model. Candidates are the healthy
+// providers that can embed text with a model of the requested size, narrowed by the filters
+// (budgets, rate limits); policy filters and the base strategy are skipped. A pinned model
+// selects its provider, otherwise the cheapest embedding model across the candidates wins.
+func (r *PipelineRouter) SelectEmbeddingProvider(ctx context.Context, input *types.SelectProviderInput) (*types.SelectedProviderOutput, error) {
+	var candidates []types.Provider
+	for _, p := range r.providerManager.GetProviders() {
+		circuit, exists := input.Circuits[p.GetProviderName()]
+		if exists && !circuit.CanExecute() {
+			continue
+		}
+		if _, ok := providers.AsEmbeddingProvider(p); !ok {
+			continue
+		}
+		if len(embeddingModels(p.GetProviderName(), input.Dimensions, input.Models)) == 0 {
+			continue
+		}
+		candidates = append(candidates, p)
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no healthy embedding providers available")
+	}
+
 	for _, filter := range r.filters {
 		filterOutput, err := filter.Filter(ctx, &types.FilterInput{
 			Candidates: candidates,
-			Messages:   input.Messages,
-			Tier:       input.Tier,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("filter %s failed: %w", filter.Name(), err)
@@ -83,12 +429,35 @@ func (r *PipelineRouter) SelectProvider(ctx context.Context, input *types.Select
 		}
 	}
 
-	input.Candidates = candidates
-	output, err := r.baseRouter.SelectProvider(ctx, input)
-	if err == nil {
-		output.Candidates = candidates
+	if input.Model != "" {
+		return r.selectPinned(ctx, input.Model, candidates, nil)
 	}
-	return output, err
+
+	var models []providers.ModelInfo
+	for _, p := range candidates {
+		models = append(models, embeddingModels(p.GetProviderName(), input.Dimensions, input.Models)...)
+	}
+	cheapest, err := providers.FindCheapestModel(models)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range candidates {
+		if p.GetProviderName() == cheapest.Provider {
+			return &types.SelectedProviderOutput{
+				Provider:   p,
+				Model:      cheapest.ID,
+				Candidates: candidates,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("provider %s for model %s is unavailable", cheapest.Provider, cheapest.ID)
+}
+
+// embeddingModels returns the provider's allowed embedding models that can produce vectors of the given size.
+func embeddingModels(providerName string, dimensions int, allowed []string) []providers.ModelInfo {
+	models := providers.FilterModelsByDimensions(providers.ListEmbeddingModels(providerName), dimensions)
+	return providers.FilterAllowedModels(models, allowed)
 }
 
 func (r *PipelineRouter) GetProviderManager() *providers.ProviderManager {
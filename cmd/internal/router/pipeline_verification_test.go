@@ -0,0 +1,85 @@
+package router_test
+
+import (
+	"context"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/types"
+	"testing"
+)
+
+// excludeFilter drops the named providers.
+type excludeFilter struct {
+	exclude map[string]bool
+}
+
+func (f *excludeFilter) Name() string {
+	return "exclude"
+}
+
+func (f *excludeFilter) Filter(ctx context.Context, input *types.FilterInput) (*types.FilterOutput, error) {
+	var filtered []types.Provider
+	for _, p := range input.Candidates {
+		if !f.exclude[p.GetProviderName()] {
+			filtered = append(filtered, p)
+		}
+	}
+	return &types.FilterOutput{Candidates: filtered}, nil
+}
+
+func TestPipelineRouter_PinnedModel(t *testing.T) {
+	provA := &MockProvider{name: "provider-a"}
+	provB := &MockProvider{name: "provider-b"}
+	provC := &MockProvider{name: "provider-c"}
+
+	manager := providers.NewProviderManager(providers.NewProviderFactory())
+	manager.SetProviders([]types.Provider{provA, provB, provC})
+
+	// The weighted strategy would only ever pick provider-a
+	base, err := router.NewWeightedRouter(manager, map[string]int{"provider-a": 100}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create weighted router: %v", err)
+	}
+
+	pipeline := router.NewPipelineRouter(base, manager, nil, nil, nil)
+	pipeline.AddFilter(&excludeFilter{exclude: map[string]bool{"provider-c": true}})
+	// A policy that only allows provider-a must not override a pin
+	pipeline.AddPolicyFilter(&excludeFilter{exclude: map[string]bool{"provider-b": true, "provider-c": true}})
+
+	tests := []struct {
+		name         string
+		model        string
+		wantProvider string
+		wantErr      bool
+	}{
+		{name: "no pin uses the strategy", model: "", wantProvider: "provider-a"},
+		{name: "pin skips the strategy and policy filters", model: "provider-b/model", wantProvider: "provider-b"},
+		{name: "pin is still subject to availability filters", model: "provider-c/model", wantErr: true},
+		{name: "pin to an unknown provider", model: "provider-d/model", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := pipeline.SelectProvider(context.Background(), &types.SelectProviderInput{
+				Circuits: map[string]types.CircuitBreaker{},
+				Model:    tt.model,
+			})
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got provider %s", out.Provider.GetProviderName())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectProvider() error = %v", err)
+			}
+			if got := out.Provider.GetProviderName(); got != tt.wantProvider {
+				t.Errorf("provider = %s, want %s", got, tt.wantProvider)
+			}
+			if tt.model != "" && out.Model != tt.model {
+				t.Errorf("model = %s, want %s", out.Model, tt.model)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -10,29 +11,39 @@ import (
 )
 
 type RateLimitManager interface {
+	// Allow counts a request against a per-minute limit
 	Allow(ctx context.Context, key string, limit int) (bool, error)
+	// AllowWithin counts a request against a limit over a fixed window, e.g. 24 hours for daily limits
+	AllowWithin(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
 }
 
 type RedisRateLimitManager struct {
-	client *redis.Client
-	logger *zap.Logger
+	client    *redis.Client
+	namespace string
+	logger    *zap.Logger
 }
 
-func NewRedisRateLimitManager(client *redis.Client, logger *zap.Logger) RateLimitManager {
+// NewRedisRateLimitManager counts requests in Redis, under keys prefixed with namespace (if any).
+func NewRedisRateLimitManager(client *redis.Client, namespace string, logger *zap.Logger) RateLimitManager {
 	return &RedisRateLimitManager{
-		client: client,
-		logger: logger,
+		client:    client,
+		namespace: namespace,
+		logger:    logger,
 	}
 }
 
 func (m *RedisRateLimitManager) Allow(ctx context.Context, key string, limit int) (bool, error) {
+	return m.AllowWithin(ctx, key, limit, time.Minute)
+}
+
+// AllowWithin counts requests in fixed windows aligned to the window size, so daily windows start at midnight UTC.
+func (m *RedisRateLimitManager) AllowWithin(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
 	if limit <= 0 {
 		return true, nil
 	}
 
-	now := time.Now()
-	bucket := now.Format("2006-01-02 15:04")
-	redisKey := fmt.Sprintf("ratelimit:%s:%s", key, bucket)
+	bucket := time.Now().UTC().Truncate(window).Unix()
+	redisKey := namespacedKey(m.namespace, fmt.Sprintf("ratelimit:%s:%d", key, bucket))
 
 	count, err := m.client.Incr(ctx, redisKey).Result()
 	if err != nil {
@@ -41,7 +52,7 @@ func (m *RedisRateLimitManager) Allow(ctx context.Context, key string, limit int
 	}
 
 	if count == 1 {
-		m.client.Expire(ctx, redisKey, time.Minute*2)
+		m.client.Expire(ctx, redisKey, window*2)
 	}
 
 	if count > int64(limit) {
@@ -52,14 +63,45 @@ func (m *RedisRateLimitManager) Allow(ctx context.Context, key string, limit int
 	return true, nil
 }
 
+// InMemoryRateLimitManager counts requests in this process only, in the same fixed windows as
+// RedisRateLimitManager.
 type InMemoryRateLimitManager struct {
-	// For MVP, we can just say everything is allowed or implement a simple map
+	mu      sync.Mutex
+	windows map[string]rateLimitWindow
+}
+
+type rateLimitWindow struct {
+	start time.Time
+	count int
 }
 
 func NewInMemoryRateLimitManager() RateLimitManager {
-	return &InMemoryRateLimitManager{}
+	return &InMemoryRateLimitManager{
+		windows: make(map[string]rateLimitWindow),
+	}
 }
 
 func (m *InMemoryRateLimitManager) Allow(ctx context.Context, key string, limit int) (bool, error) {
-	return true, nil
+	return m.AllowWithin(ctx, key, limit, time.Minute)
+}
+
+func (m *InMemoryRateLimitManager) AllowWithin(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	if limit <= 0 {
+		return true, nil
+	}
+
+	start := time.Now().UTC().Truncate(window)
+	windowKey := fmt.Sprintf("%s:%s", key, window)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.windows[windowKey]
+	if !current.start.Equal(start) {
+		current = rateLimitWindow{start: start}
+	}
+	current.count++
+	m.windows[windowKey] = current
+
+	return current.count <= limit, nil
 }
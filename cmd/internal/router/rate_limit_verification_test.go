@@ -0,0 +1,34 @@
+package router_test
+
+import (
+	"context"
+	"llm-router/cmd/internal/router"
+	"testing"
+	"time"
+)
+
+func TestInMemoryRateLimitManager_AllowWithin(t *testing.T) {
+	ctx := context.Background()
+	limiter := router.NewInMemoryRateLimitManager()
+
+	for i := 1; i <= 4; i++ {
+		allowed, err := limiter.AllowWithin(ctx, "consumer:search", 3, 24*time.Hour)
+		if err != nil {
+			t.Fatalf("AllowWithin() error = %v", err)
+		}
+		if want := i <= 3; allowed != want {
+			t.Errorf("request %d: AllowWithin() = %v, want %v", i, allowed, want)
+		}
+	}
+
+	// The same key is counted separately for other windows and keys don't share counts
+	if allowed, _ := limiter.AllowWithin(ctx, "consumer:search", 3, time.Minute); !allowed {
+		t.Error("AllowWithin() over a minute was limited by the daily count")
+	}
+	if allowed, _ := limiter.Allow(ctx, "consumer:support", 1); !allowed {
+		t.Error("Allow() limited a key without requests")
+	}
+	if allowed, _ := limiter.Allow(ctx, "consumer:support", 1); allowed {
+		t.Error("Allow() let a second request through a limit of 1")
+	}
+}
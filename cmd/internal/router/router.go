@@ -19,6 +19,17 @@ type Router interface {
 	GetUsageHistoryManager() UsageHistoryManager
 }
 
+// EmbeddingRouter selects a provider and embedding model for /v1/embeddings requests.
+type EmbeddingRouter interface {
+	SelectEmbeddingProvider(ctx context.Context, input *types.SelectProviderInput) (*types.SelectedProviderOutput, error)
+}
+
+// CostEstimatingRouter estimates what a request will cost on a model, e.g. to keep fallbacks
+// under the request's cost ceiling.
+type CostEstimatingRouter interface {
+	EstimateCost(ctx context.Context, provider types.Provider, modelID string, tokens *types.RequestTokens) (float64, bool)
+}
+
 var logger = utils.SetUpLogger()
 
 func ConfigureRouterStrategy(
@@ -29,6 +40,8 @@ func ConfigureRouterStrategy(
 	rateLimitManager RateLimitManager,
 	rateLimits map[string]int,
 	usageHistory UsageHistoryManager,
+	defaultModels map[string]string,
+	experiments []types.ExperimentData,
 ) (Router, []string, error) {
 
 	var routerStrategy Router
@@ -68,6 +81,20 @@ func ConfigureRouterStrategy(
 	}
 
 	pipeline := NewPipelineRouter(routerStrategy, providerManager, budgetManager, rateLimitManager, usageHistory)
+	pipeline.SetDefaultModels(defaultModels)
+
+	if costRouter, ok := routerStrategy.(*CostRouter); ok {
+		pipeline.SetCostEstimator(costRouter.estimator)
+	} else {
+		var percentile float64
+		if routingData.CostOptions != nil {
+			percentile = routingData.CostOptions.OutputPercentile
+		}
+		pipeline.SetCostEstimator(NewCostEstimator(usageHistory, percentile))
+	}
+
+	pipeline.AddFilter(filters.NewCapabilityFilter(logger))
+	pipeline.AddFilter(filters.NewContextWindowFilter(logger))
 
 	if budgetManager != nil {
 		pipeline.AddFilter(filters.NewBudgetFilter(budgetManager, logger))
@@ -98,7 +125,29 @@ func ConfigureRouterStrategy(
 			logger.Info("Enabled Semantic (Keyword) Filter in Routing Pipeline")
 		}
 
-		pipeline.AddFilter(semanticFilter)
+		pipeline.AddPolicyFilter(semanticFilter)
+	}
+
+	if len(routingData.Rules) > 0 {
+		var classifier IntentClassifier
+		for _, filter := range pipeline.policyFilters {
+			if c, ok := filter.(IntentClassifier); ok {
+				classifier = c
+				break
+			}
+		}
+
+		rules, err := NewRuleEngine(routingData.Rules, classifier, providerManager)
+		if err != nil {
+			return nil, nil, err
+		}
+		pipeline.SetRules(rules)
+		logger.Info("Enabled routing rules", zap.Int("rules", len(routingData.Rules)))
+	}
+
+	if enabled := NewExperiments(experiments); len(enabled) > 0 {
+		pipeline.SetExperiments(enabled)
+		logger.Info("Enabled experiments", zap.Int("experiments", len(enabled)))
 	}
 
 	routerStrategy = pipeline
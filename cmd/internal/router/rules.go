@@ -0,0 +1,192 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"llm-router/cmd/internal/providers"
+	"llm-router/expr"
+	"llm-router/types"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// RuleRouter matches requests against the configured routing rules.
+type RuleRouter interface {
+	MatchRule(ctx context.Context, input *types.RuleInput) *types.RoutingRule
+}
+
+// IntentClassifier names the semantic group a conversation belongs to.
+type IntentClassifier interface {
+	Classify(ctx context.Context, messages []types.Message) (string, error)
+}
+
+// RuleEngine evaluates routing rules. Rules are checked in order and the first match wins.
+type RuleEngine struct {
+	rules           []routingRule
+	classifier      IntentClassifier // nil when no semantic policy is enabled
+	providerManager *providers.ProviderManager
+}
+
+type routingRule struct {
+	types.RoutingRule
+	condition *expr.Expression // nil matches every request
+}
+
+// NewRuleEngine compiles the rules. Conditions are validated again here so an engine can never
+// be built from rules that config.Validate would reject.
+func NewRuleEngine(rules []types.RoutingRule, classifier IntentClassifier, manager *providers.ProviderManager) (*RuleEngine, error) {
+	engine := &RuleEngine{classifier: classifier, providerManager: manager}
+
+	for i, rule := range rules {
+		compiled := routingRule{RoutingRule: rule}
+		if compiled.Name == "" {
+			compiled.Name = fmt.Sprintf("rule-%d", i)
+		}
+
+		if rule.If != "" {
+			condition, err := expr.Compile(rule.If, types.RoutingRuleFields)
+			if err != nil {
+				return nil, fmt.Errorf("routing rule %s: %w", compiled.Name, err)
+			}
+			compiled.condition = condition
+		}
+
+		engine.rules = append(engine.rules, compiled)
+	}
+
+	return engine, nil
+}
+
+// Match returns the first rule matching input, or nil. A rule pinning a model that can't serve
+// the request (not in the catalog, its provider not configured, missing a capability the
+// request needs or with a context window too small for it) is skipped, and so is a rule leaving
+// the caller no model it is allowed to use. The prompt's tokens are
+// only counted if a rule uses them or a matching rule pins a model, and its intent only if a rule uses it.
+func (e *RuleEngine) Match(ctx context.Context, input *types.RuleInput) *types.RoutingRule {
+	if e == nil || len(e.rules) == 0 {
+		return nil
+	}
+
+	promptLength := -1
+	promptTokens := -1
+	intent := ""
+	classified := false
+
+	countTokens := func() int {
+		if promptTokens < 0 {
+			promptTokens = 0
+			if input.Tokens != nil {
+				promptTokens = input.Tokens()
+			}
+		}
+		return promptTokens
+	}
+
+	env := func(name string) any {
+		switch name {
+		case "prompt.length":
+			if promptLength < 0 {
+				promptLength = 0
+				for _, msg := range input.Messages {
+					promptLength += len(msg.Content)
+				}
+			}
+			return promptLength
+		case "prompt.tokens":
+			return countTokens()
+		case "messages.count":
+			return len(input.Messages)
+		case "request.tier":
+			return input.Tier
+		case "consumer":
+			return input.Consumer
+		case "intent":
+			if !classified && e.classifier != nil {
+				var err error
+				intent, err = e.classifier.Classify(ctx, input.Messages)
+				if err != nil {
+					logger.Warn("Failed to classify intent for routing rules", zap.Error(err))
+				}
+			}
+			classified = true
+			return intent
+		}
+
+		if header, ok := strings.CutPrefix(name, "headers."); ok {
+			for key, values := range input.Headers {
+				if strings.EqualFold(key, header) && len(values) > 0 {
+					return values[0]
+				}
+			}
+			return ""
+		}
+		return nil
+	}
+
+	for _, rule := range e.rules {
+		if rule.condition != nil {
+			matched, err := rule.condition.Eval(env)
+			if err != nil {
+				logger.Warn("Skipping routing rule that failed to evaluate", zap.String("rule", rule.Name), zap.Error(err))
+				continue
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		if !permitted(rule.RoutingRule, input.Models) {
+			logger.Debug("Skipping routing rule that leaves the caller no model it may use", zap.String("rule", rule.Name))
+			continue
+		}
+
+		if rule.Use != "" && !e.canServe(rule.Use, input, countTokens) {
+			logger.Debug("Skipping routing rule whose model can't serve the request", zap.String("rule", rule.Name), zap.String("model", rule.Use))
+			continue
+		}
+
+		matched := rule.RoutingRule
+		return &matched
+	}
+
+	return nil
+}
+
+// permitted reports whether the rule leaves the caller a model it may use: its pinned model, or a
+// chat model of its providers and tier, must be allowed. An empty allowed list allows every model.
+func permitted(rule types.RoutingRule, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	if rule.Use != "" {
+		return slices.Contains(allowed, rule.Use)
+	}
+
+	for _, modelID := range allowed {
+		info, err := providers.GetModelInfo(modelID)
+		if err != nil || info.IsEmbedding() {
+			continue
+		}
+		if len(rule.Providers) > 0 && !slices.Contains(rule.Providers, info.Provider) {
+			continue
+		}
+		if rule.Tier != "" && string(info.Tier) != rule.Tier {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (e *RuleEngine) canServe(modelID string, input *types.RuleInput, countTokens func() int) bool {
+	info, err := providers.GetModelInfo(modelID)
+	if err != nil || info.IsEmbedding() || !info.HasCapabilities(input.Capabilities) {
+		return false
+	}
+	if _, err = e.providerManager.GetProvider(info.Provider); err != nil {
+		return false
+	}
+	return info.ContextWindow == 0 || countTokens()+input.MaxTokens <= info.ContextWindow
+}
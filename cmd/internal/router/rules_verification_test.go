@@ -0,0 +1,132 @@
+package router_test
+
+import (
+	"context"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/cmd/internal/router/filters"
+	"llm-router/types"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fixedIntent classifies every conversation into the same group.
+type fixedIntent string
+
+func (f fixedIntent) Classify(ctx context.Context, messages []types.Message) (string, error) {
+	return string(f), nil
+}
+
+func TestRuleEngine_Match(t *testing.T) {
+	plain, tool := setUpCapabilityCatalog(t)
+
+	manager := providers.NewProviderManager(providers.NewProviderFactory())
+	manager.SetProviders([]types.Provider{plain, tool})
+
+	engine, err := router.NewRuleEngine([]types.RoutingRule{
+		{Name: "long", If: "prompt.tokens > 5000", Use: "tool-ai/capable"},
+		{If: `headers.x-priority == "high" && consumer == "search"`, Tier: "premium"},
+		{Name: "coding", If: `intent == "coding"`, Providers: []string{"tool-ai"}},
+		{Name: "unconfigured", If: "messages.count > 3", Use: "missing-ai/model"},
+	}, fixedIntent("coding"), manager)
+	if err != nil {
+		t.Fatalf("NewRuleEngine() error = %v", err)
+	}
+
+	messages := []types.Message{{Role: "user", Content: "hello"}}
+
+	tests := []struct {
+		name  string
+		input types.RuleInput
+		want  string
+	}{
+		{
+			name:  "token count",
+			input: types.RuleInput{Messages: messages, Tokens: func() int { return 6000 }},
+			want:  "long",
+		},
+		{
+			name: "header and consumer, with a default name",
+			input: types.RuleInput{
+				Messages: messages,
+				Consumer: "search",
+				Headers:  map[string][]string{"X-Priority": {"high"}},
+				Tokens:   func() int { return 10 },
+			},
+			want: "rule-1",
+		},
+		{
+			name:  "intent",
+			input: types.RuleInput{Messages: messages, Tokens: func() int { return 10 }},
+			want:  "coding",
+		},
+		{
+			name: "pinned model lacking a capability is skipped",
+			input: types.RuleInput{
+				Messages:     messages,
+				Capabilities: []string{types.CapabilityVision},
+				Tokens:       func() int { return 6000 },
+			},
+			want: "coding",
+		},
+		{
+			name: "pinned model the consumer may not use is skipped",
+			input: types.RuleInput{
+				Messages: messages,
+				Models:   []string{"tool-ai/cheap"},
+				Tokens:   func() int { return 6000 },
+			},
+			want: "coding",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := engine.Match(context.Background(), &tt.input)
+			if rule == nil || rule.Name != tt.want {
+				t.Errorf("Match() = %+v, want rule %s", rule, tt.want)
+			}
+		})
+	}
+
+	// Rules limited to providers whose models the consumer may not use never match
+	if rule := engine.Match(context.Background(), &types.RuleInput{
+		Messages: messages,
+		Models:   []string{"plain-ai/cheap"},
+		Tokens:   func() int { return 10 },
+	}); rule != nil {
+		t.Errorf("Match() = %+v, want no rule", rule)
+	}
+
+	// A model whose provider isn't configured never matches
+	engine, _ = router.NewRuleEngine([]types.RoutingRule{{Use: "missing-ai/model"}}, nil, manager)
+	if rule := engine.Match(context.Background(), &types.RuleInput{Messages: messages}); rule != nil {
+		t.Errorf("Match() = %+v, want no rule", rule)
+	}
+}
+
+func TestCapabilityFilter_Providers(t *testing.T) {
+	plain, tool := setUpCapabilityCatalog(t)
+
+	manager := providers.NewProviderManager(providers.NewProviderFactory())
+	manager.SetProviders([]types.Provider{plain, tool})
+
+	base, err := router.NewRoundRobinRouter(manager, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRoundRobinRouter() error = %v", err)
+	}
+	pipeline := router.NewPipelineRouter(base, manager, nil, nil, nil)
+	pipeline.AddFilter(filters.NewCapabilityFilter(zap.NewNop()))
+
+	out, err := pipeline.SelectProvider(context.Background(), &types.SelectProviderInput{
+		Messages:  []types.Message{{Role: "user", Content: "hello"}},
+		Providers: []string{"tool-ai"},
+	})
+	if err != nil {
+		t.Fatalf("SelectProvider() error = %v", err)
+	}
+	if len(out.Candidates) != 1 || out.Candidates[0] != tool {
+		t.Errorf("expected only tool-ai to remain, got %d candidates", len(out.Candidates))
+	}
+}
@@ -0,0 +1,82 @@
+package router_test
+
+import (
+	"context"
+	"llm-router/cmd/internal/providers"
+	"llm-router/cmd/internal/router"
+	"llm-router/types"
+	"testing"
+)
+
+func TestCostRouter_TierStrategy(t *testing.T) {
+	providers.InitializeModelRegistry([]types.ModelConfig{
+		{ID: "cheap-ai/small", Provider: "cheap-ai", Name: "small", InputCostPer1M: 0.1, OutputCostPer1M: 0.1, Tier: "budget", Capabilities: []string{"chat"}},
+		{ID: "mid-ai/medium", Provider: "mid-ai", Name: "medium", InputCostPer1M: 1, OutputCostPer1M: 1, Tier: "standard", Capabilities: []string{"chat"}},
+		{ID: "top-ai/large", Provider: "top-ai", Name: "large", InputCostPer1M: 10, OutputCostPer1M: 10, Tier: "premium", Capabilities: []string{"chat"}},
+	}, nil)
+	t.Cleanup(func() {
+		providers.InitializeModelRegistry(providers.GetDefaultCatalog(), nil)
+	})
+
+	manager := providers.NewProviderManager(providers.NewProviderFactory())
+	manager.SetProviders([]types.Provider{
+		&lengthProvider{MockProvider{name: "cheap-ai"}},
+		&lengthProvider{MockProvider{name: "mid-ai"}},
+		&lengthProvider{MockProvider{name: "top-ai"}},
+	})
+
+	tests := []struct {
+		name        string
+		strategy    string
+		minimumTier string
+		openCircuit []string
+		wantModel   string
+		wantErr     bool
+	}{
+		{name: "same-tier", strategy: "same-tier", wantModel: "top-ai/large"},
+		{name: "same-tier without the tier available", strategy: "same-tier", openCircuit: []string{"top-ai"}, wantErr: true},
+		{name: "allow-downgrade keeps the tier when available", strategy: "allow-downgrade", wantModel: "top-ai/large"},
+		{name: "allow-downgrade to the next tier", strategy: "allow-downgrade", openCircuit: []string{"top-ai"}, wantModel: "mid-ai/medium"},
+		{name: "allow-downgrade skips empty tiers", strategy: "allow-downgrade", openCircuit: []string{"top-ai", "mid-ai"}, wantModel: "cheap-ai/small"},
+		{name: "allow-downgrade stops at the minimum tier", strategy: "allow-downgrade", minimumTier: "standard", openCircuit: []string{"top-ai", "mid-ai"}, wantErr: true},
+		{name: "cheapest ignores the tier", strategy: "cheapest", wantModel: "cheap-ai/small"},
+		{name: "cheapest ignores the minimum tier", strategy: "cheapest", minimumTier: "standard", wantModel: "cheap-ai/small"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			costRouter, err := router.NewCostRouter(manager, &types.CostOptions{
+				DefaultTier:  "premium",
+				MinimumTier:  tt.minimumTier,
+				TierStrategy: tt.strategy,
+			}, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("NewCostRouter() error = %v", err)
+			}
+
+			circuits := make(map[string]types.CircuitBreaker)
+			for _, name := range tt.openCircuit {
+				circuits[name] = &MockCircuitBreaker{canExecute: false}
+			}
+
+			out, err := costRouter.SelectProvider(context.Background(), &types.SelectProviderInput{
+				Messages: prompt(100),
+				Circuits: circuits,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SelectProvider() = %s, want an error", out.Model)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectProvider() error = %v", err)
+			}
+
+			info, _ := providers.GetModelInfo(tt.wantModel)
+			if out.Model != tt.wantModel || out.Tier != string(info.Tier) {
+				t.Errorf("SelectProvider() = %s (%s), want %s (%s)", out.Model, out.Tier, tt.wantModel, info.Tier)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -18,29 +19,108 @@ type UsageStats struct {
 	RequestCount int     `json:"request_count"`
 }
 
+// UsageTags attribute a request's usage, besides to the provider that served it.
+type UsageTags struct {
+	Consumer   string // Empty when authentication is disabled
+	Experiment string // Empty when the request isn't in an experiment
+	Variant    string
+	// Catalog ID of the model that answered; only set for complete responses, whose output
+	// lengths are recorded for cost estimates
+	Model string
+}
+
+// OutputTokenBuckets are the upper bounds of the TokenHistogram buckets. The last bucket also
+// holds every longer output.
+var OutputTokenBuckets = []int{16, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536, 131072}
+
+// TokenHistogram counts a model's responses by output length, one count per OutputTokenBuckets bucket.
+type TokenHistogram []int
+
+func outputTokenBucket(tokens int) int {
+	for i, bound := range OutputTokenBuckets {
+		if tokens <= bound {
+			return i
+		}
+	}
+	return len(OutputTokenBuckets) - 1
+}
+
+// Samples returns the number of responses in the histogram.
+func (h TokenHistogram) Samples() int {
+	total := 0
+	for _, count := range h {
+		total += count
+	}
+	return total
+}
+
+// Percentile returns the upper bound of the bucket holding the given percentile (1-100) of outputs.
+func (h TokenHistogram) Percentile(percentile float64) int {
+	target := float64(h.Samples()) * percentile / 100
+	seen := 0
+	for i, count := range h {
+		seen += count
+		if count > 0 && float64(seen) >= target {
+			return OutputTokenBuckets[i]
+		}
+	}
+	return OutputTokenBuckets[len(OutputTokenBuckets)-1]
+}
+
+// ExperimentStats are an experiment variant's results since the experiment started.
+type ExperimentStats struct {
+	Requests     int     `json:"requests"`
+	Errors       int     `json:"errors"`
+	LatencyMs    int64   `json:"latency_ms"` // Total across Requests
+	CostUSD      float64 `json:"cost_usd"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+}
+
 type UsageHistoryManager interface {
-	RecordUsage(ctx context.Context, provider string, cost float64, inputTokens, outputTokens int) error
+	// RecordUsage records a request served by provider
+	RecordUsage(ctx context.Context, provider string, tags UsageTags, cost float64, inputTokens, outputTokens int) error
+	// RecordExperimentResult records how a request assigned to an experiment variant ended
+	RecordExperimentResult(ctx context.Context, experiment, variant string, latency time.Duration, failed bool) error
+	// GetDailyUsage returns usage by provider, plus the "global" total
 	GetDailyUsage(ctx context.Context, date string) (map[string]*UsageStats, error)
+	// GetDailyConsumerUsage returns usage by consumer
+	GetDailyConsumerUsage(ctx context.Context, date string) (map[string]*UsageStats, error)
+	// GetExperimentStats returns the results of each experiment's variants, by experiment and variant
+	GetExperimentStats(ctx context.Context) (map[string]map[string]*ExperimentStats, error)
+	// GetOutputTokenHistograms returns the output lengths of each model's complete responses, by model
+	GetOutputTokenHistograms(ctx context.Context) (map[string]TokenHistogram, error)
 }
 
+// cumulativeRetention is how long results kept across days (experiment results and output
+// lengths) are kept after their last update.
+const cumulativeRetention = time.Hour * 24 * 90
+
 type RedisUsageHistoryManager struct {
-	client *redis.Client
-	logger *zap.Logger
+	client    *redis.Client
+	namespace string
+	logger    *zap.Logger
 }
 
-func NewRedisUsageHistoryManager(client *redis.Client, logger *zap.Logger) *RedisUsageHistoryManager {
+// NewRedisUsageHistoryManager records usage in Redis, under keys prefixed with namespace (if any).
+func NewRedisUsageHistoryManager(client *redis.Client, namespace string, logger *zap.Logger) *RedisUsageHistoryManager {
 	return &RedisUsageHistoryManager{
-		client: client,
-		logger: logger,
+		client:    client,
+		namespace: namespace,
+		logger:    logger,
 	}
 }
 
-func (m *RedisUsageHistoryManager) RecordUsage(ctx context.Context, provider string, cost float64, inputTokens, outputTokens int) error {
+func (m *RedisUsageHistoryManager) RecordUsage(ctx context.Context, provider string, tags UsageTags, cost float64, inputTokens, outputTokens int) error {
 	date := time.Now().Format("2006-01-02")
 
 	keys := []string{
-		fmt.Sprintf("usage:v1:%s:%s", date, provider),
-		fmt.Sprintf("usage:v1:%s:global", date),
+		namespacedKey(m.namespace, fmt.Sprintf("usage:v1:%s:%s", date, provider)),
+		namespacedKey(m.namespace, fmt.Sprintf("usage:v1:%s:global", date)),
+	}
+	if tags.Consumer != "" {
+		// Kept under a separate prefix so consumers don't mix with providers in GetDailyUsage
+		keys = append(keys, namespacedKey(m.namespace, fmt.Sprintf("usage:v1:consumers:%s:%s", date, tags.Consumer)))
 	}
 
 	for _, key := range keys {
@@ -57,12 +137,138 @@ func (m *RedisUsageHistoryManager) RecordUsage(ctx context.Context, provider str
 		}
 	}
 
+	if tags.Model != "" {
+		key := m.outputTokensKey(tags.Model)
+		pipe := m.client.Pipeline()
+		pipe.HIncrBy(ctx, key, strconv.Itoa(OutputTokenBuckets[outputTokenBucket(outputTokens)]), 1)
+		pipe.Expire(ctx, key, cumulativeRetention)
+
+		if _, err := pipe.Exec(ctx); err != nil {
+			m.logger.Error("Failed to record output length in Redis", zap.Error(err), zap.String("key", key))
+		}
+	}
+
+	if tags.Experiment != "" {
+		// Experiments are compared over their whole run, so their usage isn't kept per day
+		key := m.experimentKey(tags.Experiment, tags.Variant)
+		pipe := m.client.Pipeline()
+		pipe.HIncrByFloat(ctx, key, "cost", cost)
+		pipe.HIncrBy(ctx, key, "input_tokens", int64(inputTokens))
+		pipe.HIncrBy(ctx, key, "output_tokens", int64(outputTokens))
+		pipe.Expire(ctx, key, cumulativeRetention)
+
+		if _, err := pipe.Exec(ctx); err != nil {
+			m.logger.Error("Failed to record experiment usage in Redis", zap.Error(err), zap.String("key", key))
+		}
+	}
+
 	return nil
 }
 
+func (m *RedisUsageHistoryManager) RecordExperimentResult(ctx context.Context, experiment, variant string, latency time.Duration, failed bool) error {
+	key := m.experimentKey(experiment, variant)
+
+	pipe := m.client.Pipeline()
+	pipe.HIncrBy(ctx, key, "requests", 1)
+	if failed {
+		pipe.HIncrBy(ctx, key, "errors", 1)
+	}
+	pipe.HIncrBy(ctx, key, "latency_ms", latency.Milliseconds())
+	pipe.Expire(ctx, key, cumulativeRetention)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		m.logger.Error("Failed to record experiment result in Redis", zap.Error(err), zap.String("key", key))
+	}
+	return err
+}
+
+func (m *RedisUsageHistoryManager) GetExperimentStats(ctx context.Context) (map[string]map[string]*ExperimentStats, error) {
+	prefix := m.experimentKey("", "")
+	keys, err := m.client.Keys(ctx, prefix+"*").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]map[string]*ExperimentStats)
+	for _, key := range keys {
+		experiment, variant, found := strings.Cut(strings.TrimPrefix(key, prefix), ":")
+		if !found {
+			continue
+		}
+
+		data, err := m.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			continue
+		}
+
+		stats := &ExperimentStats{}
+		stats.Requests, _ = strconv.Atoi(data["requests"])
+		stats.Errors, _ = strconv.Atoi(data["errors"])
+		stats.LatencyMs, _ = strconv.ParseInt(data["latency_ms"], 10, 64)
+		stats.CostUSD, _ = strconv.ParseFloat(data["cost"], 64)
+		stats.InputTokens, _ = strconv.Atoi(data["input_tokens"])
+		stats.OutputTokens, _ = strconv.Atoi(data["output_tokens"])
+
+		if results[experiment] == nil {
+			results[experiment] = make(map[string]*ExperimentStats)
+		}
+		results[experiment][variant] = stats
+	}
+
+	return results, nil
+}
+
+func (m *RedisUsageHistoryManager) GetOutputTokenHistograms(ctx context.Context) (map[string]TokenHistogram, error) {
+	prefix := m.outputTokensKey("")
+	keys, err := m.client.Keys(ctx, prefix+"*").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]TokenHistogram)
+	for _, key := range keys {
+		data, err := m.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			continue
+		}
+
+		histogram := make(TokenHistogram, len(OutputTokenBuckets))
+		for i, bound := range OutputTokenBuckets {
+			histogram[i], _ = strconv.Atoi(data[strconv.Itoa(bound)])
+		}
+		results[strings.TrimPrefix(key, prefix)] = histogram
+	}
+
+	return results, nil
+}
+
+// outputTokensKey is the hash counting a model's responses by output length bucket. Output lengths
+// change slowly, so they are kept across days like experiment results.
+func (m *RedisUsageHistoryManager) outputTokensKey(model string) string {
+	return namespacedKey(m.namespace, "usage:v1:output-tokens:"+model)
+}
+
+// experimentKey is the hash holding a variant's results. Experiment and variant names can't
+// contain colons, so the key splits back into them.
+func (m *RedisUsageHistoryManager) experimentKey(experiment, variant string) string {
+	if experiment == "" {
+		return namespacedKey(m.namespace, "usage:v1:experiments:")
+	}
+	return namespacedKey(m.namespace, fmt.Sprintf("usage:v1:experiments:%s:%s", experiment, variant))
+}
+
 func (m *RedisUsageHistoryManager) GetDailyUsage(ctx context.Context, date string) (map[string]*UsageStats, error) {
 	// Identify all provider usage keys for that date
-	pattern := fmt.Sprintf("usage:v1:%s:*", date)
+	return m.readUsage(ctx, namespacedKey(m.namespace, fmt.Sprintf("usage:v1:%s:*", date)))
+}
+
+func (m *RedisUsageHistoryManager) GetDailyConsumerUsage(ctx context.Context, date string) (map[string]*UsageStats, error) {
+	return m.readUsage(ctx, namespacedKey(m.namespace, fmt.Sprintf("usage:v1:consumers:%s:*", date)))
+}
+
+// readUsage reads the usage hashes matching pattern, keyed by the last segment of their key.
+func (m *RedisUsageHistoryManager) readUsage(ctx context.Context, pattern string) (map[string]*UsageStats, error) {
 	keys, err := m.client.Keys(ctx, pattern).Result()
 	if err != nil {
 		return nil, err
@@ -98,18 +304,101 @@ func (m *RedisUsageHistoryManager) GetDailyUsage(ctx context.Context, date strin
 	return results, nil
 }
 
+// InMemoryUsageHistoryManager only keeps experiment results and output lengths, so experiments
+// can be compared and costs estimated without Redis. Daily usage isn't recorded.
 type InMemoryUsageHistoryManager struct {
-	// Not implemented for now, just a placeholder
+	mu           sync.Mutex
+	experiments  map[string]map[string]*ExperimentStats
+	outputTokens map[string]TokenHistogram
 }
 
 func NewInMemoryUsageHistoryManager() *InMemoryUsageHistoryManager {
-	return &InMemoryUsageHistoryManager{}
+	return &InMemoryUsageHistoryManager{
+		experiments:  make(map[string]map[string]*ExperimentStats),
+		outputTokens: make(map[string]TokenHistogram),
+	}
 }
 
-func (m *InMemoryUsageHistoryManager) RecordUsage(ctx context.Context, provider string, cost float64, inputTokens, outputTokens int) error {
+func (m *InMemoryUsageHistoryManager) RecordUsage(ctx context.Context, provider string, tags UsageTags, cost float64, inputTokens, outputTokens int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if tags.Model != "" {
+		histogram, ok := m.outputTokens[tags.Model]
+		if !ok {
+			histogram = make(TokenHistogram, len(OutputTokenBuckets))
+			m.outputTokens[tags.Model] = histogram
+		}
+		histogram[outputTokenBucket(outputTokens)]++
+	}
+
+	if tags.Experiment == "" {
+		return nil
+	}
+
+	stats := m.variantStats(tags.Experiment, tags.Variant)
+	stats.CostUSD += cost
+	stats.InputTokens += inputTokens
+	stats.OutputTokens += outputTokens
 	return nil
 }
 
+func (m *InMemoryUsageHistoryManager) RecordExperimentResult(ctx context.Context, experiment, variant string, latency time.Duration, failed bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.variantStats(experiment, variant)
+	stats.Requests++
+	if failed {
+		stats.Errors++
+	}
+	stats.LatencyMs += latency.Milliseconds()
+	return nil
+}
+
+// variantStats returns the variant's stats, creating them if needed. Callers must hold m.mu.
+func (m *InMemoryUsageHistoryManager) variantStats(experiment, variant string) *ExperimentStats {
+	if m.experiments[experiment] == nil {
+		m.experiments[experiment] = make(map[string]*ExperimentStats)
+	}
+	stats, ok := m.experiments[experiment][variant]
+	if !ok {
+		stats = &ExperimentStats{}
+		m.experiments[experiment][variant] = stats
+	}
+	return stats
+}
+
+func (m *InMemoryUsageHistoryManager) GetExperimentStats(ctx context.Context) (map[string]map[string]*ExperimentStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make(map[string]map[string]*ExperimentStats)
+	for experiment, variants := range m.experiments {
+		results[experiment] = make(map[string]*ExperimentStats)
+		for variant, stats := range variants {
+			copied := *stats
+			results[experiment][variant] = &copied
+		}
+	}
+	return results, nil
+}
+
 func (m *InMemoryUsageHistoryManager) GetDailyUsage(ctx context.Context, date string) (map[string]*UsageStats, error) {
 	return make(map[string]*UsageStats), nil
 }
+
+func (m *InMemoryUsageHistoryManager) GetDailyConsumerUsage(ctx context.Context, date string) (map[string]*UsageStats, error) {
+	return make(map[string]*UsageStats), nil
+}
+
+func (m *InMemoryUsageHistoryManager) GetOutputTokenHistograms(ctx context.Context) (map[string]TokenHistogram, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make(map[string]TokenHistogram, len(m.outputTokens))
+	for model, histogram := range m.outputTokens {
+		results[model] = append(TokenHistogram{}, histogram...)
+	}
+	return results, nil
+}
@@ -5,6 +5,7 @@ import (
 	"llm-router/cmd/internal/endpoints"
 	"llm-router/cmd/internal/metrics"
 	"llm-router/cmd/internal/middleware"
+	"llm-router/types"
 	"llm-router/utils"
 	"os"
 	"strconv"
@@ -40,11 +41,22 @@ func MetricsMiddleware() gin.HandlerFunc {
 }
 
 func Server() {
+	ginRouter := gin.Default()
+	ginRouter.Use(MetricsMiddleware())
+
 	// decide start up type
-	var resolver app.ConfigResolver
+	var resolvers app.RequestResolver
+	var auth []gin.HandlerFunc
 
 	if os.Getenv("MULTI_TENANT") == "true" {
-		resolver = &app.MultiTenantResolver{Logger: logger}
+		multiTenant, err := app.SetUpMultiTenant(tenantSettings())
+		if err != nil {
+			logger.Error("Failed to initialize multi-tenant mode", zap.Error(err))
+			os.Exit(1)
+		}
+		// Every API and admin request needs an API key, since it is what identifies the tenant
+		auth = append(auth, multiTenant.Authenticate())
+		resolvers = multiTenant
 	} else {
 		singleTenant, err := app.SetUpApp()
 		if err != nil {
@@ -53,22 +65,37 @@ func Server() {
 		}
 		resolverInstance := &app.SingleTenantResolver{}
 		resolverInstance.App.Store(singleTenant)
-		resolver = resolverInstance
+
+		// Keys are looked up on each request, so reloads can add, remove or revoke them
+		auth = append(auth, middleware.APIKeyAuth(resolverInstance.Consumers))
+		resolvers = resolverInstance
 	}
 
-	ginRouter := gin.Default()
-	ginRouter.Use(MetricsMiddleware())
+	// Same handlers work for both modes: the resolver serving each request is picked per request
+	endpoints.SetUpRoutes(resolvers, ginRouter, auth...)
 
-	if config := resolver.GetConfig(); config != nil && len(config.Security.APIKeys) > 0 {
-		ginRouter.Use(middleware.APIKeyAuth(config.Security.APIKeys))
+	logger.Info("Starting server on localhost:8000")
+	ginRouter.Run("localhost:8000")
+}
+
+// tenantSettings reads the multi-tenant mode settings from the environment.
+func tenantSettings() app.TenantSettings {
+	settings := app.TenantSettings{
+		Store: os.Getenv("TENANT_STORE"),
+		Dir:   os.Getenv("TENANT_DIR"),
+		Redis: types.RedisData{
+			Addr:     os.Getenv("REDIS_ADDR"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+		},
 	}
 
-	endpoints.SetUpRoutes(resolver, ginRouter)
+	if settings.Dir == "" {
+		settings.Dir = "tenants"
+	}
 
-	// Same handler works for both modes
-	// multi-tenant reasoning: all the same functions only difference is how config is gotten,
-	// validated per request API key, user config fetched and used on per request basis - normal web app standard
+	if size, err := strconv.Atoi(os.Getenv("TENANT_CACHE_SIZE")); err == nil {
+		settings.CacheSize = size
+	}
 
-	logger.Info("Starting server on localhost:8000")
-	ginRouter.Run("localhost:8000")
+	return settings
 }
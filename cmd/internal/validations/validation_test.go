@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"llm-router/types"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -42,6 +43,46 @@ func TestMessageValidation(t *testing.T) {
 			jsonBody:    `{"role": "user", "content": ""}`,
 			expectError: true,
 		},
+		{
+			name:        "assistant tool calls without content",
+			jsonBody:    `{"role": "assistant", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{}"}}]}`,
+			expectError: false,
+		},
+		{
+			name:        "tool call missing id",
+			jsonBody:    `{"role": "assistant", "tool_calls": [{"type": "function", "function": {"name": "get_weather"}}]}`,
+			expectError: true,
+		},
+		{
+			name:        "tool result",
+			jsonBody:    `{"role": "tool", "tool_call_id": "call_1", "content": "sunny"}`,
+			expectError: false,
+		},
+		{
+			name:        "tool result missing tool_call_id",
+			jsonBody:    `{"role": "tool", "content": "sunny"}`,
+			expectError: true,
+		},
+		{
+			name:        "content parts",
+			jsonBody:    `{"role": "user", "content": [{"type": "text", "text": "What's this?"}, {"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}}]}`,
+			expectError: false,
+		},
+		{
+			name:        "image only content",
+			jsonBody:    `{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw0KGgo="}}]}`,
+			expectError: false,
+		},
+		{
+			name:        "image part missing image_url",
+			jsonBody:    `{"role": "user", "content": [{"type": "image_url"}]}`,
+			expectError: true,
+		},
+		{
+			name:        "unknown content part type",
+			jsonBody:    `{"role": "user", "content": [{"type": "video", "text": "hi"}]}`,
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -66,6 +107,116 @@ func TestMessageValidation(t *testing.T) {
 	}
 }
 
+func TestCompletionToolValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tools := `"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}]`
+
+	tests := []struct {
+		name        string
+		jsonBody    string
+		expectError bool
+		wantChoice  *types.ToolChoice
+	}{
+		{
+			name:     "tools without tool_choice",
+			jsonBody: `{"messages": [{"role": "user", "content": "Hi"}], ` + tools + `}`,
+		},
+		{
+			name:       "string tool_choice",
+			jsonBody:   `{"messages": [{"role": "user", "content": "Hi"}], ` + tools + `, "tool_choice": "required"}`,
+			wantChoice: &types.ToolChoice{Mode: types.ToolChoiceRequired},
+		},
+		{
+			name:       "function tool_choice",
+			jsonBody:   `{"messages": [{"role": "user", "content": "Hi"}], ` + tools + `, "tool_choice": {"type": "function", "function": {"name": "get_weather"}}}`,
+			wantChoice: &types.ToolChoice{Mode: types.ToolChoiceFunction, Function: "get_weather"},
+		},
+		{
+			name:        "unknown tool_choice mode",
+			jsonBody:    `{"messages": [{"role": "user", "content": "Hi"}], ` + tools + `, "tool_choice": "always"}`,
+			expectError: true,
+		},
+		{
+			name:        "unsupported tool type",
+			jsonBody:    `{"messages": [{"role": "user", "content": "Hi"}], "tools": [{"type": "retrieval", "function": {"name": "search"}}]}`,
+			expectError: true,
+		},
+		{
+			name:        "tool missing function name",
+			jsonBody:    `{"messages": [{"role": "user", "content": "Hi"}], "tools": [{"type": "function", "function": {}}]}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(tt.jsonBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			var completion types.Completion
+			err := c.ShouldBindJSON(&completion)
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected validation error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if !reflect.DeepEqual(completion.ToolChoice, tt.wantChoice) {
+				t.Errorf("ToolChoice = %+v, want %+v", completion.ToolChoice, tt.wantChoice)
+			}
+			if got := completion.RequiredCapabilities(); len(got) != 1 || got[0] != types.CapabilityTools {
+				t.Errorf("RequiredCapabilities() = %v, want [%s]", got, types.CapabilityTools)
+			}
+		})
+	}
+}
+
+func TestCompletionResponseFormatValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		format      string
+		expectError bool
+	}{
+		{name: "json_object", format: `{"type": "json_object"}`},
+		{name: "json_schema", format: `{"type": "json_schema", "json_schema": {"name": "person", "schema": {"type": "object"}}}`},
+		{name: "json_schema without schema", format: `{"type": "json_schema"}`, expectError: true},
+		{name: "json_schema without name", format: `{"type": "json_schema", "json_schema": {"schema": {"type": "object"}}}`, expectError: true},
+		{name: "unknown type", format: `{"type": "yaml"}`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body := `{"messages": [{"role": "user", "content": "Hi"}], "response_format": ` + tt.format + `}`
+			c.Request = httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			var completion types.Completion
+			err := c.ShouldBindJSON(&completion)
+
+			if tt.expectError && err == nil {
+				t.Error("Expected validation error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
 func TestHandleValidationError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -93,3 +244,103 @@ func TestHandleValidationError(t *testing.T) {
 		t.Error("Expected non-empty response body")
 	}
 }
+
+func TestAnthropicMessagesRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		jsonBody     string
+		expectError  bool
+		wantMessages []types.Message
+	}{
+		{
+			name:     "string content with system prompt",
+			jsonBody: `{"model": "claude-sonnet-4", "max_tokens": 256, "system": "Be brief.", "messages": [{"role": "user", "content": "Hello"}]}`,
+			wantMessages: []types.Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Content: "Hello"},
+			},
+		},
+		{
+			name:     "text content blocks",
+			jsonBody: `{"model": "claude-sonnet-4", "max_tokens": 256, "system": [{"type": "text", "text": "Be brief."}], "messages": [{"role": "user", "content": [{"type": "text", "text": "Hello"}, {"type": "text", "text": "there"}]}]}`,
+			wantMessages: []types.Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Content: "Hello\n\nthere"},
+			},
+		},
+		{
+			name:     "image content blocks",
+			jsonBody: `{"model": "claude-sonnet-4", "max_tokens": 256, "messages": [{"role": "user", "content": [{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "aGk="}}, {"type": "image", "source": {"type": "url", "url": "https://example.com/cat.png"}}, {"type": "text", "text": "Compare these"}]}]}`,
+			wantMessages: []types.Message{
+				{Role: "user", Content: "Compare these", Parts: []types.ContentPart{
+					{Type: types.ContentPartImageURL, ImageURL: &types.ImageURL{URL: "data:image/png;base64,aGk="}},
+					{Type: types.ContentPartImageURL, ImageURL: &types.ImageURL{URL: "https://example.com/cat.png"}},
+					{Type: types.ContentPartText, Text: "Compare these"},
+				}},
+			},
+		},
+		{
+			name:        "image block without a source",
+			jsonBody:    `{"model": "claude-sonnet-4", "max_tokens": 256, "messages": [{"role": "user", "content": [{"type": "image", "source": {}}]}]}`,
+			expectError: true,
+		},
+		{
+			name:        "unsupported content block",
+			jsonBody:    `{"model": "claude-sonnet-4", "max_tokens": 256, "messages": [{"role": "user", "content": [{"type": "document", "source": {}}]}]}`,
+			expectError: true,
+		},
+		{
+			name:        "empty content",
+			jsonBody:    `{"model": "claude-sonnet-4", "max_tokens": 256, "messages": [{"role": "user", "content": ""}]}`,
+			expectError: true,
+		},
+		{
+			name:        "missing max_tokens",
+			jsonBody:    `{"model": "claude-sonnet-4", "messages": [{"role": "user", "content": "Hello"}]}`,
+			expectError: true,
+		},
+		{
+			name:        "system role in messages",
+			jsonBody:    `{"model": "claude-sonnet-4", "max_tokens": 256, "messages": [{"role": "system", "content": "Hello"}]}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(tt.jsonBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			var request types.AnthropicMessagesRequest
+			err := c.ShouldBindJSON(&request)
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected validation error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			completion := request.ToCompletion()
+			if len(completion.Messages) != len(tt.wantMessages) {
+				t.Fatalf("Expected %d messages, got %d", len(tt.wantMessages), len(completion.Messages))
+			}
+			for i, want := range tt.wantMessages {
+				if !reflect.DeepEqual(completion.Messages[i], want) {
+					t.Errorf("Message %d = %+v, want %+v", i, completion.Messages[i], want)
+				}
+			}
+			if completion.MaxTokens == nil || *completion.MaxTokens != 256 {
+				t.Errorf("Expected max_tokens to be carried over, got %v", completion.MaxTokens)
+			}
+		})
+	}
+}
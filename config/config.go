@@ -1,10 +1,14 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"llm-router/expr"
 	"llm-router/types"
 	"llm-router/utils"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -19,6 +23,7 @@ type Config struct {
 	CacheConfig types.CacheData        `mapstructure:"cache"`
 	Redis       types.RedisData        `mapstructure:"redis"`
 	Security    types.SecurityData     `mapstructure:"security"`
+	Experiments []types.ExperimentData `mapstructure:"experiments"`
 }
 
 var logger = utils.SetUpLogger()
@@ -67,6 +72,48 @@ func LoadConfig() (*Config, error) {
 		config.Routing.Policies.Semantic.SharedLibPath = os.ExpandEnv(config.Routing.Policies.Semantic.SharedLibPath)
 	}
 
+	config.CacheConfig.Semantic.SharedLibPath = os.ExpandEnv(config.CacheConfig.Semantic.SharedLibPath)
+
+	config.DeduplicateProviders()
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &config, nil
+}
+
+// ParseConfig reads a tenant's configuration from YAML. Unlike LoadConfig, environment variables
+// are not expanded, since a tenant must not be able to read the server's environment. Tenants
+// share the server's Redis, which replaces any redis section, and may not extend the model
+// catalog, which is shared by every tenant; providers whose models must be added to the catalog
+// (openai-compatible and azure-openai) are rejected.
+func ParseConfig(data []byte, redis types.RedisData) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	if len(config.Models.Catalog) > 0 {
+		return nil, fmt.Errorf("invalid configuration: models.catalog can only be set in the server's config")
+	}
+
+	// Their models are only known from the catalog, so they could never be routed to
+	for _, p := range config.Providers {
+		if strings.EqualFold(p.Type, "openai-compatible") || strings.EqualFold(p.Type, "azure-openai") {
+			return nil, fmt.Errorf("invalid configuration: provider %s: %s providers need models.catalog entries, which tenants can't set", p.Name, p.Type)
+		}
+	}
+
+	config.Redis = redis
+
 	config.DeduplicateProviders()
 
 	if err := config.Validate(); err != nil {
@@ -85,11 +132,25 @@ func (c *Config) GetEnabledProviders() []types.ProviderConfigWithExtras {
 
 			providerDefaults := c.GetDefaultModelConfigDataByName(provider.Name)
 			providerWithExtras := types.ProviderConfigWithExtras{
-				Name:    strings.ToLower(provider.Name),
-				APIKey:  provider.APIKey,
-				Enabled: provider.Enabled,
-				Timeout: c.Resilience.Timeout,
-				Limits:  c.GetLimitsConfigDataByName(provider.Name),
+				Name:          strings.ToLower(provider.Name),
+				Type:          strings.ToLower(provider.Type),
+				APIKey:        provider.APIKey,
+				APIKeys:       provider.APIKeys,
+				KeySelection:  strings.ToLower(provider.KeySelection),
+				KeyQuarantine: provider.KeyQuarantine,
+				BaseURL:       provider.BaseURL,
+				Headers:       provider.Headers,
+				APIVersion:    provider.APIVersion,
+				Enabled:       provider.Enabled,
+				Timeout:       c.Resilience.Timeout,
+				Limits:        c.GetLimitsConfigDataByName(provider.Name),
+			}
+
+			if len(provider.Deployments) > 0 {
+				providerWithExtras.Deployments = make(map[string]string, len(provider.Deployments))
+				for _, d := range provider.Deployments {
+					providerWithExtras.Deployments[d.Model] = d.Deployment
+				}
 			}
 
 			if providerDefaults != nil {
@@ -203,6 +264,30 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("at least one provider must be enabled")
 	}
 
+	for _, p := range c.Providers {
+		if strings.EqualFold(p.Type, "openai-compatible") && p.BaseURL == "" {
+			return fmt.Errorf("provider %s: baseURL is required for openai-compatible providers", p.Name)
+		}
+		switch strings.ToLower(p.KeySelection) {
+		case "", "round-robin", "least-used", "failover":
+		default:
+			return fmt.Errorf("provider %s: keySelection must be round-robin, least-used or failover (got %q)", p.Name, p.KeySelection)
+		}
+		if p.KeyQuarantine < 0 {
+			return fmt.Errorf("provider %s: keyQuarantine cannot be negative", p.Name)
+		}
+		if strings.EqualFold(p.Type, "azure-openai") {
+			if p.BaseURL == "" || p.APIVersion == "" {
+				return fmt.Errorf("provider %s: baseURL and apiVersion are required for azure-openai providers", p.Name)
+			}
+			for i, d := range p.Deployments {
+				if d.Deployment == "" || !strings.HasPrefix(d.Model, strings.ToLower(p.Name)+"/") {
+					return fmt.Errorf("provider %s: deployment %d needs a deployment name and one of the provider's models (got %q)", p.Name, i, d.Model)
+				}
+			}
+		}
+	}
+
 	if c.Routing.Strategy == "weighted" {
 		if len(c.Routing.Weights) == 0 {
 			return fmt.Errorf("weighted routing strategy requires weights to be defined")
@@ -214,6 +299,18 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if options := c.Routing.CostOptions; options != nil {
+		if options.OutputPercentile < 0 || options.OutputPercentile > 100 {
+			return fmt.Errorf("routing costOptions outputPercentile must be between 1 and 100 (got %v)", options.OutputPercentile)
+		}
+
+		switch options.TierStrategy {
+		case "", "same-tier", "allow-downgrade", "cheapest":
+		default:
+			return fmt.Errorf("routing costOptions tierStrategy must be same-tier, allow-downgrade or cheapest (got %q)", options.TierStrategy)
+		}
+	}
+
 	if c.Resilience.Timeout <= 0 {
 		return fmt.Errorf("resilience timeout must be greater than 0")
 	}
@@ -222,5 +319,194 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("redis address is required when caching is enabled")
 	}
 
+	if threshold := c.CacheConfig.Semantic.SimilarityThreshold; threshold < 0 || threshold > 1 {
+		return fmt.Errorf("cache similarity threshold must be between 0 and 1 (got %v)", threshold)
+	}
+
+	for i, model := range c.Models.Catalog {
+		if model.Provider == "" || !strings.HasPrefix(model.ID, model.Provider+"/") {
+			return fmt.Errorf("models catalog entry %d: id must be in provider/model form and match its provider (got %q, provider %q)", i, model.ID, model.Provider)
+		}
+		if model.MaxTokens < 0 {
+			return fmt.Errorf("models catalog entry %s: maxTokens cannot be negative", model.ID)
+		}
+		if model.Type != "" && model.Type != types.ModelTypeChat && model.Type != types.ModelTypeEmbedding {
+			return fmt.Errorf("models catalog entry %s: type must be %q or %q (got %q)", model.ID, types.ModelTypeChat, types.ModelTypeEmbedding, model.Type)
+		}
+		if model.Dimensions < 0 {
+			return fmt.Errorf("models catalog entry %s: dimensions cannot be negative", model.ID)
+		}
+	}
+
+	if err := c.validateConsumers(); err != nil {
+		return err
+	}
+
+	if err := c.validateRoutingRules(); err != nil {
+		return err
+	}
+
+	if err := c.validateExperiments(); err != nil {
+		return err
+	}
+
+	for i, rule := range c.CacheConfig.Rules {
+		if rule.MaxSize < 0 || rule.Ttl < 0 {
+			return fmt.Errorf("cache rule %d: maxSize and ttl cannot be negative", i)
+		}
+		if rule.Scope != "" && rule.Scope != "shared" && rule.Scope != "user" {
+			return fmt.Errorf("cache rule %d: scope must be \"shared\" or \"user\" (got %q)", i, rule.Scope)
+		}
+		if rule.If != "" {
+			if _, err := expr.Compile(rule.If, types.CacheRuleFields); err != nil {
+				return fmt.Errorf("cache rule %d: %w", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateConsumers() error {
+	names := map[string]bool{types.DefaultConsumer: true}
+	keys := make(map[string]bool)
+	for _, key := range c.Security.APIKeys {
+		keys[key] = true
+	}
+
+	for i, consumer := range c.Security.Consumers {
+		if consumer.Name == "" {
+			return fmt.Errorf("security consumer %d: name is required", i)
+		}
+		if names[consumer.Name] {
+			return fmt.Errorf("security consumer %s: name is already used", consumer.Name)
+		}
+		names[consumer.Name] = true
+
+		for _, key := range consumer.APIKeys {
+			if key == "" {
+				continue
+			}
+			if keys[key] {
+				return fmt.Errorf("security consumer %s: API key is already used by another consumer", consumer.Name)
+			}
+			keys[key] = true
+		}
+
+		if consumer.RequestsPerMinute < 0 || consumer.RequestsPerDay < 0 || consumer.DailyBudget < 0 {
+			return fmt.Errorf("security consumer %s: limits cannot be negative", consumer.Name)
+		}
+		for _, tier := range consumer.Tiers {
+			switch tier {
+			case "budget", "standard", "premium", "ultra-premium":
+			default:
+				return fmt.Errorf("security consumer %s: unknown tier %q", consumer.Name, tier)
+			}
+		}
+		for _, model := range consumer.Models {
+			if !strings.Contains(model, "/") {
+				return fmt.Errorf("security consumer %s: model %q must be a provider/model catalog ID", consumer.Name, model)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateRoutingRules() error {
+	names := make(map[string]bool)
+
+	for i, rule := range c.Routing.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule-%d", i)
+		}
+		if names[name] {
+			return fmt.Errorf("routing rule %s: name is already used", name)
+		}
+		names[name] = true
+
+		if rule.If != "" {
+			if _, err := expr.Compile(rule.If, types.RoutingRuleFields); err != nil {
+				return fmt.Errorf("routing rule %s: %w", name, err)
+			}
+		}
+
+		if rule.Use == "" && len(rule.Providers) == 0 && rule.Tier == "" {
+			return fmt.Errorf("routing rule %s: needs use, providers or tier", name)
+		}
+
+		if rule.Use != "" {
+			provider, _, found := strings.Cut(rule.Use, "/")
+			if !found || provider == "" {
+				return fmt.Errorf("routing rule %s: use must be a provider/model catalog ID (got %q)", name, rule.Use)
+			}
+			if len(rule.Providers) > 0 && !slices.Contains(rule.Providers, provider) {
+				return fmt.Errorf("routing rule %s: model %s is not from one of the rule's providers", name, rule.Use)
+			}
+		}
+
+		switch rule.Tier {
+		case "", "budget", "standard", "premium", "ultra-premium":
+		default:
+			return fmt.Errorf("routing rule %s: unknown tier %q", name, rule.Tier)
+		}
+	}
+
+	return nil
+}
+
+// experimentName keeps experiment and variant names usable in Redis keys and metric labels.
+var experimentName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func (c *Config) validateExperiments() error {
+	names := make(map[string]bool)
+
+	for i, experiment := range c.Experiments {
+		if !experimentName.MatchString(experiment.Name) {
+			return fmt.Errorf("experiment %d: name may only contain letters, digits, underscores and dashes (got %q)", i, experiment.Name)
+		}
+		if names[experiment.Name] {
+			return fmt.Errorf("experiment %s: name is already used", experiment.Name)
+		}
+		names[experiment.Name] = true
+
+		if experiment.Traffic <= 0 || experiment.Traffic > 1 {
+			return fmt.Errorf("experiment %s: traffic must be greater than 0 and at most 1 (got %v)", experiment.Name, experiment.Traffic)
+		}
+
+		if header, ok := strings.CutPrefix(experiment.StickyKey, "header:"); ok {
+			if header == "" {
+				return fmt.Errorf("experiment %s: stickyKey header name is empty", experiment.Name)
+			}
+		} else if experiment.StickyKey != "" && experiment.StickyKey != "apiKey" && experiment.StickyKey != "user" {
+			return fmt.Errorf("experiment %s: stickyKey must be apiKey, user or header:<name> (got %q)", experiment.Name, experiment.StickyKey)
+		}
+
+		if len(experiment.Variants) == 0 {
+			return fmt.Errorf("experiment %s: at least one variant is required", experiment.Name)
+		}
+		for name, variant := range experiment.Variants {
+			if !experimentName.MatchString(name) {
+				return fmt.Errorf("experiment %s: variant name may only contain letters, digits, underscores and dashes (got %q)", experiment.Name, name)
+			}
+			if variant.Provider == "" && variant.Model == "" {
+				return fmt.Errorf("experiment %s: variant %s needs a provider or a model", experiment.Name, name)
+			}
+			if variant.Model != "" {
+				provider, _, found := strings.Cut(variant.Model, "/")
+				if !found || provider == "" {
+					return fmt.Errorf("experiment %s: variant %s model must be a provider/model catalog ID (got %q)", experiment.Name, name, variant.Model)
+				}
+				if variant.Provider != "" && variant.Provider != provider {
+					return fmt.Errorf("experiment %s: variant %s model %s is not from provider %s", experiment.Name, name, variant.Model, variant.Provider)
+				}
+			}
+			if variant.Weight < 0 {
+				return fmt.Errorf("experiment %s: variant %s weight cannot be negative", experiment.Name, name)
+			}
+		}
+	}
+
 	return nil
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"llm-router/types"
 	"testing"
 )
@@ -68,6 +69,20 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Unknown Tier Strategy",
+			config: Config{
+				Providers: []types.ProviderConfig{
+					{Name: "openai", Enabled: true},
+				},
+				Routing: types.RoutingData{
+					Strategy:    "cost-based",
+					CostOptions: &types.CostOptions{TierStrategy: "downgrade"},
+				},
+				Resilience: types.ResilienceData{Timeout: 30},
+			},
+			wantErr: true,
+		},
 		{
 			name: "Zero Timeout",
 			config: Config{
@@ -78,6 +93,244 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Valid Cache Rules",
+			config: Config{
+				Providers: []types.ProviderConfig{
+					{Name: "openai", Enabled: true},
+				},
+				Resilience: types.ResilienceData{Timeout: 30},
+				CacheConfig: types.CacheData{
+					Rules: []types.CacheRule{
+						{If: `request.user == "premium"`, Cache: false},
+						{If: "request.temperature == 0 && request.tokens < 2000", Cache: true, Ttl: 600, Scope: "user"},
+						{Cache: true, MaxSize: 1024},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Cache Rule With Unknown Field",
+			config: Config{
+				Providers: []types.ProviderConfig{
+					{Name: "openai", Enabled: true},
+				},
+				Resilience: types.ResilienceData{Timeout: 30},
+				CacheConfig: types.CacheData{
+					Rules: []types.CacheRule{{If: "prompt.length > 5000", Cache: true}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Cache Rule With Invalid Syntax",
+			config: Config{
+				Providers: []types.ProviderConfig{
+					{Name: "openai", Enabled: true},
+				},
+				Resilience: types.ResilienceData{Timeout: 30},
+				CacheConfig: types.CacheData{
+					Rules: []types.CacheRule{{If: "request.temperature ==", Cache: true}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Cache Rule With Unknown Scope",
+			config: Config{
+				Providers: []types.ProviderConfig{
+					{Name: "openai", Enabled: true},
+				},
+				Resilience: types.ResilienceData{Timeout: 30},
+				CacheConfig: types.CacheData{
+					Rules: []types.CacheRule{{Cache: true, Scope: "tenant"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unknown Key Selection",
+			config: Config{
+				Providers: []types.ProviderConfig{
+					{Name: "openai", Enabled: true, APIKeys: []string{"sk-2"}, KeySelection: "random"},
+				},
+				Resilience: types.ResilienceData{Timeout: 30},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Azure Provider",
+			config: Config{
+				Providers: []types.ProviderConfig{
+					{
+						Name: "azure-eastus", Type: "azure-openai", Enabled: true,
+						BaseURL: "https://example.openai.azure.com", APIVersion: "2024-10-21",
+						Deployments: []types.ModelDeployment{{Model: "azure-eastus/gpt-4o", Deployment: "gpt4o-prod"}},
+					},
+				},
+				Resilience: types.ResilienceData{Timeout: 30},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Azure Provider Without API Version",
+			config: Config{
+				Providers: []types.ProviderConfig{
+					{Name: "azure-eastus", Type: "azure-openai", Enabled: true, BaseURL: "https://example.openai.azure.com"},
+				},
+				Resilience: types.ResilienceData{Timeout: 30},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Azure Deployment For Another Provider's Model",
+			config: Config{
+				Providers: []types.ProviderConfig{
+					{
+						Name: "azure-eastus", Type: "azure-openai", Enabled: true,
+						BaseURL: "https://example.openai.azure.com", APIVersion: "2024-10-21",
+						Deployments: []types.ModelDeployment{{Model: "openai/gpt-4o", Deployment: "gpt4o-prod"}},
+					},
+				},
+				Resilience: types.ResilienceData{Timeout: 30},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Consumers",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Security: types.SecurityData{
+					APIKeys: []string{"sk-shared"},
+					Consumers: []types.ConsumerData{
+						{Name: "search", APIKeys: []string{"sk-search"}, RequestsPerDay: 1000, DailyBudget: 5, Tiers: []string{"budget"}},
+						{Name: "support", APIKeys: []string{"sk-support"}, Models: []string{"openai/gpt-4o-mini"}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Consumer Reusing An API Key",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Security: types.SecurityData{
+					APIKeys:   []string{"sk-shared"},
+					Consumers: []types.ConsumerData{{Name: "search", APIKeys: []string{"sk-shared"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Consumer Named Default",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Security: types.SecurityData{
+					Consumers: []types.ConsumerData{{Name: types.DefaultConsumer, APIKeys: []string{"sk-search"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Consumer With Unknown Tier",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Security: types.SecurityData{
+					Consumers: []types.ConsumerData{{Name: "search", APIKeys: []string{"sk-search"}, Tiers: []string{"gold"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Routing Rules",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Routing: types.RoutingData{Rules: []types.RoutingRule{
+					{Name: "long-prompts", If: "prompt.tokens > 50000", Use: "anthropic/claude-sonnet-4"},
+					{If: `headers.x-priority == "high"`, Tier: "premium", Providers: []string{"openai"}},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Routing Rule With Unknown Field",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Routing:    types.RoutingData{Rules: []types.RoutingRule{{If: `task == "code"`, Use: "openai/gpt-4o"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Routing Rule Without Action",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Routing:    types.RoutingData{Rules: []types.RoutingRule{{If: "messages.count > 10"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Routing Rule Using A Model Outside Its Providers",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Routing: types.RoutingData{Rules: []types.RoutingRule{
+					{Use: "openai/gpt-4o", Providers: []string{"anthropic"}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Experiments",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Experiments: []types.ExperimentData{{
+					Name:      "claude-vs-gpt4",
+					Enabled:   true,
+					Traffic:   0.1,
+					StickyKey: "header:X-Session-Id",
+					Variants: map[string]types.ExperimentVariant{
+						"control":   {Provider: "openai", Model: "openai/gpt-4o"},
+						"treatment": {Model: "anthropic/claude-sonnet-4", Weight: 2},
+					},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Experiment With Invalid Traffic",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Experiments: []types.ExperimentData{{
+					Name:     "gpt4",
+					Traffic:  1.5,
+					Variants: map[string]types.ExperimentVariant{"control": {Provider: "openai"}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Experiment Variant Model Outside Its Provider",
+			config: Config{
+				Providers:  []types.ProviderConfig{{Name: "openai", Enabled: true}},
+				Resilience: types.ResilienceData{Timeout: 30},
+				Experiments: []types.ExperimentData{{
+					Name:     "gpt4",
+					Traffic:  0.5,
+					Variants: map[string]types.ExperimentVariant{"control": {Provider: "anthropic", Model: "openai/gpt-4o"}},
+				}},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,3 +341,35 @@ func TestConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestParseConfig_TenantProviderTypes(t *testing.T) {
+	const tenant = `
+providers:
+  - name: %s
+    type: %s
+    apiKey: sk-tenant
+    baseURL: https://llm.internal/v1
+    enabled: true
+resilience:
+  timeout: 30000
+`
+
+	tests := []struct {
+		name         string
+		providerType string
+		wantErr      bool
+	}{
+		{name: "openai", providerType: "openai"},
+		{name: "local", providerType: "openai-compatible", wantErr: true},
+		{name: "azure-eastus", providerType: "azure-openai", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.providerType, func(t *testing.T) {
+			_, err := ParseConfig([]byte(fmt.Sprintf(tenant, tt.name, tt.providerType)), types.RedisData{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
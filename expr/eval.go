@@ -0,0 +1,176 @@
+package expr
+
+import "fmt"
+
+type node interface {
+	eval(env Env) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+type identNode struct {
+	name string
+}
+
+type notNode struct {
+	operand node
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *literalNode) eval(env Env) (any, error) {
+	return n.value, nil
+}
+
+func (n *identNode) eval(env Env) (any, error) {
+	if env == nil {
+		return nil, nil
+	}
+	return normalize(env(n.name)), nil
+}
+
+func (n *notNode) eval(env Env) (any, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate %v", value)
+	}
+	return !b, nil
+}
+
+func (n *listNode) eval(env Env) (any, error) {
+	values := make([]any, len(n.items))
+	for i, item := range n.items {
+		value, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (n *binaryNode) eval(env Env) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit logical operators
+	switch n.op {
+	case "&&", "||":
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a boolean", left)
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a boolean", right)
+		}
+		return r, nil
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "in":
+		for _, item := range right.([]any) {
+			if item == left {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	// Ordering comparisons involving a missing value are false rather than an error,
+	// e.g. `request.temperature < 0.5` when no temperature was sent.
+	if left == nil || right == nil {
+		return false, nil
+	}
+
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %v with %v", left, right)
+		}
+		return compare(n.op, l, r), nil
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %v with %v", left, right)
+		}
+		return compare(n.op, l, r), nil
+	default:
+		return nil, fmt.Errorf("cannot order %v", left)
+	}
+}
+
+func compare[T float64 | string](op string, l, r T) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	default:
+		return l >= r
+	}
+}
+
+// normalize converts env values into the handful of types the evaluator works with.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case *float64:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *int:
+		if v == nil {
+			return nil
+		}
+		return float64(*v)
+	case *string:
+		if v == nil {
+			return nil
+		}
+		return *v
+	default:
+		return v
+	}
+}
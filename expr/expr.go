@@ -0,0 +1,113 @@
+// Package expr implements the small condition language used by config rules,
+// e.g. `request.temperature == 0 && request.tier != "premium"`.
+//
+// Supported syntax:
+//   - literals: numbers, "strings" or 'strings', true, false, null
+//   - identifiers: dotted names such as request.tokens, resolved at evaluation time
+//   - comparison: == != < <= > >=, and `in [..]` for list membership
+//   - logic: && (and), || (or), ! (not), parentheses
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Env resolves an identifier to its value for a single evaluation.
+// Supported value types are numbers, strings, bools and nil (including nil pointers).
+type Env func(name string) any
+
+// Expression is a compiled condition that can be evaluated many times.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses source and checks that it only references the given identifiers.
+// An identifier ending in ".*" (e.g. headers.*) allows any name under that prefix.
+func Compile(source string, identifiers []string) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpression()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+
+	if !p.done() {
+		return nil, fmt.Errorf("invalid expression %q: unexpected %q", source, p.peek().text)
+	}
+
+	allowed := make(map[string]bool, len(identifiers))
+	for _, id := range identifiers {
+		allowed[id] = true
+	}
+
+	if err := checkIdentifiers(root, allowed, identifiers); err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+
+	if lit, ok := root.(*literalNode); ok {
+		if _, isBool := lit.value.(bool); !isBool {
+			return nil, fmt.Errorf("invalid expression %q: must evaluate to true or false", source)
+		}
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source the expression was compiled from.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression against env.
+func (e *Expression) Eval(env Env) (bool, error) {
+	value, err := e.root.eval(env)
+	if err != nil {
+		return false, fmt.Errorf("evaluating %q: %w", e.source, err)
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("evaluating %q: result is %v, not a boolean", e.source, value)
+	}
+
+	return result, nil
+}
+
+func checkIdentifiers(n node, allowed map[string]bool, identifiers []string) error {
+	switch n := n.(type) {
+	case *identNode:
+		if !allowed[n.name] && !allowedPrefix(n.name, identifiers) {
+			return fmt.Errorf("unknown field %q (supported: %s)", n.name, strings.Join(identifiers, ", "))
+		}
+	case *notNode:
+		return checkIdentifiers(n.operand, allowed, identifiers)
+	case *binaryNode:
+		if err := checkIdentifiers(n.left, allowed, identifiers); err != nil {
+			return err
+		}
+		return checkIdentifiers(n.right, allowed, identifiers)
+	case *listNode:
+		for _, item := range n.items {
+			if err := checkIdentifiers(item, allowed, identifiers); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func allowedPrefix(name string, identifiers []string) bool {
+	for _, id := range identifiers {
+		prefix, ok := strings.CutSuffix(id, "*")
+		if ok && strings.HasSuffix(prefix, ".") && len(name) > len(prefix) && strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package expr
+
+import "testing"
+
+var fields = []string{"request.temperature", "request.user", "request.tokens", "request.tier"}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "unknown field", source: "prompt.length > 10"},
+		{name: "missing operand", source: "request.tokens >"},
+		{name: "unbalanced parentheses", source: "(request.tokens > 10"},
+		{name: "unterminated string", source: `request.user == "premium`},
+		{name: "trailing tokens", source: "request.tokens > 10 10"},
+		{name: "non-boolean literal", source: "42"},
+		{name: "unknown character", source: "request.tokens % 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.source, fields); err == nil {
+				t.Errorf("Compile(%q) expected an error", tt.source)
+			}
+		})
+	}
+}
+
+func TestExpression_Eval(t *testing.T) {
+	zero := 0.0
+	warm := 0.7
+
+	values := map[string]any{
+		"request.temperature": &zero,
+		"request.user":        "premium",
+		"request.tokens":      512,
+		"request.tier":        "budget",
+	}
+	env := func(name string) any { return values[name] }
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{source: "request.temperature == 0", want: true},
+		{source: "request.temperature != 0", want: false},
+		{source: `request.user == "premium"`, want: true},
+		{source: "request.user == 'free'", want: false},
+		{source: "request.tokens <= 512 && request.tokens > 100", want: true},
+		{source: "request.tokens < 100 || request.tier == \"budget\"", want: true},
+		{source: `request.tier in ["premium", "ultra-premium"]`, want: false},
+		{source: `not (request.tier in ["premium", "ultra-premium"])`, want: true},
+		{source: "!(request.temperature == 0) and request.tokens > 0", want: false},
+		{source: "request.tokens >= -1", want: true},
+		{source: "true", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			e, err := Compile(tt.source, fields)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := e.Eval(env)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A nil pointer means the field was not sent
+	values["request.temperature"] = (*float64)(nil)
+	e, _ := Compile("request.temperature == 0 || request.temperature < 1", fields)
+	if got, err := e.Eval(env); err != nil || got {
+		t.Errorf("Eval() with missing temperature = %v, %v; want false, nil", got, err)
+	}
+
+	values["request.temperature"] = &warm
+	e, _ = Compile(`request.temperature < "high"`, fields)
+	if _, err := e.Eval(env); err == nil {
+		t.Error("Eval() comparing a number with a string should fail")
+	}
+}
+
+func TestCompile_PrefixIdentifiers(t *testing.T) {
+	identifiers := []string{"request.tier", "headers.*"}
+
+	if _, err := Compile(`headers.x-priority == "high"`, identifiers); err != nil {
+		t.Errorf("Compile() with a prefixed field error = %v", err)
+	}
+	for _, source := range []string{`headers == "high"`, `headers. == "high"`, `header.x-priority == "high"`} {
+		if _, err := Compile(source, identifiers); err == nil {
+			t.Errorf("Compile(%q) expected an error", source)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+// Package jsonschema checks JSON values against the subset of JSON Schema used for
+// structured output, e.g. the schemas sent in OpenAI's response_format.
+//
+// Supported keywords:
+//   - type (a name or a list of names), enum, const
+//   - objects: properties, required, additionalProperties (a bool or a schema)
+//   - arrays: items, prefixItems, minItems, maxItems
+//   - strings: minLength, maxLength, pattern
+//   - numbers: minimum, maximum, exclusiveMinimum, exclusiveMaximum
+//   - composition: anyOf, oneOf, allOf, not
+//   - $ref to a location in the same schema, e.g. "#/$defs/address"
+//
+// Other keywords (format, description, ...) are accepted and ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxRefDepth stops recursive schemas that never consume any input.
+const maxRefDepth = 64
+
+// ValidateJSON decodes data and checks it against schema.
+func ValidateJSON(schema map[string]any, data string) error {
+	var value any
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return Validate(schema, value)
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json) against schema.
+func Validate(schema map[string]any, value any) error {
+	v := &validator{root: schema}
+	return v.validate(schema, value, "$", 0)
+}
+
+type validator struct {
+	root map[string]any
+}
+
+func (v *validator) validate(schema map[string]any, value any, path string, depth int) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			return fmt.Errorf("%s: $ref nesting too deep", path)
+		}
+		target, err := v.resolve(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := v.validate(target, value, path, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if types, ok := typeNames(schema["type"]); ok && !matchesAnyType(value, types) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), typeName(value))
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		return fmt.Errorf("%s: value is not one of the allowed values", path)
+	}
+	if constant, ok := schema["const"]; ok && !equalValues(constant, value) {
+		return fmt.Errorf("%s: value does not equal the required constant", path)
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		if err := v.validateObject(schema, typed, path, depth); err != nil {
+			return err
+		}
+	case []any:
+		if err := v.validateArray(schema, typed, path, depth); err != nil {
+			return err
+		}
+	case string:
+		if err := validateString(schema, typed, path); err != nil {
+			return err
+		}
+	case float64:
+		if err := validateNumber(schema, typed, path); err != nil {
+			return err
+		}
+	}
+
+	return v.validateComposition(schema, value, path, depth)
+}
+
+func (v *validator) validateObject(schema map[string]any, object map[string]any, path string, depth int) error {
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, present := object[key]; !present {
+					return fmt.Errorf("%s: missing required property %q", path, key)
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	for key, propertyValue := range object {
+		propertyPath := path + "." + key
+		if propertySchema, ok := properties[key].(map[string]any); ok {
+			if err := v.validate(propertySchema, propertyValue, propertyPath, depth); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, declared := properties[key]; declared {
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: unexpected property %q", path, key)
+			}
+		case map[string]any:
+			if err := v.validate(additional, propertyValue, propertyPath, depth); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *validator) validateArray(schema map[string]any, array []any, path string, depth int) error {
+	if minItems, ok := number(schema["minItems"]); ok && float64(len(array)) < minItems {
+		return fmt.Errorf("%s: expected at least %v items, got %d", path, minItems, len(array))
+	}
+	if maxItems, ok := number(schema["maxItems"]); ok && float64(len(array)) > maxItems {
+		return fmt.Errorf("%s: expected at most %v items, got %d", path, maxItems, len(array))
+	}
+
+	prefixItems, _ := schema["prefixItems"].([]any)
+	items, _ := schema["items"].(map[string]any)
+	for i, item := range array {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		itemSchema := items
+		if i < len(prefixItems) {
+			itemSchema, _ = prefixItems[i].(map[string]any)
+		}
+		if itemSchema == nil {
+			continue
+		}
+		if err := v.validate(itemSchema, item, itemPath, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateString(schema map[string]any, value string, path string) error {
+	length := float64(utf8.RuneCountInString(value))
+	if minLength, ok := number(schema["minLength"]); ok && length < minLength {
+		return fmt.Errorf("%s: expected at least %v characters", path, minLength)
+	}
+	if maxLength, ok := number(schema["maxLength"]); ok && length > maxLength {
+		return fmt.Errorf("%s: expected at most %v characters", path, maxLength)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern %q in schema", path, pattern)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("%s: value does not match pattern %q", path, pattern)
+		}
+	}
+	return nil
+}
+
+func validateNumber(schema map[string]any, value float64, path string) error {
+	if minimum, ok := number(schema["minimum"]); ok && value < minimum {
+		return fmt.Errorf("%s: expected a value >= %v", path, minimum)
+	}
+	if maximum, ok := number(schema["maximum"]); ok && value > maximum {
+		return fmt.Errorf("%s: expected a value <= %v", path, maximum)
+	}
+	if minimum, ok := number(schema["exclusiveMinimum"]); ok && value <= minimum {
+		return fmt.Errorf("%s: expected a value > %v", path, minimum)
+	}
+	if maximum, ok := number(schema["exclusiveMaximum"]); ok && value >= maximum {
+		return fmt.Errorf("%s: expected a value < %v", path, maximum)
+	}
+	return nil
+}
+
+func (v *validator) validateComposition(schema map[string]any, value any, path string, depth int) error {
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, sub := range subschemas(allOf) {
+			if err := v.validate(sub, value, path, depth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		var firstErr error
+		matched := false
+		for _, sub := range subschemas(anyOf) {
+			err := v.validate(sub, value, path, depth)
+			if err == nil {
+				matched = true
+				break
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if !matched && firstErr != nil {
+			return fmt.Errorf("%s: value matches none of anyOf (first mismatch: %w)", path, firstErr)
+		}
+	}
+
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		matches := 0
+		for _, sub := range subschemas(oneOf) {
+			if v.validate(sub, value, path, depth) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s: value must match exactly one of oneOf, matched %d", path, matches)
+		}
+	}
+
+	if not, ok := schema["not"].(map[string]any); ok && v.validate(not, value, path, depth) == nil {
+		return fmt.Errorf("%s: value must not match the schema in not", path)
+	}
+
+	return nil
+}
+
+// resolve follows a JSON pointer into the root schema. Only local references are supported.
+func (v *validator) resolve(ref string) (map[string]any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref %q: only references within the schema are supported", ref)
+	}
+
+	var current any = v.root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		switch node := current.(type) {
+		case map[string]any:
+			current = node[token]
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("unresolvable $ref %q", ref)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+
+	target, ok := current.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unresolvable $ref %q", ref)
+	}
+	return target, nil
+}
+
+func typeNames(value any) ([]string, bool) {
+	switch typed := value.(type) {
+	case string:
+		return []string{typed}, true
+	case []any:
+		names := make([]string, 0, len(typed))
+		for _, name := range typed {
+			if s, ok := name.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names, len(names) > 0
+	}
+	return nil, false
+}
+
+func matchesAnyType(value any, types []string) bool {
+	for _, name := range types {
+		if matchesType(value, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(value any, name string) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return typeName(value) == name
+	}
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func subschemas(values []any) []map[string]any {
+	schemas := make([]map[string]any, 0, len(values))
+	for _, value := range values {
+		if schema, ok := value.(map[string]any); ok {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas
+}
+
+func containsValue(values []any, value any) bool {
+	for _, candidate := range values {
+		if equalValues(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues compares JSON values, treating all numeric types as equal by value, since
+// schemas built in Go may hold ints where decoded JSON holds float64s.
+func equalValues(a any, b any) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func number(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
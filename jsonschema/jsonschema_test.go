@@ -0,0 +1,92 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const personSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"age": {"type": "integer", "minimum": 0},
+		"role": {"enum": ["admin", "member"]},
+		"email": {"type": ["string", "null"], "pattern": "@"},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"address": {"$ref": "#/$defs/address"}
+	},
+	"required": ["name", "age"],
+	"additionalProperties": false,
+	"$defs": {
+		"address": {
+			"type": "object",
+			"properties": {"city": {"type": "string"}},
+			"required": ["city"]
+		}
+	}
+}`
+
+func TestValidateJSON(t *testing.T) {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(personSchema), &schema); err != nil {
+		t.Fatalf("invalid test schema: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "minimal", data: `{"name": "Ada", "age": 36}`},
+		{name: "all fields", data: `{"name": "Ada", "age": 36, "role": "admin", "email": null, "tags": ["a"], "address": {"city": "London"}}`},
+		{name: "invalid JSON", data: `{"name": "Ada"`, wantErr: true},
+		{name: "not an object", data: `["Ada"]`, wantErr: true},
+		{name: "missing required", data: `{"name": "Ada"}`, wantErr: true},
+		{name: "wrong type", data: `{"name": "Ada", "age": "36"}`, wantErr: true},
+		{name: "not an integer", data: `{"name": "Ada", "age": 36.5}`, wantErr: true},
+		{name: "below minimum", data: `{"name": "Ada", "age": -1}`, wantErr: true},
+		{name: "empty string", data: `{"name": "", "age": 36}`, wantErr: true},
+		{name: "not in enum", data: `{"name": "Ada", "age": 36, "role": "owner"}`, wantErr: true},
+		{name: "pattern mismatch", data: `{"name": "Ada", "age": 36, "email": "ada"}`, wantErr: true},
+		{name: "too many items", data: `{"name": "Ada", "age": 36, "tags": ["a", "b", "c"]}`, wantErr: true},
+		{name: "wrong item type", data: `{"name": "Ada", "age": 36, "tags": [1]}`, wantErr: true},
+		{name: "additional property", data: `{"name": "Ada", "age": 36, "nickname": "A"}`, wantErr: true},
+		{name: "invalid reference target", data: `{"name": "Ada", "age": 36, "address": {}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJSON(schema, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateJSON(%s) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Composition(t *testing.T) {
+	schema := map[string]any{
+		"anyOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "integer", "exclusiveMaximum": 10},
+		},
+		"not": map[string]any{"const": 5},
+	}
+
+	tests := []struct {
+		value   any
+		wantErr bool
+	}{
+		{value: "text"},
+		{value: 3.0},
+		{value: 5.0, wantErr: true},
+		{value: 10.0, wantErr: true},
+		{value: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := Validate(schema, tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
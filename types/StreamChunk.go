@@ -1,9 +1,13 @@
 package types
 
 type StreamChunk struct {
-	Content string  `json:"content"`
-	Done    bool    `json:"done"`
-	Error   error   `json:"-"`
-	Usage   Usage   `json:"usage,omitempty"`
-	CostUSD float64 `json:"cost_usd,omitempty"`
+	Content string `json:"content"`
+	// Tool call fragments, see ToolCallDelta
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"`
+	Done      bool            `json:"done"`
+	Error     error           `json:"-"`
+	Usage     Usage           `json:"usage,omitempty"`
+	CostUSD   float64         `json:"cost_usd,omitempty"`
+	// Set on the final chunk, one of the types.FinishReason* values
+	FinishReason string `json:"finish_reason,omitempty"`
 }
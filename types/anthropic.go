@@ -0,0 +1,226 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Request and response shapes for the Anthropic-compatible /v1/messages endpoint.
+
+type AnthropicMessagesRequest struct {
+	Model         string             `json:"model" binding:"required,min=1,max=100"`
+	MaxTokens     int                `json:"max_tokens" binding:"required,gt=0,lte=100000"`
+	System        AnthropicText      `json:"system,omitempty"`
+	Messages      []AnthropicMessage `json:"messages" binding:"required,min=1,max=100,dive"`
+	Stream        bool               `json:"stream"`
+	Temperature   *float64           `json:"temperature,omitempty" binding:"omitempty,gte=0,lte=1"`
+	TopP          *float64           `json:"top_p,omitempty" binding:"omitempty,gte=0,lte=1"`
+	Metadata      *AnthropicMetadata `json:"metadata,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	// Tool calling is only available through /v1/chat/completions; Tools is kept so it can be rejected
+	Tools json.RawMessage `json:"tools,omitempty"`
+}
+
+type AnthropicMessage struct {
+	Role    string           `json:"role" binding:"required,oneof=user assistant"`
+	Content AnthropicContent `json:"content"`
+}
+
+type AnthropicMetadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+// AnthropicText accepts either a plain string or an array of text content blocks, as the
+// system field does in the Anthropic API. Text blocks are joined with a blank line; other block
+// types are rejected.
+type AnthropicText string
+
+func (t *AnthropicText) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*t = AnthropicText(text)
+		return nil
+	}
+
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return fmt.Errorf("content must be a string or an array of content blocks")
+	}
+
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type != "text" {
+			return fmt.Errorf("unsupported content block type %q", block.Type)
+		}
+		parts = append(parts, block.Text)
+	}
+
+	*t = AnthropicText(strings.Join(parts, "\n\n"))
+	return nil
+}
+
+// AnthropicContent is a message's content: a plain string or an array of text and image
+// content blocks. Text blocks are joined with a blank line into Text. When there are images,
+// Parts holds every block in order, with images as image_url parts.
+type AnthropicContent struct {
+	Text  string        `binding:"required_without=Parts"`
+	Parts []ContentPart `binding:"omitempty,max=100,dive"`
+}
+
+type anthropicBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text"`
+	Source *anthropicImageSource `json:"source"`
+}
+
+// anthropicImageSource is an image block's source: base64 data with its media type, or a URL.
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+	URL       string `json:"url"`
+}
+
+func (c *AnthropicContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = AnthropicContent{Text: text}
+		return nil
+	}
+
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return fmt.Errorf("content must be a string or an array of content blocks")
+	}
+
+	texts := make([]string, 0, len(blocks))
+	parts := make([]ContentPart, 0, len(blocks))
+	hasImage := false
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			texts = append(texts, block.Text)
+			parts = append(parts, ContentPart{Type: ContentPartText, Text: block.Text})
+
+		case "image":
+			url, err := block.Source.imageURL()
+			if err != nil {
+				return err
+			}
+			parts = append(parts, ContentPart{Type: ContentPartImageURL, ImageURL: &ImageURL{URL: url}})
+			hasImage = true
+
+		default:
+			return fmt.Errorf("unsupported content block type %q", block.Type)
+		}
+	}
+
+	*c = AnthropicContent{Text: strings.Join(texts, "\n\n")}
+	if hasImage {
+		c.Parts = parts
+	}
+	return nil
+}
+
+// imageURL returns the source as an image_url URL, turning base64 data into a data URL.
+func (s *anthropicImageSource) imageURL() (string, error) {
+	if s == nil {
+		return "", fmt.Errorf("image block requires a source")
+	}
+
+	switch s.Type {
+	case "base64":
+		if s.MediaType == "" || s.Data == "" {
+			return "", fmt.Errorf("base64 image source requires media_type and data")
+		}
+		return "data:" + s.MediaType + ";base64," + s.Data, nil
+	case "url":
+		if s.URL == "" {
+			return "", fmt.Errorf("url image source requires a url")
+		}
+		return s.URL, nil
+	default:
+		return "", fmt.Errorf("unsupported image source type %q", s.Type)
+	}
+}
+
+// ToCompletion converts the request into the router's internal completion request.
+func (r *AnthropicMessagesRequest) ToCompletion() Completion {
+	messages := make([]Message, 0, len(r.Messages)+1)
+	if system := strings.TrimSpace(string(r.System)); system != "" {
+		messages = append(messages, Message{Role: "system", Content: system})
+	}
+	for _, message := range r.Messages {
+		messages = append(messages, Message{Role: message.Role, Content: message.Content.Text, Parts: message.Content.Parts})
+	}
+
+	maxTokens := r.MaxTokens
+	completion := Completion{
+		Messages:    messages,
+		Model:       r.Model,
+		Stream:      r.Stream,
+		Temperature: r.Temperature,
+		MaxTokens:   &maxTokens,
+		TopP:        r.TopP,
+	}
+	if r.Metadata != nil {
+		completion.User = r.Metadata.UserID
+	}
+
+	return completion
+}
+
+type AnthropicMessageResponse struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []AnthropicContentBlock `json:"content"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        AnthropicUsage          `json:"usage"`
+	Octo         *OctoExtension          `json:"x_octo,omitempty"`
+}
+
+type AnthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// AnthropicErrorResponse is the Anthropic error body: {"type": "error", "error": {"type": ..., "message": ...}}.
+type AnthropicErrorResponse struct {
+	Type  string               `json:"type"`
+	Error AnthropicErrorDetail `json:"error"`
+}
+
+type AnthropicErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func NewAnthropicErrorResponse(errType string, message string) AnthropicErrorResponse {
+	return AnthropicErrorResponse{
+		Type:  "error",
+		Error: AnthropicErrorDetail{Type: errType, Message: message},
+	}
+}
+
+// AnthropicStopReason translates a types.FinishReason* value into Anthropic's stop_reason.
+func AnthropicStopReason(finishReason string) string {
+	switch finishReason {
+	case FinishReasonLength:
+		return "max_tokens"
+	case FinishReasonToolCalls:
+		return "tool_use"
+	case FinishReasonContentFilter:
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
@@ -2,13 +2,53 @@ package types
 
 type Completion struct {
 	Messages []Message `json:"messages" binding:"required,min=1,max=100,dive"`
-	Model    string    `json:"model" binding:"omitempty,min=1,max=100"`
-	Stream   bool      `json:"stream"`
-	Tier     string    `json:"tier,omitempty" binding:"omitempty,oneof=budget standard premium ultra-premium"`
+	// A provider/model ID from the catalog (e.g. "anthropic/claude-sonnet-4") pins the request to
+	// that model; other values are ignored and the routing strategy picks the model
+	Model string `json:"model" binding:"omitempty,min=1,max=100"`
+	// Lets a pinned request fall back to equivalent models (same tier and capabilities) when the pinned one fails
+	AllowFallback bool `json:"allow_fallback,omitempty"`
+	Stream        bool `json:"stream"`
+	// Optional OpenAI stream settings, e.g. include_usage for a final usage chunk
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	Tier          string         `json:"tier,omitempty" binding:"omitempty,oneof=budget standard premium ultra-premium"`
+	User          string         `json:"user,omitempty" binding:"omitempty,max=256"`
 	// Optional fields
 	Temperature      *float64 `json:"temperature,omitempty" binding:"omitempty,gte=0,lte=2"`
 	MaxTokens        *int     `json:"max_tokens,omitempty" binding:"omitempty,gt=0,lte=100000"`
 	TopP             *float64 `json:"top_p,omitempty" binding:"omitempty,gte=0,lte=1"`
 	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty" binding:"omitempty,gte=-2,lte=2"`
 	PresencePenalty  *float64 `json:"presence_penalty,omitempty" binding:"omitempty,gte=-2,lte=2"`
+	// Tool calling
+	Tools      []Tool      `json:"tools,omitempty" binding:"omitempty,max=128,dive"`
+	ToolChoice *ToolChoice `json:"tool_choice,omitempty"`
+	// Structured output: json_object or json_schema responses are checked before they are returned
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	// Most the request may cost in USD; models estimated to cost more are never used
+	MaxCostUSD *float64 `json:"max_cost_usd,omitempty" binding:"omitempty,gt=0"`
+	// Catalog IDs of the models the caller may use (empty means any), from its consumer's limits
+	AllowedModels []string `json:"-"`
+	// Providers the request may be routed to (empty means any), from the routing rule it matched
+	AllowedProviders []string `json:"-"`
+	// Counts the prompt's tokens once per provider; set when routing starts
+	Tokens *RequestTokens `json:"-"`
+}
+
+// SamplingParams returns the generation settings to forward to the selected provider.
+func (c *Completion) SamplingParams() SamplingParams {
+	return SamplingParams{
+		Temperature:      c.Temperature,
+		MaxTokens:        c.MaxTokens,
+		TopP:             c.TopP,
+		FrequencyPenalty: c.FrequencyPenalty,
+		PresencePenalty:  c.PresencePenalty,
+	}
+}
+
+// RequiredCapabilities lists the model capabilities needed to serve the request.
+func (c *Completion) RequiredCapabilities() []string {
+	var capabilities []string
+	if len(c.Tools) > 0 {
+		capabilities = append(capabilities, CapabilityTools)
+	}
+	return append(capabilities, mediaCapabilities(c.Messages)...)
 }
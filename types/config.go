@@ -14,8 +14,24 @@ type ModelConfig struct {
 	ContextWindow   int      `mapstructure:"contextWindow"`
 	Tier            string   `mapstructure:"tier"`
 	Capabilities    []string `mapstructure:"capabilities"`
+	// Model name sent to the provider's API; defaults to the ID without its provider prefix
+	UpstreamName string `mapstructure:"upstreamName"`
+	// Default max output tokens for this model, used instead of the provider default (optional)
+	MaxTokens int64 `mapstructure:"maxTokens"`
+	// Extra fields merged into the provider's request body for this model (optional)
+	Params map[string]any `mapstructure:"params"`
+	// "chat" (default) or "embedding"; embedding models only serve /v1/embeddings
+	Type string `mapstructure:"type"`
+	// Output vector size of an embedding model (optional)
+	Dimensions int `mapstructure:"dimensions"`
 }
 
+// Catalog model types
+const (
+	ModelTypeChat      = "chat"
+	ModelTypeEmbedding = "embedding"
+)
+
 type DefaultModels struct {
 	// Name      string `mapstructure:"name"`
 	Model     string `mapstructure:"model"`
@@ -44,7 +60,34 @@ type ProviderLimits struct {
 type CacheData struct {
 	Enabled  bool              `mapstructure:"enabled"`
 	Ttl      int               `mapstructure:"ttl"`
-	Semantic map[string]string `mapstructure:"semantic"`
+	Semantic SemanticCacheData `mapstructure:"semantic"`
+	Rules    []CacheRule       `mapstructure:"rules"`
+}
+
+// CacheRule decides whether matching requests are cached. Rules are checked in order and the first match wins.
+type CacheRule struct {
+	If      string `mapstructure:"if"`      // Condition over CacheRuleFields; empty matches every request
+	Cache   bool   `mapstructure:"cache"`   // Whether matching requests are cached
+	MaxSize int    `mapstructure:"maxSize"` // Only match prompts up to this many tokens (0 = no limit)
+	Ttl     int    `mapstructure:"ttl"`     // Overrides cache.ttl for matching requests
+	Scope   string `mapstructure:"scope"`   // "shared" (default) or "user" to keep entries per request user
+}
+
+// CacheRuleFields are the request fields cache rule conditions may reference.
+var CacheRuleFields = []string{
+	"request.temperature",
+	"request.user",
+	"request.model",
+	"request.tier",
+	"request.tokens",
+}
+
+type SemanticCacheData struct {
+	Enabled             bool    `mapstructure:"enabled"`
+	SimilarityThreshold float64 `mapstructure:"similarityThreshold"`
+	MaxEntries          int     `mapstructure:"maxEntries"`    // Per model/tier scope
+	ModelPath           string  `mapstructure:"modelPath"`     // Defaults to the semantic routing model
+	SharedLibPath       string  `mapstructure:"sharedLibPath"` // Path to libonnxruntime.dylib/so
 }
 
 type RedisData struct {
@@ -54,5 +97,22 @@ type RedisData struct {
 }
 
 type SecurityData struct {
-	APIKeys []string `mapstructure:"apiKeys"`
+	APIKeys   []string       `mapstructure:"apiKeys"` // Keys of the default consumer, which has no limits of its own and is an admin
+	Consumers []ConsumerData `mapstructure:"consumers"`
+}
+
+// DefaultConsumer names the consumer that security.apiKeys belong to.
+const DefaultConsumer = "default"
+
+// ConsumerData is a named team or app calling the router with its own API keys and limits.
+// Zero limits and empty lists mean unrestricted.
+type ConsumerData struct {
+	Name              string   `mapstructure:"name"`
+	APIKeys           []string `mapstructure:"apiKeys"`
+	RequestsPerMinute int      `mapstructure:"requestsPerMinute"`
+	RequestsPerDay    int      `mapstructure:"requestsPerDay"`
+	DailyBudget       float64  `mapstructure:"dailyBudget"` // USD
+	Tiers             []string `mapstructure:"tiers"`       // Tiers of the chat models the consumer may use
+	Models            []string `mapstructure:"models"`      // Catalog IDs of the models the consumer may use
+	Admin             bool     `mapstructure:"admin"`       // May call the /admin endpoints
 }
@@ -0,0 +1,193 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Multimodal content follows the OpenAI chat completions shapes: a message's content may be a
+// string or an array of parts. Each provider converts the parts to its own representation.
+
+// Model capabilities required to serve requests containing each kind of media
+const (
+	CapabilityVision = "vision"
+	CapabilityAudio  = "audio"
+	CapabilityFiles  = "files"
+)
+
+const (
+	ContentPartText       = "text"
+	ContentPartImageURL   = "image_url"
+	ContentPartInputAudio = "input_audio"
+	ContentPartFile       = "file"
+)
+
+type ContentPart struct {
+	Type       string      `json:"type" binding:"required,oneof=text image_url input_audio file"`
+	Text       string      `json:"text,omitempty"`
+	ImageURL   *ImageURL   `json:"image_url,omitempty" binding:"required_if=Type image_url"`
+	InputAudio *InputAudio `json:"input_audio,omitempty" binding:"required_if=Type input_audio"`
+	File       *File       `json:"file,omitempty" binding:"required_if=Type file"`
+}
+
+// ImageURL is an http(s) URL or a base64 data URL, e.g. "data:image/png;base64,...".
+type ImageURL struct {
+	URL    string `json:"url" binding:"required"`
+	Detail string `json:"detail,omitempty" binding:"omitempty,oneof=auto low high"`
+}
+
+type InputAudio struct {
+	Data   string `json:"data" binding:"required"` // Base64-encoded audio
+	Format string `json:"format" binding:"required,oneof=wav mp3"`
+}
+
+// File is a document sent inline, e.g. a PDF as "data:application/pdf;base64,...".
+type File struct {
+	FileData string `json:"file_data" binding:"required"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// DataURL is a decoded base64 data URL.
+type DataURL struct {
+	MediaType string
+	Data      string // Base64-encoded
+}
+
+// ParseDataURL parses a base64 data URL ("data:<media type>;base64,<data>").
+func ParseDataURL(value string) (DataURL, error) {
+	rest, ok := strings.CutPrefix(value, "data:")
+	if !ok {
+		return DataURL{}, fmt.Errorf("not a data URL")
+	}
+
+	header, data, ok := strings.Cut(rest, ",")
+	mediaType, isBase64 := strings.CutSuffix(header, ";base64")
+	if !ok || !isBase64 || mediaType == "" {
+		return DataURL{}, fmt.Errorf("data URL must be of the form data:<media type>;base64,<data>")
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+		return DataURL{}, fmt.Errorf("data URL is not valid base64")
+	}
+
+	return DataURL{MediaType: mediaType, Data: data}, nil
+}
+
+// Validate checks the parts of a message's content that binding can't, such as data URLs.
+func (p ContentPart) Validate() error {
+	switch p.Type {
+	case ContentPartImageURL:
+		if strings.HasPrefix(p.ImageURL.URL, "data:") {
+			if _, err := ParseDataURL(p.ImageURL.URL); err != nil {
+				return fmt.Errorf("image_url: %w", err)
+			}
+		} else if !strings.HasPrefix(p.ImageURL.URL, "https://") && !strings.HasPrefix(p.ImageURL.URL, "http://") {
+			return fmt.Errorf("image_url must be an http(s) URL or a base64 data URL")
+		}
+
+	case ContentPartInputAudio:
+		if _, err := base64.StdEncoding.DecodeString(p.InputAudio.Data); err != nil {
+			return fmt.Errorf("input_audio data is not valid base64")
+		}
+
+	case ContentPartFile:
+		if _, err := ParseDataURL(p.File.FileData); err != nil {
+			return fmt.Errorf("file_data: %w", err)
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON accepts content as either a string or an array of content parts. The text of
+// the parts is also joined into Content, so text-only consumers keep working.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type plainMessage Message
+	var raw struct {
+		plainMessage
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*m = Message(raw.plainMessage)
+
+	content := strings.TrimSpace(string(raw.Content))
+	switch {
+	case content == "" || content == "null":
+		return nil
+
+	case strings.HasPrefix(content, "["):
+		if err := json.Unmarshal(raw.Content, &m.Parts); err != nil {
+			return err
+		}
+		var text []string
+		for _, part := range m.Parts {
+			if part.Type == ContentPartText && part.Text != "" {
+				text = append(text, part.Text)
+			}
+		}
+		m.Content = strings.Join(text, "\n")
+		return nil
+
+	default:
+		if err := json.Unmarshal(raw.Content, &m.Content); err != nil {
+			return fmt.Errorf("content must be a string or an array of content parts")
+		}
+		return nil
+	}
+}
+
+// MarshalJSON writes content as parts when the message has them, and as a string otherwise.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type plainMessage Message
+	if len(m.Parts) == 0 {
+		return json.Marshal(plainMessage(m))
+	}
+
+	return json.Marshal(struct {
+		plainMessage
+		Content []ContentPart `json:"content"`
+	}{plainMessage: plainMessage(m), Content: m.Parts})
+}
+
+// HasMedia reports whether the message carries anything other than text.
+func (m Message) HasMedia() bool {
+	for _, part := range m.Parts {
+		if part.Type != ContentPartText {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaCapabilities lists the model capabilities needed for the media in the messages.
+func mediaCapabilities(messages []Message) []string {
+	var capabilities []string
+	seen := make(map[string]bool)
+
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			var capability string
+			switch part.Type {
+			case ContentPartImageURL:
+				capability = CapabilityVision
+			case ContentPartInputAudio:
+				capability = CapabilityAudio
+			case ContentPartFile:
+				capability = CapabilityFiles
+			default:
+				continue
+			}
+
+			if !seen[capability] {
+				seen[capability] = true
+				capabilities = append(capabilities, capability)
+			}
+		}
+	}
+
+	return capabilities
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Request and response shapes for the OpenAI-compatible /v1/embeddings endpoint.
+
+const (
+	ObjectList      = "list"
+	ObjectEmbedding = "embedding"
+)
+
+type EmbeddingRequest struct {
+	Input EmbeddingTexts `json:"input" binding:"required,min=1,max=2048,dive,required,max=100000"`
+	// A provider/model ID from the catalog (e.g. "openai/text-embedding-3-small") or a model's
+	// upstream name (e.g. "text-embedding-3-small") pins the request to that model
+	Model string `json:"model" binding:"omitempty,min=1,max=100"`
+	// Lets a pinned request fall back to other embedding models when the pinned one fails
+	AllowFallback  bool   `json:"allow_fallback,omitempty"`
+	EncodingFormat string `json:"encoding_format,omitempty" binding:"omitempty,oneof=float base64"`
+	Dimensions     *int   `json:"dimensions,omitempty" binding:"omitempty,gt=0,lte=8192"`
+	User           string `json:"user,omitempty" binding:"omitempty,max=256"`
+}
+
+// EmbeddingTexts accepts input as a single string or an array of strings.
+// Token arrays, which OpenAI also accepts, are rejected since not every provider can take them.
+type EmbeddingTexts []string
+
+func (t *EmbeddingTexts) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*t = EmbeddingTexts{text}
+		return nil
+	}
+
+	var texts []string
+	if err := json.Unmarshal(data, &texts); err != nil {
+		return fmt.Errorf("input must be a string or an array of strings")
+	}
+	*t = texts
+	return nil
+}
+
+type EmbeddingList struct {
+	Object string          `json:"object"`
+	Data   []EmbeddingData `json:"data"`
+	Model  string          `json:"model"`
+	Usage  EmbeddingUsage  `json:"usage"`
+	Octo   *OctoExtension  `json:"x_octo,omitempty"`
+}
+
+type EmbeddingData struct {
+	Object string `json:"object"`
+	Index  int    `json:"index"`
+	// A list of floats, or a base64 string of little-endian float32s for encoding_format "base64"
+	Embedding any `json:"embedding"`
+}
+
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
@@ -1,6 +1,12 @@
 package types
 
 type Message struct {
-	Role    string `json:"role" binding:"required,oneof=user assistant system"`
-	Content string `json:"content" binding:"required,min=1,max=500000"`
+	Role string `json:"role" binding:"required,oneof=user assistant system tool"`
+	// Content may be empty on assistant messages that only carry tool calls, and on messages whose
+	// parts are all media. For content sent as parts, it holds the text of the parts.
+	Content string `json:"content" binding:"required_without_all=ToolCalls Parts,max=500000"`
+	// Parts holds content sent as an array of parts (see content.go)
+	Parts      []ContentPart `json:"-" binding:"omitempty,max=100,dive"`
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty" binding:"omitempty,dive"`
+	ToolCallID string        `json:"tool_call_id,omitempty" binding:"required_if=Role tool"`
 }
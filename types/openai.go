@@ -0,0 +1,97 @@
+package types
+
+// Response shapes for the OpenAI-compatible /v1/chat/completions endpoint.
+// They mirror the OpenAI API so stock OpenAI SDKs can talk to the router unchanged.
+
+const (
+	ObjectChatCompletion      = "chat.completion"
+	ObjectChatCompletionChunk = "chat.completion.chunk"
+)
+
+// Finish reasons reported in choices[].finish_reason.
+const (
+	FinishReasonStop          = "stop"
+	FinishReasonLength        = "length"
+	FinishReasonContentFilter = "content_filter"
+	FinishReasonToolCalls     = "tool_calls"
+)
+
+type ChatCompletion struct {
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []ChatCompletionChoice `json:"choices"`
+	Usage   Usage                  `json:"usage"`
+	Octo    *OctoExtension         `json:"x_octo,omitempty"`
+}
+
+type ChatCompletionChoice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+type ChatCompletionChunk struct {
+	ID      string                      `json:"id"`
+	Object  string                      `json:"object"`
+	Created int64                       `json:"created"`
+	Model   string                      `json:"model"`
+	Choices []ChatCompletionChunkChoice `json:"choices"`
+	Usage   *Usage                      `json:"usage,omitempty"`
+	Octo    *OctoExtension              `json:"x_octo,omitempty"`
+}
+
+type ChatCompletionChunkChoice struct {
+	Index        int                 `json:"index"`
+	Delta        ChatCompletionDelta `json:"delta"`
+	FinishReason *string             `json:"finish_reason"`
+}
+
+type ChatCompletionDelta struct {
+	Role      string          `json:"role,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// OctoExtension carries router-specific details that have no OpenAI equivalent.
+// OpenAI clients ignore unknown fields; the same values are also sent as X-Octo-* headers.
+type OctoExtension struct {
+	Provider string  `json:"provider"`
+	CostUSD  float64 `json:"cost_usd"`
+	Cached   bool    `json:"cached,omitempty"`
+}
+
+// StreamOptions mirrors OpenAI's stream_options request field.
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ErrorResponse is the OpenAI error body: {"error": {"message": ..., "type": ..., "code": ...}}.
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Param   any    `json:"param"`
+	Code    any    `json:"code"`
+}
+
+// Error types used in ErrorDetail.Type, following OpenAI's conventions.
+const (
+	ErrorTypeInvalidRequest = "invalid_request_error"
+	ErrorTypeRateLimit      = "rate_limit_error"
+	ErrorTypeServer         = "server_error"
+	ErrorTypePermission     = "permission_error"
+	ErrorTypeQuota          = "insufficient_quota"
+)
+
+func NewErrorResponse(errType string, code string, message string) ErrorResponse {
+	detail := ErrorDetail{Message: message, Type: errType}
+	if code != "" {
+		detail.Code = code
+	}
+	return ErrorResponse{Error: detail}
+}
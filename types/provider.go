@@ -7,13 +7,35 @@ import (
 type Provider interface {
 	Complete(ctx context.Context, input *CompletionInput) (*CompletionResponse, error)
 	CountTokens(ctx context.Context, messages []Message) (int, error)
+	// CompleteStream stops when ctx is cancelled, finishing with a chunk that carries the usage
+	// generated so far. Callers must read the channel until it is closed.
 	CompleteStream(ctx context.Context, data *StreamCompletionInput) (<-chan *StreamChunk, error)
 	GetProviderName() string
 }
 
+// EmbeddingProvider is implemented by providers that can also embed text.
+type EmbeddingProvider interface {
+	Provider
+	Embed(ctx context.Context, input *EmbeddingInput) (*EmbeddingResponse, error)
+}
+
 type CompletionInput struct {
-	Model    string
-	Messages []Message
+	Model          string
+	Messages       []Message
+	Params         SamplingParams
+	Tools          []Tool
+	ToolChoice     *ToolChoice
+	ResponseFormat *ResponseFormat
+}
+
+// SamplingParams are the optional generation settings sent by the client.
+// A nil field means the provider default is used.
+type SamplingParams struct {
+	Temperature      *float64
+	MaxTokens        *int
+	TopP             *float64
+	FrequencyPenalty *float64
+	PresencePenalty  *float64
 }
 
 type Usage struct {
@@ -23,16 +45,50 @@ type Usage struct {
 }
 
 type CompletionResponse struct {
-	Message Message           `json:"message"`
-	Usage   Usage             `json:"usage"`
-	CostUSD float64           `json:"cost_usd"`
-	Headers map[string]string `json:"-"`
+	Message      Message           `json:"message"`
+	Usage        Usage             `json:"usage"`
+	CostUSD      float64           `json:"cost_usd"`
+	Model        string            `json:"model"`         // Standardized ID of the model that answered
+	FinishReason string            `json:"finish_reason"` // One of the types.FinishReason* values
+	Headers      map[string]string `json:"-"`
+}
+
+type EmbeddingInput struct {
+	Model      string // Catalog ID of an embedding model
+	Input      []string
+	Dimensions *int // Requested vector size; nil uses the model's own
+}
+
+type EmbeddingResponse struct {
+	Embeddings [][]float32 // One per input, in order
+	Usage      Usage
+	CostUSD    float64
+	Model      string // Standardized ID of the model that answered
 }
 
 type ProviderConfig struct {
 	Name    string `mapstructure:"name"`
 	APIKey  string `mapstructure:"apiKey"`
 	Enabled bool   `mapstructure:"enabled"`
+	// Additional keys, used together with APIKey
+	APIKeys       []string `mapstructure:"apiKeys"`
+	KeySelection  string   `mapstructure:"keySelection"`  // round-robin (default), least-used or failover
+	KeyQuarantine int      `mapstructure:"keyQuarantine"` // How long a rejected key is skipped, in ms (default 60000)
+	// Provider implementation; defaults to the name (openai, anthropic, gemini).
+	// "openai-compatible" serves any server speaking the OpenAI API under a name of your choice,
+	// and "azure-openai" an Azure OpenAI resource.
+	Type    string            `mapstructure:"type"`
+	BaseURL string            `mapstructure:"baseURL"`
+	Headers map[string]string `mapstructure:"headers"` // Extra HTTP headers sent with every request
+	// Azure OpenAI only
+	APIVersion  string            `mapstructure:"apiVersion"`
+	Deployments []ModelDeployment `mapstructure:"deployments"`
+}
+
+// ModelDeployment maps a catalog model to the Azure deployment serving it.
+type ModelDeployment struct {
+	Model      string `mapstructure:"model"`
+	Deployment string `mapstructure:"deployment"`
 }
 
 type ProviderExtra struct {
@@ -41,12 +97,20 @@ type ProviderExtra struct {
 }
 
 type ProviderConfigWithExtras struct {
-	Name     string
-	APIKey   string
-	Enabled  bool
-	Defaults *ProviderExtra
-	Timeout  int
-	Limits   ProviderLimits
+	Name          string
+	Type          string
+	APIKey        string
+	APIKeys       []string
+	KeySelection  string
+	KeyQuarantine int
+	BaseURL       string
+	Headers       map[string]string
+	APIVersion    string
+	Deployments   map[string]string // Catalog model ID to deployment name
+	Enabled       bool
+	Defaults      *ProviderExtra
+	Timeout       int
+	Limits        ProviderLimits
 }
 
 type ProviderWithModel struct {
@@ -55,6 +119,10 @@ type ProviderWithModel struct {
 }
 
 type StreamCompletionInput struct {
-	Model    string
-	Messages []Message
+	Model          string
+	Messages       []Message
+	Params         SamplingParams
+	Tools          []Tool
+	ToolChoice     *ToolChoice
+	ResponseFormat *ResponseFormat
 }
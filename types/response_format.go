@@ -0,0 +1,39 @@
+package types
+
+// Structured output follows the OpenAI chat completions shapes; each provider converts them
+// to its own representation, and the handler checks the response against the requested format.
+
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+type ResponseFormat struct {
+	Type       string      `json:"type" binding:"required,oneof=text json_object json_schema"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty" binding:"required_if=Type json_schema"`
+}
+
+type JSONSchema struct {
+	Name        string         `json:"name" binding:"required,max=64"`
+	Description string         `json:"description,omitempty" binding:"max=4096"`
+	Schema      map[string]any `json:"schema,omitempty"`
+	Strict      *bool          `json:"strict,omitempty"`
+}
+
+// IsJSON reports whether the format asks for a JSON response.
+func (f *ResponseFormat) IsJSON() bool {
+	return f != nil && (f.Type == ResponseFormatJSONObject || f.Type == ResponseFormatJSONSchema)
+}
+
+// ResponseSchema returns the JSON schema the response must match. A json_object response must
+// be an object but is otherwise free-form; nil means the format asks for no JSON at all.
+func (f *ResponseFormat) ResponseSchema() map[string]any {
+	if !f.IsJSON() {
+		return nil
+	}
+	if f.Type == ResponseFormatJSONSchema && f.JSONSchema.Schema != nil {
+		return f.JSONSchema.Schema
+	}
+	return map[string]any{"type": "object"}
+}
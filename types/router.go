@@ -1,9 +1,13 @@
 package types
 
+import "fmt"
+
 type CostOptions struct {
 	DefaultTier  string `mapstructure:"defaultTier"`  // Default tier when not specified in request
-	MinimumTier  string `mapstructure:"minimumTier"`  // Absolute minimum tier to use
+	MinimumTier  string `mapstructure:"minimumTier"`  // Absolute minimum tier to use (ignored by the "cheapest" strategy)
 	TierStrategy string `mapstructure:"tierStrategy"` // "same-tier", "allow-downgrade", "cheapest"
+	// Percentile of a model's past output lengths used to estimate a request's output (1-100, default 90)
+	OutputPercentile float64 `mapstructure:"outputPercentile"`
 }
 
 type SemanticGroup struct {
@@ -37,6 +41,65 @@ type RoutingData struct {
 	Fallbacks   []string       `mapstructure:"fallbacks"`
 	Policies    *Policies      `mapstructure:"policies"`
 	CostOptions *CostOptions   `mapstructure:"costOptions"`
+	Rules       []RoutingRule  `mapstructure:"rules"`
+}
+
+// RoutingRule changes how matching requests are routed. Rules are checked in order and the first
+// match wins; requests pinned to a model by the client skip them.
+type RoutingRule struct {
+	Name      string   `mapstructure:"name"`      // Reported in the X-Octo-Rule header; defaults to "rule-<index>"
+	If        string   `mapstructure:"if"`        // Condition over RoutingRuleFields; empty matches every request
+	Use       string   `mapstructure:"use"`       // Pins matching requests to this catalog model
+	Providers []string `mapstructure:"providers"` // Restricts matching requests to these providers
+	Tier      string   `mapstructure:"tier"`      // Replaces the requested tier
+}
+
+// RoutingRuleFields are the request fields routing rule conditions may reference.
+var RoutingRuleFields = []string{
+	"prompt.length",  // Characters across all messages
+	"prompt.tokens",  // Estimated prompt tokens
+	"messages.count", // Number of messages
+	"request.tier",   // Requested tier
+	"consumer",       // Name of the authenticated consumer
+	"intent",         // Group chosen by the semantic policy
+	"headers.*",      // Request headers by lower-case name, e.g. headers.x-priority
+}
+
+// RuleInput is what routing rules are evaluated against.
+type RuleInput struct {
+	Messages     []Message
+	Tier         string
+	Consumer     string
+	Headers      map[string][]string
+	Capabilities []string   // Rules pinning a model without these are skipped
+	Tokens       func() int // Counts the prompt tokens; only called when a rule needs them
+	MaxTokens    int        // Requested output tokens; rules pinning a model whose context can't fit them and the prompt are skipped
+	// Catalog IDs of the models the caller may use (empty means any); rules leaving it none of them are skipped
+	Models []string
+}
+
+// ExperimentData splits a share of traffic between variants that each route to a fixed provider
+// or model, so their latency, error rate and cost can be compared. Requests are assigned by
+// hashing a sticky key, so the same key always lands in the same variant.
+type ExperimentData struct {
+	Name      string                       `mapstructure:"name"`
+	Enabled   bool                         `mapstructure:"enabled"`
+	Traffic   float64                      `mapstructure:"traffic"`   // Share of requests in the experiment, between 0 and 1
+	StickyKey string                       `mapstructure:"stickyKey"` // "apiKey" (default), "user" or "header:<name>"
+	Variants  map[string]ExperimentVariant `mapstructure:"variants"`
+}
+
+type ExperimentVariant struct {
+	Provider string `mapstructure:"provider"` // Routes the variant's requests to this provider
+	Model    string `mapstructure:"model"`    // Pins the variant's requests to this catalog model
+	Weight   int    `mapstructure:"weight"`   // Relative share of the experiment's requests; defaults to 1
+}
+
+// StickyKeys are the request values experiments may assign requests by.
+type StickyKeys struct {
+	APIKey  string
+	User    string
+	Headers map[string][]string
 }
 
 type RouterConfig struct {
@@ -49,20 +112,58 @@ type SelectProviderInput struct {
 	Messages   []Message
 	Tier       string     // Requested tier (optional)
 	Candidates []Provider // Optional: Pre-filtered list of providers (e.g. from Semantic Router)
+	// Model capabilities the request needs (e.g. "tools"); models without them are never selected
+	Capabilities []string
+	// Pinned catalog model ID (optional); skips strategy selection in favour of this model's provider
+	Model string
+	// Output size an embedding request asks for (optional); embedding models with fewer dimensions are never selected
+	Dimensions int
+	// Catalog IDs of the models the caller may use (optional); other models are never selected
+	Models []string
+	// Providers the request may be routed to (optional); other providers are never selected
+	Providers []string
+	// Values experiments assign the request by (optional); without them it joins no experiment
+	Sticky StickyKeys
+	// Counts the prompt's tokens (optional); models whose context window can't fit the prompt and
+	// requested output are never selected
+	Tokens *RequestTokens
+	// Most the request may cost in USD (optional); models estimated to cost more are never selected
+	MaxCost float64
 }
 
 type SelectedProviderOutput struct {
 	Provider   Provider
 	Model      string
 	Candidates []Provider // The filtered pool of candidates
+	Experiment string     // Experiment the request was assigned to, if any
+	Variant    string     // Variant of Experiment that chose Provider and Model
+	// Expected cost of the request in USD, when Model is known
+	EstimatedCost float64
+	// Catalog tier of Model, which may be below the requested tier after a downgrade
+	Tier string
 }
 
 type FilterInput struct {
-	Candidates []Provider
-	Messages   []Message
-	Tier       string
+	Candidates   []Provider
+	Messages     []Message
+	Tier         string
+	Capabilities []string
+	Models       []string // Catalog IDs of the models the caller may use (empty means any)
+	Providers    []string // Providers the request may be routed to (empty means any)
+	Tokens       *RequestTokens
 }
 
 type FilterOutput struct {
 	Candidates []Provider
 }
+
+// CostCeilingError is returned when every model that could serve a request is estimated to cost
+// more than the request allows.
+type CostCeilingError struct {
+	MaxCost  float64
+	Cheapest float64 // Estimated cost of the cheapest model considered
+}
+
+func (e *CostCeilingError) Error() string {
+	return fmt.Sprintf("no available model is estimated to cost less than max_cost_usd of $%.6f (cheapest: $%.6f)", e.MaxCost, e.Cheapest)
+}
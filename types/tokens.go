@@ -0,0 +1,90 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RequestTokens counts a request's prompt tokens with each provider's tokenizer, once per provider
+// for the lifetime of the request, and checks whether the request fits a model's context window.
+// A nil *RequestTokens fits every window.
+type RequestTokens struct {
+	messages  []Message
+	maxTokens int
+
+	mu     sync.Mutex
+	counts map[string]tokenCount
+}
+
+type tokenCount struct {
+	tokens int
+	err    error
+}
+
+// NewRequestTokens returns the token counter of a request asking for up to maxTokens output tokens
+// (0 when it doesn't say).
+func NewRequestTokens(messages []Message, maxTokens int) *RequestTokens {
+	return &RequestTokens{
+		messages:  messages,
+		maxTokens: maxTokens,
+		counts:    make(map[string]tokenCount),
+	}
+}
+
+// MaxTokens returns the output tokens the request asked for, or 0.
+func (t *RequestTokens) MaxTokens() int {
+	if t == nil {
+		return 0
+	}
+	return t.maxTokens
+}
+
+// Prompt returns the prompt's tokens as counted by provider.
+func (t *RequestTokens) Prompt(ctx context.Context, provider Provider) (int, error) {
+	name := provider.GetProviderName()
+
+	t.mu.Lock()
+	count, ok := t.counts[name]
+	t.mu.Unlock()
+	if ok {
+		return count.tokens, count.err
+	}
+
+	tokens, err := provider.CountTokens(ctx, t.messages)
+
+	t.mu.Lock()
+	t.counts[name] = tokenCount{tokens: tokens, err: err}
+	t.mu.Unlock()
+	return tokens, err
+}
+
+// Fits reports whether the prompt, as counted by provider, and the requested output fit a context
+// window of contextWindow tokens. Unknown windows (0) and prompts provider can't count are assumed to fit.
+func (t *RequestTokens) Fits(ctx context.Context, provider Provider, contextWindow int) bool {
+	if t == nil || contextWindow <= 0 {
+		return true
+	}
+
+	tokens, err := t.Prompt(ctx, provider)
+	if err != nil {
+		return true
+	}
+	return tokens+t.maxTokens <= contextWindow
+}
+
+// ContextWindowError is returned when a request doesn't fit the context window of any model that
+// could serve it.
+type ContextWindowError struct {
+	PromptTokens  int
+	MaxTokens     int
+	ContextWindow int // Largest context window among the models considered
+}
+
+func (e *ContextWindowError) Error() string {
+	if e.MaxTokens > 0 {
+		return fmt.Sprintf("request needs %d tokens (%d prompt + %d max_tokens), but the largest context window of the available models is %d tokens",
+			e.PromptTokens+e.MaxTokens, e.PromptTokens, e.MaxTokens, e.ContextWindow)
+	}
+	return fmt.Sprintf("prompt of %d tokens exceeds the largest context window of the available models (%d tokens)", e.PromptTokens, e.ContextWindow)
+}
@@ -0,0 +1,104 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Tool calling follows the OpenAI chat completions shapes; each provider converts them
+// to its own representation.
+
+// CapabilityTools is the model capability required to serve requests that define tools.
+const CapabilityTools = "tools"
+
+type Tool struct {
+	Type     string             `json:"type" binding:"required,oneof=function"`
+	Function FunctionDefinition `json:"function" binding:"required"`
+}
+
+type FunctionDefinition struct {
+	Name        string `json:"name" binding:"required,min=1,max=64"`
+	Description string `json:"description,omitempty" binding:"max=4096"`
+	// JSON schema of the function's arguments
+	Parameters map[string]any `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call made by the model, as returned in an assistant message.
+type ToolCall struct {
+	ID       string       `json:"id" binding:"required"`
+	Type     string       `json:"type" binding:"omitempty,oneof=function"`
+	Function FunctionCall `json:"function" binding:"required"`
+}
+
+type FunctionCall struct {
+	Name string `json:"name" binding:"required"`
+	// JSON-encoded arguments
+	Arguments string `json:"arguments"`
+}
+
+// ToolCallDelta is a streamed fragment of a tool call. The first fragment of a call carries
+// its ID and function name; later fragments with the same Index append to its arguments.
+type ToolCallDelta struct {
+	Index    int               `json:"index"`
+	ID       string            `json:"id,omitempty"`
+	Type     string            `json:"type,omitempty"`
+	Function FunctionCallDelta `json:"function"`
+}
+
+type FunctionCallDelta struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// Tool choice modes. ToolChoiceFunction forces a call to the named function.
+const (
+	ToolChoiceNone     = "none"
+	ToolChoiceAuto     = "auto"
+	ToolChoiceRequired = "required"
+	ToolChoiceFunction = "function"
+)
+
+// ToolChoice accepts OpenAI's tool_choice values: "none", "auto", "required",
+// or {"type": "function", "function": {"name": ...}}.
+type ToolChoice struct {
+	Mode     string
+	Function string // Set when Mode is ToolChoiceFunction
+}
+
+func (t *ToolChoice) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		switch mode {
+		case ToolChoiceNone, ToolChoiceAuto, ToolChoiceRequired:
+			*t = ToolChoice{Mode: mode}
+			return nil
+		}
+		return fmt.Errorf("tool_choice must be one of none, auto, required or a function object")
+	}
+
+	var named struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &named); err != nil {
+		return fmt.Errorf("tool_choice must be a string or a function object")
+	}
+	if named.Type != "function" || named.Function.Name == "" {
+		return fmt.Errorf("tool_choice object must have type function and a function name")
+	}
+
+	*t = ToolChoice{Mode: ToolChoiceFunction, Function: named.Function.Name}
+	return nil
+}
+
+func (t ToolChoice) MarshalJSON() ([]byte, error) {
+	if t.Mode == ToolChoiceFunction {
+		return json.Marshal(map[string]any{
+			"type":     "function",
+			"function": map[string]string{"name": t.Function},
+		})
+	}
+	return json.Marshal(t.Mode)
+}